@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"downloader/internal/infra/report"
+	"downloader/internal/infra/youtube"
+	"downloader/internal/usecase"
+)
+
+// runInfo prints what is available for a video without downloading it and
+// returns the exit status.
+func runInfo(args []string) int {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	url := fs.String("v", "", "Video URL")
+	asJSON := fs.Bool("json", false, "Print the info as JSON")
+	fs.Parse(args)
+
+	if *url == "" {
+		fs.Usage()
+		fmt.Println("Usage: downloader info -v \"<url>\" [-json]")
+		return 1
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	go cancelOnSignal(cancel)
+
+	useCase := usecase.VideoInfoUseCase{Fetcher: youtube.NewKkdaiInfoFetcher()}
+	info, err := useCase.Execute(ctx, *url)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		err = report.VideoInfoJSON(os.Stdout, info)
+	} else {
+		err = report.VideoInfo(os.Stdout, info)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"downloader/pkg/config"
+	"downloader/pkg/utils"
+)
+
+const subscriptionsUsage = `Usage: downloader subscriptions <command> [flags]
+
+Commands:
+  add     -url <channel or playlist> -requester <name> [flags]
+  update  <id> -url <channel or playlist> -requester <name> [flags]
+  list    [-requester <name>]
+  remove  <id>
+  check   <id>
+
+The subscriptions live in the web server, reached at DOWNLOADER_URL or -server.`
+
+type subscription struct {
+	ID             string `json:"id,omitempty"`
+	URL            string `json:"url"`
+	Requester      string `json:"requester"`
+	Format         string `json:"format,omitempty"`
+	AudioOnly      bool   `json:"audio_only"`
+	Interval       int64  `json:"interval_seconds,omitempty"`
+	TitlePattern   string `json:"title_regex,omitempty"`
+	MinDuration    int64  `json:"min_duration_seconds,omitempty"`
+	MaxDuration    int64  `json:"max_duration_seconds,omitempty"`
+	PublishedAfter string `json:"published_after,omitempty"`
+	Seen           int    `json:"seen,omitempty"`
+	LastChecked    string `json:"last_checked,omitempty"`
+	LastError      string `json:"last_error,omitempty"`
+}
+
+// runSubscriptions manages the subscriptions of a running web server and
+// returns the exit status.
+func runSubscriptions(args []string) int {
+	if len(args) == 0 {
+		fmt.Println(subscriptionsUsage)
+		return 1
+	}
+
+	server := utils.GetEnvOrDefault("DOWNLOADER_URL", "http://localhost:"+config.GetConfig().Port)
+	fs := flag.NewFlagSet("subscriptions "+args[0], flag.ExitOnError)
+	fs.StringVar(&server, "server", server, "Address of the downloader web server")
+
+	var err error
+	switch command := args[0]; command {
+	case "add", "update":
+		var sub subscription
+		var interval, minDuration, maxDuration time.Duration
+		fs.StringVar(&sub.URL, "url", "", "Channel or playlist URL")
+		fs.StringVar(&sub.Requester, "requester", "", "Who receives the downloads")
+		fs.StringVar(&sub.Format, "f", "", "Format selector")
+		fs.BoolVar(&sub.AudioOnly, "a", false, "Download only the audio stream")
+		fs.DurationVar(&interval, "interval", 0, "Poll interval, e.g. \"30m\" (default 1h)")
+		fs.StringVar(&sub.TitlePattern, "title", "", "Only videos whose title matches this regular expression")
+		fs.DurationVar(&minDuration, "min", 0, "Only videos at least this long")
+		fs.DurationVar(&maxDuration, "max", 0, "Only videos at most this long")
+		fs.StringVar(&sub.PublishedAfter, "after", "", "Only videos published after this date, e.g. \"2024-01-31\"")
+
+		id, rest := leadingID(args[1:], command == "update")
+		fs.Parse(rest)
+		sub.Interval = int64(interval.Seconds())
+		sub.MinDuration = int64(minDuration.Seconds())
+		sub.MaxDuration = int64(maxDuration.Seconds())
+
+		if command == "add" {
+			err = subscriptionRequest(server, http.MethodPost, "/subscriptions", sub, &sub)
+		} else {
+			err = subscriptionRequest(server, http.MethodPut, "/subscriptions/"+id, sub, &sub)
+		}
+		if err == nil {
+			fmt.Printf("Subscription %s saved.\n", sub.ID)
+		}
+	case "list":
+		requester := fs.String("requester", "", "Only the subscriptions of this requester")
+		fs.Parse(args[1:])
+
+		var subs []subscription
+		path := "/subscriptions"
+		if *requester != "" {
+			path += "?" + neturl.Values{"requester": {*requester}}.Encode()
+		}
+		if err = subscriptionRequest(server, http.MethodGet, path, nil, &subs); err == nil {
+			printSubscriptions(subs)
+		}
+	case "remove":
+		id, rest := leadingID(args[1:], true)
+		fs.Parse(rest)
+		if err = subscriptionRequest(server, http.MethodDelete, "/subscriptions/"+id, nil, nil); err == nil {
+			fmt.Printf("Subscription %s removed.\n", id)
+		}
+	case "check":
+		id, rest := leadingID(args[1:], true)
+		fs.Parse(rest)
+		var result struct {
+			Enqueued int `json:"enqueued"`
+		}
+		if err = subscriptionRequest(server, http.MethodPost, "/subscriptions/"+id+"/check", nil, &result); err == nil {
+			fmt.Printf("%d new videos enqueued.\n", result.Enqueued)
+		}
+	default:
+		fmt.Println(subscriptionsUsage)
+		return 1
+	}
+
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// leadingID takes the subscription ID given before the flags.
+func leadingID(args []string, required bool) (string, []string) {
+	if !required {
+		return "", args
+	}
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Println(subscriptionsUsage)
+		os.Exit(1)
+	}
+	return args[0], args[1:]
+}
+
+func subscriptionRequest(server, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimRight(server, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error reaching the server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("server answered %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func printSubscriptions(subs []subscription) {
+	if len(subs) == 0 {
+		fmt.Println("No subscriptions.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tURL\tREQUESTER\tINTERVAL\tSEEN\tLAST CHECKED\tLAST ERROR")
+	for _, sub := range subs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", sub.ID, sub.URL, sub.Requester,
+			time.Duration(sub.Interval)*time.Second, sub.Seen, sub.LastChecked, sub.LastError)
+	}
+	w.Flush()
+}
@@ -1,17 +1,39 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 
+	"downloader/internal/domain"
+	"downloader/internal/infra/archive"
 	termux "downloader/internal/infra/notifyer/termux"
 	"downloader/internal/infra/progress"
+	"downloader/internal/infra/registry"
 	"downloader/internal/infra/youtube"
 	"downloader/internal/usecase"
+	"downloader/pkg/config"
 )
 
 var url = flag.String("v", "", "Video must not be null")
+var format = flag.String("f", "best", "Format selector, e.g. \"best\", \"height<=720,mp4\", \"itag=22\"")
+var audioOnly = flag.Bool("a", false, "Download only the audio stream")
+var items = flag.String("items", "", "Playlist items to download, e.g. \"1-10,15\"")
+var reverse = flag.Bool("reverse", false, "Download the playlist in reverse order")
+var skipDownloaded = flag.Bool("skip-downloaded", false, "Skip playlist videos already downloaded")
+var subs = flag.String("subs", "", "Subtitle languages to download, e.g. \"en,pt-BR\"")
+var autoSubs = flag.Bool("auto-subs", false, "Fall back to auto-generated or translated subtitles")
+var embedThumb = flag.Bool("embed-thumb", false, "Embed the thumbnail as cover art in the downloaded file")
+var clipStart = flag.String("start", "", "Download from this time on, e.g. \"1:30\" or \"90\"")
+var clipEnd = flag.String("end", "", "Download up to this time, e.g. \"1:02:00\"")
+var splitChapters = flag.Bool("split-chapters", false, "Also save every chapter of the video as a file")
+var liveFromStart = flag.Bool("live-from-start", false, "Record a livestream from the earliest available segment")
+var liveMax = flag.String("live-max", "", "Stop recording a livestream after this long, e.g. \"2h\" or \"30:00\"")
 
 func main() {
 	flag.Parse()
@@ -21,15 +43,69 @@ func main() {
 		os.Exit(1)
 	}
 
-	downloader := youtube.NewKkdaiDownloader(termux.NewTermuxNotifyer(), nil)
+	downloader := registry.NewDefault(termux.NewTermuxNotifyer(), nil)
 	progressBar := progress.NewTerminalProgressBar()
 
-	useCase := usecase.DownloadVideoUseCase{Downloader: downloader}
-	err := useCase.Execute(usecase.Solicitation{URL: *url}, progressBar)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	go cancelOnSignal(cancel)
 
+	downloads, err := archive.NewFileArchive(filepath.Join(config.GetConfig().ConfigDir, "archive.txt"))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	subtitles, err := usecase.ParseLanguages(*subs)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	start, end, err := usecase.ParseClip(*clipStart, *clipEnd)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	maxDuration, err := usecase.ParseDuration(*liveMax)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	sol := usecase.Solicitation{URL: *url, Format: *format, AudioOnly: *audioOnly, Subtitles: subtitles, AutoSubtitles: *autoSubs, EmbedThumbnail: *embedThumb, Start: start, End: end, SplitChapters: *splitChapters, LiveFromStart: *liveFromStart, LiveMaxDuration: maxDuration}
+	playlistUC := usecase.PlaylistUseCase{Fetcher: youtube.NewKkdaiPlaylistFetcher(), Downloader: downloader, Archive: downloads}
+	if playlistUC.IsPlaylist(*url) {
+		var result usecase.PlaylistResult
+		result, err = playlistUC.Execute(ctx, usecase.PlaylistSolicitation{
+			Solicitation:   sol,
+			Items:          *items,
+			Reverse:        *reverse,
+			SkipDownloaded: *skipDownloaded,
+		}, progressBar)
+		if err == nil {
+			fmt.Printf("Playlist: %d downloaded, %d failed, %d skipped.\n", result.Done, result.Failed, result.Skipped)
+		}
+	} else {
+		useCase := usecase.DownloadVideoUseCase{Downloader: downloader, Archive: downloads}
+		err = useCase.Execute(ctx, sol, progressBar)
+	}
+
+	if errors.Is(err, domain.ErrDownloadCancelled) {
+		fmt.Println("\nDownload cancelled.")
+		os.Exit(130)
+	}
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 	} else {
 		fmt.Println("Download complete.")
 	}
 }
+
+// cancelOnSignal cancels the download on Ctrl-C or SIGTERM, letting the
+// downloader remove the partial file before the program exits.
+func cancelOnSignal(cancel context.CancelCauseFunc) {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+	cancel(domain.ErrDownloadCancelled)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	dependencyinjections "downloader/internal/infra/dependency_injections"
 	"downloader/internal/infra/notifyer/server"
+	"downloader/internal/infra/registry"
 	webserver "downloader/internal/infra/web_server"
 	"downloader/internal/infra/youtube"
 	"downloader/pkg/config"
@@ -27,8 +28,12 @@ func main() {
 
 	notifyer := server.NewServerNotifyer(cfg.URLWebhook)
 	db := *dependencyinjections.GetVideoDatabase()
+	jobs := *dependencyinjections.GetJobStore()
+	archive := *dependencyinjections.GetArchive()
+	subscriptions := *dependencyinjections.GetSubscriptionStore()
 
-	svr := webserver.NewWebServer(youtube.NewKkdaiDownloader(notifyer, db), db)
+	downloader := registry.NewDefault(notifyer, db)
+	svr := webserver.NewWebServer(downloader, db, jobs, youtube.NewKkdaiPlaylistFetcher(), youtube.NewKkdaiInfoFetcher(), archive, subscriptions)
 
 	svr.Start(getPort())
 }
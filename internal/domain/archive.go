@@ -0,0 +1,7 @@
+package domain
+
+// Archive records the source IDs of the videos already downloaded.
+type Archive interface {
+	Contains(sourceID string) bool
+	Add(sourceID string) error
+}
@@ -4,4 +4,6 @@ type Database[T any] interface {
 	Save(id string, obj T) error
 	Get(id string) (T, error)
 	Remove(id string) error
+	List(query Query) ([]T, error)
+	Count(query Query) (int, error)
 }
@@ -1,9 +1,33 @@
 package domain
 
-import "os"
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+// ErrDownloadCancelled is the cancellation cause used when a requester
+// cancels a download, as opposed to the process shutting down.
+var ErrDownloadCancelled = errors.New("download cancelled")
+
+// ErrUnsupportedSource is returned for URLs that no downloader handles.
+var ErrUnsupportedSource = errors.New("unsupported source")
 
 type Downloader interface {
-	Download(video Video, progress ProgressBar) error
+	Download(ctx context.Context, video Video, progress ProgressBar) error
 	Finalize(notification Notification) error
-	Cancel(file os.File) error
+	Cancel(file *os.File) error
+}
+
+// SourceChecker is implemented by downloaders that can tell whether they
+// handle a URL before downloading it.
+type SourceChecker interface {
+	Supports(ctx context.Context, url string) error
+}
+
+// SourceIdentifier is implemented by downloaders that can tell the ID the
+// source gives to the video at a URL, such as a YouTube video ID, before
+// downloading it. URLs without one yield an empty ID.
+type SourceIdentifier interface {
+	SourceID(url string) (string, error)
 }
@@ -0,0 +1,9 @@
+package domain
+
+import "context"
+
+// HealthChecker is implemented by the dependencies that can tell whether
+// they are usable, such as a remote database.
+type HealthChecker interface {
+	Health(ctx context.Context) error
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+type JobState string
+
+const (
+	JobQueued           JobState = "queued"
+	JobFetchingMetadata JobState = "fetching_metadata"
+	JobDownloading      JobState = "downloading"
+	JobPostProcessing   JobState = "post_processing"
+	JobDone             JobState = "done"
+	JobFailed           JobState = "failed"
+	JobCancelled        JobState = "cancelled"
+)
+
+type Job struct {
+	ID         string
+	Video      Video
+	Priority   int
+	State      JobState
+	BytesDone  int64
+	BytesTotal int64
+	Speed      float64
+	ETA        time.Duration
+	Error      string
+	VideoID    string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Finished reports whether the job reached a final state.
+func (j Job) Finished() bool {
+	return j.State == JobDone || j.State == JobFailed || j.State == JobCancelled
+}
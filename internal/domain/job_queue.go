@@ -0,0 +1,5 @@
+package domain
+
+type JobQueue interface {
+	Enqueue(job Job) (Job, error)
+}
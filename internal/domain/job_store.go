@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+type JobStore interface {
+	Database[Job]
+}
+
+// JobLease is how long a shared job store keeps an unfinished job for the
+// instance holding it without hearing from it.
+const JobLease = time.Minute
+
+// JobClaimer is implemented by job stores shared between instances, so
+// each unfinished job runs on a single one. Jobs saved by an instance are
+// held by it as long as it renews them within JobLease.
+type JobClaimer interface {
+	// ClaimJobs takes the unfinished jobs of this instance and the ones
+	// left behind by instances that stopped renewing them.
+	ClaimJobs() ([]Job, error)
+	// RenewJobs extends the lease of the unfinished jobs of this instance.
+	RenewJobs() error
+	// RequestCancel asks the instance holding the job id to cancel it,
+	// reporting false when the job already reached a final state.
+	RequestCancel(id string) (bool, error)
+	// CancelRequests returns the IDs of the unfinished jobs of this
+	// instance whose cancellation was requested.
+	CancelRequests() ([]string, error)
+}
@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+type Muxer interface {
+	Merge(videoPath string, audioPath string, outputPath string) error
+	// Cut copies the part of inputPath between start and end to outputPath,
+	// starting at a keyframe. A zero end runs to the end.
+	Cut(inputPath string, outputPath string, start, end time.Duration) error
+}
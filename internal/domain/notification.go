@@ -1,7 +1,17 @@
 package domain
 
+type NotificationKind string
+
+const (
+	NotificationDone      NotificationKind = "done"
+	NotificationCancelled NotificationKind = "cancelled"
+	NotificationFailed    NotificationKind = "failed"
+	NotificationPlaylist  NotificationKind = "playlist"
+)
+
 type Notification struct {
 	Title   string
 	Message string
 	To      string
+	Kind    NotificationKind
 }
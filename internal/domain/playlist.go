@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type Playlist struct {
+	ID      string
+	Title   string
+	Author  string
+	Entries []PlaylistEntry
+}
+
+// PlaylistEntry is a video of a playlist. Index starts at 1.
+type PlaylistEntry struct {
+	Index    int
+	ID       string
+	URL      string
+	Title    string
+	Duration time.Duration
+}
+
+// PlaylistFetcher lists the videos of a playlist or channel. PublishDate
+// looks up a single entry, as listings do not carry the upload date.
+type PlaylistFetcher interface {
+	IsPlaylist(url string) bool
+	Fetch(ctx context.Context, url string) (Playlist, error)
+	PublishDate(ctx context.Context, entryID string) (time.Time, error)
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "errors"
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+type Operator string
+
+const (
+	OpEqual          Operator = "eq"
+	OpContains       Operator = "contains"
+	OpGreaterOrEqual Operator = "gte"
+	OpLessOrEqual    Operator = "lte"
+)
+
+// Filter compares a field of the record with Value. Field is the name of
+// the Go struct field, with dots for nested structs, e.g. "Video.Requester".
+// OpContains matches strings ignoring case.
+type Filter struct {
+	Field string
+	Op    Operator
+	Value any
+}
+
+// Query selects the records matching every filter, sorted by OrderBy and
+// paginated by Offset and Limit. A zero Limit returns every record.
+type Query struct {
+	Filters    []Filter
+	OrderBy    string
+	Descending bool
+	Offset     int
+	Limit      int
+}
@@ -0,0 +1,15 @@
+package domain
+
+// SegmentReporter is implemented by progress bars that also follow how
+// many segments of a segmented stream were downloaded.
+type SegmentReporter interface {
+	Segments(done, total int)
+}
+
+// ReportSegments forwards the segment count to progress when it is a
+// SegmentReporter.
+func ReportSegments(progress ProgressBar, done, total int) {
+	if reporter, ok := progress.(SegmentReporter); ok {
+		reporter.Segments(done, total)
+	}
+}
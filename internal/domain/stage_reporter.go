@@ -0,0 +1,14 @@
+package domain
+
+// StageReporter is implemented by progress bars that also follow which
+// step of the download is running.
+type StageReporter interface {
+	Stage(state JobState)
+}
+
+// ReportStage forwards the stage to progress when it is a StageReporter.
+func ReportStage(progress ProgressBar, state JobState) {
+	if reporter, ok := progress.(StageReporter); ok {
+		reporter.Stage(state)
+	}
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// Subscription follows a channel or playlist and downloads its new videos.
+// Zero filters match every video.
+type Subscription struct {
+	ID             string
+	URL            string
+	Requester      string
+	Format         string
+	AudioOnly      bool
+	Interval       time.Duration
+	TitlePattern   string
+	MinDuration    time.Duration
+	MaxDuration    time.Duration
+	PublishedAfter time.Time
+	Seen           map[string]bool
+	LastChecked    time.Time
+	LastError      string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Due reports whether the subscription should be checked at now.
+func (s Subscription) Due(now time.Time) bool {
+	return s.LastChecked.IsZero() || !now.Before(s.LastChecked.Add(s.Interval))
+}
+
+type SubscriptionStore interface {
+	Database[Subscription]
+}
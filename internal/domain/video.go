@@ -1,7 +1,122 @@
 package domain
 
+import "time"
+
 type Video struct {
-	URL       string
-	Filename  string
-	Requester string
+	ID            string
+	URL           string
+	Title         string
+	Filename      string
+	Requester     string
+	Format        string
+	AudioOnly     bool
+	Extension     string
+	MimeType      string
+	SourceID      string
+	PlaylistID    string
+	PlaylistTitle string
+	// PlaylistSkipped counts the entries of the playlist request skipped as
+	// already downloaded, for its summary.
+	PlaylistSkipped int
+	// SubtitleLanguages asks for the captions in these languages, falling
+	// back to auto-generated or translated ones when AutoSubtitles is set.
+	SubtitleLanguages []string
+	AutoSubtitles     bool
+	Subtitles         []Subtitle
+	// EmbedThumbnail asks for the thumbnail to be stored as the cover art
+	// of the output, when its format has tags. The thumbnail is saved apart
+	// either way.
+	EmbedThumbnail bool
+	Thumbnail      string
+	ThumbnailType  string
+	// ClipStart and ClipEnd limit the download to a part of the video,
+	// widened to the keyframes around it. A zero ClipEnd runs to the end.
+	ClipStart time.Duration
+	ClipEnd   time.Duration
+	// SplitChapters asks for one more file per chapter. Each is a video of
+	// its own, numbered by ChapterIndex, whose ParentID is the full video.
+	SplitChapters bool
+	ParentID      string
+	ChapterIndex  int
+	// LiveFromStart records a livestream from the earliest segment still
+	// available instead of from the live edge, and LiveMaxDuration stops
+	// the recording after that much of it. Live marks recordings.
+	LiveFromStart   bool
+	LiveMaxDuration time.Duration
+	Live            bool
+	Metadata        VideoMetadata
+	Stream          StreamInfo
+	Path            string
+	Size            int64
+	Checksum        string
+	RequestedAt     time.Time
+	StartedAt       time.Time
+	FinishedAt      time.Time
+	CreatedAt       time.Time
+}
+
+// VideoMetadata is what the source tells about a video.
+type VideoMetadata struct {
+	Author      string
+	ChannelID   string
+	Duration    time.Duration
+	Description string
+	PublishDate time.Time
+	Views       int
+	Thumbnails  []Thumbnail
+	Chapters    []Chapter
+}
+
+type Thumbnail struct {
+	URL    string
+	Width  int
+	Height int
+}
+
+// Subtitle is a caption track saved next to the video, in every format of
+// SubtitleFormats.
+type Subtitle struct {
+	Language      string
+	Name          string
+	AutoGenerated bool
+	Translated    bool
+}
+
+var SubtitleFormats = []string{"srt", "vtt"}
+
+// SubtitleFilename names the file of a subtitle of the video with the
+// given ID.
+func SubtitleFilename(videoID, language, format string) string {
+	return videoID + "." + language + "." + format
+}
+
+// ThumbnailFilename names the thumbnail file of the video with the given
+// ID.
+func ThumbnailFilename(videoID, extension string) string {
+	return videoID + ".thumb." + extension
+}
+
+// StreamInfo describes the streams chosen for the download. AudioItag is
+// set when a separate audio stream was merged into the video.
+type StreamInfo struct {
+	Itag       int
+	AudioItag  int
+	Resolution string
+	Width      int
+	Height     int
+	FPS        int
+	Bitrate    int
+	VideoCodec string
+	AudioCodec string
+}
+
+// Clipped reports whether only a part of the video was asked for.
+func (v Video) Clipped() bool {
+	return v.ClipStart > 0 || v.ClipEnd > 0
+}
+
+// InPlaylist reports whether the video was requested as part of a
+// playlist, whose requester gets a single summary notification.
+func (v Video) InPlaylist() bool {
+	return v.PlaylistID != ""
 }
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// VideoInfo is what the source offers for a video, fetched without
+// downloading any media.
+type VideoInfo struct {
+	ID       string
+	URL      string
+	Title    string
+	Metadata VideoMetadata
+	Captions []Caption
+	Formats  []FormatInfo
+}
+
+type Chapter struct {
+	Title string
+	Start time.Duration
+	End   time.Duration
+}
+
+type Caption struct {
+	LanguageCode  string
+	Name          string
+	AutoGenerated bool
+}
+
+// FormatInfo describes one of the streams a video can be downloaded in.
+// Size is zero when the source does not tell it.
+type FormatInfo struct {
+	Itag            int
+	Container       string
+	MimeType        string
+	Codecs          []string
+	Resolution      string
+	Width           int
+	Height          int
+	FPS             int
+	Bitrate         int
+	Size            int64
+	AudioChannels   int
+	AudioSampleRate int
+}
+
+type VideoInfoFetcher interface {
+	Info(ctx context.Context, url string) (VideoInfo, error)
+}
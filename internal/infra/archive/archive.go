@@ -0,0 +1,67 @@
+package archive
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// FileArchive keeps the downloaded source IDs in a text file, one per line.
+type FileArchive struct {
+	path string
+	ids  map[string]bool
+	mu   sync.RWMutex
+}
+
+func NewFileArchive(path string) (*FileArchive, error) {
+	a := &FileArchive{path: path, ids: map[string]bool{}}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if id := strings.TrimSpace(scanner.Text()); id != "" {
+			a.ids[id] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	return a, nil
+}
+
+func (a *FileArchive) Contains(sourceID string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.ids[sourceID]
+}
+
+func (a *FileArchive) Add(sourceID string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.ids[sourceID] {
+		return nil
+	}
+
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", a.path, err)
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintln(file, sourceID); err != nil {
+		return fmt.Errorf("failed to write %s: %w", a.path, err)
+	}
+	a.ids[sourceID] = true
+	return nil
+}
@@ -0,0 +1,396 @@
+// Package dash reads MPEG-DASH manifests (ISO/IEC 23009-1).
+package dash
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidManifest     = errors.New("invalid dash manifest")
+	ErrUnsupportedManifest = errors.New("unsupported dash manifest")
+)
+
+// Manifest is what a MPD offers to download. Encrypted representations
+// are left out.
+type Manifest struct {
+	// Dynamic manifests describe a live stream, and change over time.
+	Dynamic         bool
+	Duration        time.Duration
+	Representations []Representation
+}
+
+// Representation is a version of one of the streams of the manifest.
+// Init is nil when the segments need no initialization segment.
+type Representation struct {
+	ID          string
+	ContentType string
+	MimeType    string
+	Codecs      string
+	Language    string
+	Bandwidth   int
+	Width       int
+	Height      int
+	Init        *Segment
+	Segments    []Segment
+}
+
+// Segment is a resource to fetch, or the part of it starting at Offset
+// when Length is not 0.
+type Segment struct {
+	URL    string
+	Offset int64
+	Length int64
+}
+
+type mpd struct {
+	Type                      string   `xml:"type,attr"`
+	MediaPresentationDuration string   `xml:"mediaPresentationDuration,attr"`
+	BaseURL                   []string `xml:"BaseURL"`
+	Periods                   []period `xml:"Period"`
+}
+
+type period struct {
+	Duration       string          `xml:"duration,attr"`
+	BaseURL        []string        `xml:"BaseURL"`
+	AdaptationSets []adaptationSet `xml:"AdaptationSet"`
+}
+
+type adaptationSet struct {
+	ContentType       string           `xml:"contentType,attr"`
+	MimeType          string           `xml:"mimeType,attr"`
+	Codecs            string           `xml:"codecs,attr"`
+	Lang              string           `xml:"lang,attr"`
+	BaseURL           []string         `xml:"BaseURL"`
+	ContentProtection []struct{}       `xml:"ContentProtection"`
+	SegmentTemplate   *segmentTemplate `xml:"SegmentTemplate"`
+	SegmentList       *segmentList     `xml:"SegmentList"`
+	Representations   []representation `xml:"Representation"`
+}
+
+type representation struct {
+	ID                string           `xml:"id,attr"`
+	Bandwidth         int              `xml:"bandwidth,attr"`
+	Width             int              `xml:"width,attr"`
+	Height            int              `xml:"height,attr"`
+	MimeType          string           `xml:"mimeType,attr"`
+	Codecs            string           `xml:"codecs,attr"`
+	BaseURL           []string         `xml:"BaseURL"`
+	ContentProtection []struct{}       `xml:"ContentProtection"`
+	SegmentTemplate   *segmentTemplate `xml:"SegmentTemplate"`
+	SegmentList       *segmentList     `xml:"SegmentList"`
+}
+
+type segmentTemplate struct {
+	Media          string           `xml:"media,attr"`
+	Initialization string           `xml:"initialization,attr"`
+	StartNumber    *int64           `xml:"startNumber,attr"`
+	Timescale      int64            `xml:"timescale,attr"`
+	Duration       int64            `xml:"duration,attr"`
+	Timeline       *segmentTimeline `xml:"SegmentTimeline"`
+}
+
+type segmentTimeline struct {
+	S []struct {
+		T *int64 `xml:"t,attr"`
+		D int64  `xml:"d,attr"`
+		R int64  `xml:"r,attr"`
+	} `xml:"S"`
+}
+
+type segmentList struct {
+	Initialization *struct {
+		SourceURL string `xml:"sourceURL,attr"`
+		Range     string `xml:"range,attr"`
+	} `xml:"Initialization"`
+	SegmentURLs []struct {
+		Media      string `xml:"media,attr"`
+		MediaRange string `xml:"mediaRange,attr"`
+	} `xml:"SegmentURL"`
+}
+
+// Parse reads a MPD, resolving its URLs against base. Only single period
+// manifests are supported.
+func Parse(data []byte, base *url.URL) (*Manifest, error) {
+	var doc mpd
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+	}
+	if len(doc.Periods) == 0 {
+		return nil, fmt.Errorf("%w: no period", ErrInvalidManifest)
+	}
+	if len(doc.Periods) > 1 {
+		return nil, fmt.Errorf("%w: %d periods", ErrUnsupportedManifest, len(doc.Periods))
+	}
+
+	m := &Manifest{Dynamic: doc.Type == "dynamic"}
+	p := doc.Periods[0]
+	var err error
+	if p.Duration != "" {
+		m.Duration, err = ParseDuration(p.Duration)
+	} else if doc.MediaPresentationDuration != "" {
+		m.Duration, err = ParseDuration(doc.MediaPresentationDuration)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+	}
+
+	periodBase := withBaseURL(withBaseURL(base, doc.BaseURL), p.BaseURL)
+	protected := 0
+	for _, set := range p.AdaptationSets {
+		setBase := withBaseURL(periodBase, set.BaseURL)
+		for _, r := range set.Representations {
+			if len(set.ContentProtection) > 0 || len(r.ContentProtection) > 0 {
+				protected++
+				continue
+			}
+			rep, err := newRepresentation(m, set, r, withBaseURL(setBase, r.BaseURL))
+			if err != nil {
+				return nil, fmt.Errorf("%w: representation %s: %v", ErrInvalidManifest, r.ID, err)
+			}
+			m.Representations = append(m.Representations, rep)
+		}
+	}
+	if len(m.Representations) == 0 && protected > 0 {
+		return nil, fmt.Errorf("%w: every representation is encrypted", ErrUnsupportedManifest)
+	}
+	return m, nil
+}
+
+func newRepresentation(m *Manifest, set adaptationSet, r representation, base *url.URL) (Representation, error) {
+	rep := Representation{
+		ID:          r.ID,
+		ContentType: set.ContentType,
+		MimeType:    firstNonEmpty(r.MimeType, set.MimeType),
+		Codecs:      firstNonEmpty(r.Codecs, set.Codecs),
+		Language:    set.Lang,
+		Bandwidth:   r.Bandwidth,
+		Width:       r.Width,
+		Height:      r.Height,
+	}
+	if rep.ContentType == "" {
+		rep.ContentType, _, _ = strings.Cut(rep.MimeType, "/")
+	}
+
+	var err error
+	switch {
+	case r.SegmentTemplate != nil || set.SegmentTemplate != nil:
+		err = rep.expandTemplate(mergeTemplates(set.SegmentTemplate, r.SegmentTemplate), base, m.Duration)
+	case r.SegmentList != nil || set.SegmentList != nil:
+		list := r.SegmentList
+		if list == nil {
+			list = set.SegmentList
+		}
+		err = rep.readList(list, base)
+	default:
+		// A single file, such as one described by a SegmentBase.
+		rep.Segments = []Segment{{URL: base.String()}}
+	}
+	return rep, err
+}
+
+// mergeTemplates applies the attributes of the representation template
+// over the ones it inherits from its adaptation set.
+func mergeTemplates(inherited, own *segmentTemplate) segmentTemplate {
+	var t segmentTemplate
+	if inherited != nil {
+		t = *inherited
+	}
+	if own == nil {
+		return t
+	}
+	if own.Media != "" {
+		t.Media = own.Media
+	}
+	if own.Initialization != "" {
+		t.Initialization = own.Initialization
+	}
+	if own.StartNumber != nil {
+		t.StartNumber = own.StartNumber
+	}
+	if own.Timescale != 0 {
+		t.Timescale = own.Timescale
+	}
+	if own.Duration != 0 {
+		t.Duration = own.Duration
+	}
+	if own.Timeline != nil {
+		t.Timeline = own.Timeline
+	}
+	return t
+}
+
+func (rep *Representation) expandTemplate(t segmentTemplate, base *url.URL, total time.Duration) error {
+	if t.Media == "" {
+		return errors.New("segment template without media")
+	}
+	timescale := max(t.Timescale, 1)
+	number := int64(1)
+	if t.StartNumber != nil {
+		number = *t.StartNumber
+	}
+	if t.Initialization != "" {
+		rep.Init = &Segment{URL: resolve(base, rep.fill(t.Initialization, 0, 0))}
+	}
+
+	add := func(time int64) {
+		rep.Segments = append(rep.Segments, Segment{URL: resolve(base, rep.fill(t.Media, number, time))})
+		number++
+	}
+	end := int64(total.Seconds() * float64(timescale))
+	switch {
+	case t.Timeline != nil:
+		var at int64
+		for i, s := range t.Timeline.S {
+			if s.T != nil {
+				at = *s.T
+			}
+			if s.D <= 0 {
+				return errors.New("segment timeline entry without duration")
+			}
+			repeat := s.R
+			if repeat < 0 {
+				// Repeats until the next entry or the end of the period.
+				until := end
+				if i+1 < len(t.Timeline.S) && t.Timeline.S[i+1].T != nil {
+					until = *t.Timeline.S[i+1].T
+				}
+				repeat = (until-at+s.D-1)/s.D - 1
+			}
+			for range repeat + 1 {
+				add(at)
+				at += s.D
+			}
+		}
+	case t.Duration > 0:
+		if total <= 0 {
+			return errors.New("segment template without a known duration")
+		}
+		count := (end + t.Duration - 1) / t.Duration
+		for i := range count {
+			add(i * t.Duration)
+		}
+	default:
+		return errors.New("segment template without duration or timeline")
+	}
+	return nil
+}
+
+var templateIdentifier = regexp.MustCompile(`\$(RepresentationID|Number|Time|Bandwidth)(%0\d+d)?\$`)
+
+// fill replaces the identifiers of a template, such as $Number%05d$.
+func (rep *Representation) fill(template string, number, time int64) string {
+	filled := templateIdentifier.ReplaceAllStringFunc(template, func(match string) string {
+		parts := templateIdentifier.FindStringSubmatch(match)
+		format := parts[2]
+		if format == "" {
+			format = "%d"
+		}
+		switch parts[1] {
+		case "RepresentationID":
+			return rep.ID
+		case "Number":
+			return fmt.Sprintf(format, number)
+		case "Time":
+			return fmt.Sprintf(format, time)
+		default:
+			return fmt.Sprintf(format, rep.Bandwidth)
+		}
+	})
+	return strings.ReplaceAll(filled, "$$", "$")
+}
+
+func (rep *Representation) readList(list *segmentList, base *url.URL) error {
+	if init := list.Initialization; init != nil {
+		rep.Init = &Segment{URL: base.String()}
+		if init.SourceURL != "" {
+			rep.Init.URL = resolve(base, init.SourceURL)
+		}
+		if err := parseRange(init.Range, rep.Init); err != nil {
+			return err
+		}
+	}
+	for _, s := range list.SegmentURLs {
+		segment := Segment{URL: base.String()}
+		if s.Media != "" {
+			segment.URL = resolve(base, s.Media)
+		}
+		if err := parseRange(s.MediaRange, &segment); err != nil {
+			return err
+		}
+		rep.Segments = append(rep.Segments, segment)
+	}
+	return nil
+}
+
+// parseRange reads a byte range such as "0-863".
+func parseRange(value string, s *Segment) error {
+	if value == "" {
+		return nil
+	}
+	first, last, ok := strings.Cut(value, "-")
+	from, err1 := strconv.ParseInt(first, 10, 64)
+	to, err2 := strconv.ParseInt(last, 10, 64)
+	if !ok || err1 != nil || err2 != nil || to < from {
+		return fmt.Errorf("invalid byte range %q", value)
+	}
+	s.Offset, s.Length = from, to-from+1
+	return nil
+}
+
+var durationPattern = regexp.MustCompile(`^P(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// ParseDuration reads an ISO 8601 duration such as "PT1H2M3.5S".
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	m := durationPattern.FindStringSubmatch(value)
+	if m == nil || value == "P" || value == "PT" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	var total float64
+	for i, unit := range []float64{24 * 3600, 3600, 60, 1} {
+		if m[i+1] != "" {
+			n, _ := strconv.ParseFloat(m[i+1], 64)
+			total += n * unit
+		}
+	}
+	return time.Duration(total * float64(time.Second)), nil
+}
+
+// withBaseURL applies the first BaseURL element of a level of the MPD.
+func withBaseURL(base *url.URL, elements []string) *url.URL {
+	if len(elements) == 0 {
+		return base
+	}
+	u, err := url.Parse(strings.TrimSpace(elements[0]))
+	if err != nil {
+		return base
+	}
+	if base == nil {
+		return u
+	}
+	return base.ResolveReference(u)
+}
+
+func resolve(base *url.URL, ref string) string {
+	u, err := url.Parse(ref)
+	if err != nil || base == nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,170 @@
+package dash
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://cdn.example.com/vod/manifest.mpd")
+	data := `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static" mediaPresentationDuration="PT10S">
+  <BaseURL>media/</BaseURL>
+  <Period duration="PT8S">
+    <AdaptationSet contentType="video" mimeType="video/mp4" codecs="avc1.64001f">
+      <SegmentTemplate media="$RepresentationID$/$Number%03d$.m4s" initialization="$RepresentationID$/init.mp4" timescale="1" duration="3"/>
+      <Representation id="720p" bandwidth="2000000" width="1280" height="720">
+        <SegmentTemplate startNumber="5"/>
+      </Representation>
+    </AdaptationSet>
+    <AdaptationSet mimeType="audio/mp4" lang="pt">
+      <Representation id="aac" bandwidth="128000" codecs="mp4a.40.2">
+        <SegmentTemplate media="audio/$Time$.m4s" timescale="1000">
+          <SegmentTimeline>
+            <S t="0" d="2000" r="2"/>
+            <S d="1000" r="-1"/>
+          </SegmentTimeline>
+        </SegmentTemplate>
+      </Representation>
+    </AdaptationSet>
+    <AdaptationSet mimeType="text/vtt">
+      <Representation id="sub" bandwidth="100">
+        <BaseURL>https://subs.example.com/pt.vtt</BaseURL>
+      </Representation>
+    </AdaptationSet>
+    <AdaptationSet mimeType="video/webm">
+      <Representation id="ranges" bandwidth="500000">
+        <BaseURL>ranges.webm</BaseURL>
+        <SegmentList>
+          <Initialization range="0-99"/>
+          <SegmentURL mediaRange="100-199"/>
+          <SegmentURL media="other.webm" mediaRange="0-49"/>
+        </SegmentList>
+      </Representation>
+    </AdaptationSet>
+    <AdaptationSet mimeType="video/mp4">
+      <ContentProtection schemeIdUri="urn:mpeg:dash:mp4protection:2011"/>
+      <Representation id="drm" bandwidth="1"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	m, err := Parse([]byte(data), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Dynamic || m.Duration != 8*time.Second {
+		t.Fatalf("manifest is dynamic %v with duration %v", m.Dynamic, m.Duration)
+	}
+
+	const root = "https://cdn.example.com/vod/media/"
+	want := []Representation{
+		{
+			ID: "720p", ContentType: "video", MimeType: "video/mp4", Codecs: "avc1.64001f", Bandwidth: 2000000, Width: 1280, Height: 720,
+			Init:     &Segment{URL: root + "720p/init.mp4"},
+			Segments: []Segment{{URL: root + "720p/005.m4s"}, {URL: root + "720p/006.m4s"}, {URL: root + "720p/007.m4s"}},
+		},
+		{
+			ID: "aac", ContentType: "audio", MimeType: "audio/mp4", Codecs: "mp4a.40.2", Language: "pt", Bandwidth: 128000,
+			Segments: []Segment{
+				{URL: root + "audio/0.m4s"}, {URL: root + "audio/2000.m4s"}, {URL: root + "audio/4000.m4s"},
+				{URL: root + "audio/6000.m4s"}, {URL: root + "audio/7000.m4s"},
+			},
+		},
+		{
+			ID: "sub", ContentType: "text", MimeType: "text/vtt", Bandwidth: 100,
+			Segments: []Segment{{URL: "https://subs.example.com/pt.vtt"}},
+		},
+		{
+			ID: "ranges", ContentType: "video", MimeType: "video/webm", Bandwidth: 500000,
+			Init:     &Segment{URL: root + "ranges.webm", Length: 100},
+			Segments: []Segment{{URL: root + "ranges.webm", Offset: 100, Length: 100}, {URL: root + "other.webm", Length: 50}},
+		},
+	}
+	if len(m.Representations) != len(want) {
+		t.Fatalf("representations = %+v", m.Representations)
+	}
+	for i, w := range want {
+		got := m.Representations[i]
+		if got.ID != w.ID || got.ContentType != w.ContentType || got.MimeType != w.MimeType || got.Codecs != w.Codecs ||
+			got.Language != w.Language || got.Bandwidth != w.Bandwidth || got.Width != w.Width || got.Height != w.Height {
+			t.Errorf("representation %d = %+v, want %+v", i, got, w)
+		}
+		if (got.Init == nil) != (w.Init == nil) || got.Init != nil && *got.Init != *w.Init {
+			t.Errorf("representation %s init = %+v, want %+v", w.ID, got.Init, w.Init)
+		}
+		if len(got.Segments) != len(w.Segments) {
+			t.Errorf("representation %s segments = %+v, want %+v", w.ID, got.Segments, w.Segments)
+			continue
+		}
+		for j := range w.Segments {
+			if got.Segments[j] != w.Segments[j] {
+				t.Errorf("representation %s segment %d = %+v, want %+v", w.ID, j, got.Segments[j], w.Segments[j])
+			}
+		}
+	}
+}
+
+func TestParseRefused(t *testing.T) {
+	period := func(body string) string {
+		return `<MPD mediaPresentationDuration="PT4S"><Period>` + body + `</Period></MPD>`
+	}
+	tests := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"not XML", "{}", ErrInvalidManifest},
+		{"no period", `<MPD/>`, ErrInvalidManifest},
+		{"bad duration", `<MPD mediaPresentationDuration="4 seconds"><Period/></MPD>`, ErrInvalidManifest},
+		{"two periods", `<MPD><Period/><Period/></MPD>`, ErrUnsupportedManifest},
+		{"only encrypted", period(`<AdaptationSet><Representation id="a"><ContentProtection/></Representation></AdaptationSet>`), ErrUnsupportedManifest},
+		{"template without media", period(`<AdaptationSet><SegmentTemplate duration="1"/><Representation id="a"/></AdaptationSet>`), ErrInvalidManifest},
+		{"template without duration", period(`<AdaptationSet><SegmentTemplate media="$Number$"/><Representation id="a"/></AdaptationSet>`), ErrInvalidManifest},
+		{"timeline entry without duration", period(`<AdaptationSet><SegmentTemplate media="$Time$"><SegmentTimeline><S t="0"/></SegmentTimeline></SegmentTemplate><Representation id="a"/></AdaptationSet>`), ErrInvalidManifest},
+		{"bad byte range", period(`<AdaptationSet><Representation id="a"><SegmentList><SegmentURL mediaRange="9-1"/></SegmentList></Representation></AdaptationSet>`), ErrInvalidManifest},
+	}
+	base, _ := url.Parse("https://cdn.example.com/manifest.mpd")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if m, err := Parse([]byte(tt.data), base); !errors.Is(err, tt.err) {
+				t.Fatalf("Parse = %+v, %v, want %v", m, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		invalid bool
+	}{
+		{value: "PT1H2M3.5S", want: time.Hour + 2*time.Minute + 3500*time.Millisecond},
+		{value: "P1DT1S", want: 24*time.Hour + time.Second},
+		{value: "PT90M", want: 90 * time.Minute},
+		{value: " PT0S ", want: 0},
+		{value: "P", invalid: true},
+		{value: "PT", invalid: true},
+		{value: " PT ", invalid: true},
+		{value: "1H", invalid: true},
+		{value: "PT1.5", invalid: true},
+		{value: "PT-1S", invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseDuration(tt.value)
+			if tt.invalid {
+				if err == nil {
+					t.Fatalf("ParseDuration(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseDuration(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
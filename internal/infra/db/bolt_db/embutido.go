@@ -0,0 +1,116 @@
+package embutido
+
+import (
+	"downloader/internal/domain"
+	"downloader/internal/infra/db/query"
+	logger "downloader/pkg/log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var log = logger.GetLogger("embutido")
+
+// EmbutidoDatabase stores each record as JSON in a bucket of a bbolt file,
+// so the data survives restarts without an external database.
+type EmbutidoDatabase[T any] struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+// Open opens the database file at path, creating it when missing, and
+// brings its schema up to date.
+func Open(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// NewEmbutidoDatabase stores records in bucket, which the migrations must
+// have created.
+func NewEmbutidoDatabase[T any](db *bolt.DB, bucket string) (*EmbutidoDatabase[T], error) {
+	err := db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(bucket)) == nil {
+			return fmt.Errorf("bucket %s does not exist", bucket)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &EmbutidoDatabase[T]{db: db, bucket: []byte(bucket)}, nil
+}
+
+func (r *EmbutidoDatabase[T]) Save(id string, v T) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", id, err)
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(r.bucket).Put([]byte(id), content)
+	})
+}
+
+func (r *EmbutidoDatabase[T]) Get(id string) (T, error) {
+	var v T
+	err := r.db.View(func(tx *bolt.Tx) error {
+		content := tx.Bucket(r.bucket).Get([]byte(id))
+		if content == nil {
+			return errors.New("not found")
+		}
+		return json.Unmarshal(content, &v)
+	})
+	return v, err
+}
+
+func (r *EmbutidoDatabase[T]) Remove(id string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(r.bucket)
+		if bucket.Get([]byte(id)) == nil {
+			return errors.New("not found")
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+func (r *EmbutidoDatabase[T]) List(q domain.Query) ([]T, error) {
+	list, err := r.all()
+	if err != nil {
+		return nil, err
+	}
+	return query.Apply(list, q)
+}
+
+func (r *EmbutidoDatabase[T]) Count(q domain.Query) (int, error) {
+	list, err := r.all()
+	if err != nil {
+		return 0, err
+	}
+	return query.Count(list, q)
+}
+
+// all decodes every record of the bucket; bbolt has no query language, so
+// filters run in memory.
+func (r *EmbutidoDatabase[T]) all() ([]T, error) {
+	var list []T
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(r.bucket).ForEach(func(k, content []byte) error {
+			var v T
+			if err := json.Unmarshal(content, &v); err != nil {
+				return fmt.Errorf("failed to decode %s: %w", k, err)
+			}
+			list = append(list, v)
+			return nil
+		})
+	})
+	return list, err
+}
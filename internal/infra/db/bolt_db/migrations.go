@@ -0,0 +1,61 @@
+package embutido
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const VideosBucket = "videos"
+
+var (
+	metaBucket    = []byte("meta")
+	schemaVersion = []byte("schema_version")
+)
+
+// migration changes the layout of the database from the previous version.
+// New migrations go at the end of the list and are never edited once
+// released.
+type migration struct {
+	name string
+	up   func(tx *bolt.Tx) error
+}
+
+var migrations = []migration{
+	{name: "create videos bucket", up: createBucket(VideosBucket)},
+}
+
+// migrate applies the migrations newer than the stored schema version, all
+// in a single transaction.
+func migrate(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return fmt.Errorf("failed to create meta bucket: %w", err)
+		}
+
+		version := 0
+		if stored := meta.Get(schemaVersion); len(stored) == 8 {
+			version = int(binary.BigEndian.Uint64(stored))
+		}
+		if version > len(migrations) {
+			return fmt.Errorf("database schema version %d is newer than this program (%d)", version, len(migrations))
+		}
+
+		for i := version; i < len(migrations); i++ {
+			if err := migrations[i].up(tx); err != nil {
+				return fmt.Errorf("migration %d (%s) failed: %w", i+1, migrations[i].name, err)
+			}
+			log.Info(fmt.Sprintf("Migração %d aplicada: %s", i+1, migrations[i].name))
+		}
+		return meta.Put(schemaVersion, binary.BigEndian.AppendUint64(nil, uint64(len(migrations))))
+	})
+}
+
+func createBucket(name string) func(tx *bolt.Tx) error {
+	return func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(name))
+		return err
+	}
+}
@@ -0,0 +1,112 @@
+package arquivo
+
+import (
+	"downloader/internal/domain"
+	"downloader/internal/infra/db/query"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ArquivoDatabase keeps every record in memory and rewrites a JSON file on
+// each change, so the data survives restarts.
+type ArquivoDatabase[T any] struct {
+	path string
+	data map[string]T
+	mu   sync.RWMutex
+}
+
+func NewArquivoDatabase[T any](path string) (*ArquivoDatabase[T], error) {
+	db := &ArquivoDatabase[T]{path: path, data: map[string]T{}}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(content, &db.data); err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	return db, nil
+}
+
+func (r *ArquivoDatabase[T]) Save(id string, v T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.data[id] = v
+	return r.flush()
+}
+
+func (r *ArquivoDatabase[T]) Get(id string) (T, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	v, ok := r.data[id]
+	var zero T
+	if !ok {
+		return zero, errors.New("not found")
+	}
+
+	return v, nil
+}
+
+func (r *ArquivoDatabase[T]) Remove(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.data[id]; !ok {
+		return errors.New("not found")
+	}
+	delete(r.data, id)
+	return r.flush()
+}
+
+func (r *ArquivoDatabase[T]) List(q domain.Query) ([]T, error) {
+	return query.Apply(r.all(), q)
+}
+
+func (r *ArquivoDatabase[T]) Count(q domain.Query) (int, error) {
+	return query.Count(r.all(), q)
+}
+
+func (r *ArquivoDatabase[T]) all() []T {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]T, 0, len(r.data))
+	for _, v := range r.data {
+		list = append(list, v)
+	}
+	return list
+}
+
+// flush writes the data to a temporary file and renames it over the
+// previous one, so a crash never leaves a truncated file behind.
+func (r *ArquivoDatabase[T]) flush() error {
+	content, err := json.Marshal(r.data)
+	if err != nil {
+		return fmt.Errorf("failed to encode data: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write data: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write data: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", r.path, err)
+	}
+	return nil
+}
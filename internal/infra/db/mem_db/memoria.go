@@ -1,6 +1,8 @@
 package memoria
 
 import (
+	"downloader/internal/domain"
+	"downloader/internal/infra/db/query"
 	"errors"
 	"sync"
 )
@@ -22,6 +24,8 @@ func (r *MemoriaDatabase[T]) Save(id string, v T) error {
 }
 
 func (r *MemoriaDatabase[T]) Get(id string) (T, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	v, ok := r.data[id]
 	var zero T
 	if !ok {
@@ -40,3 +44,21 @@ func (r *MemoriaDatabase[T]) Remove(id string) error {
 	delete(r.data, id)
 	return nil
 }
+
+func (r *MemoriaDatabase[T]) List(q domain.Query) ([]T, error) {
+	return query.Apply(r.all(), q)
+}
+
+func (r *MemoriaDatabase[T]) Count(q domain.Query) (int, error) {
+	return query.Count(r.all(), q)
+}
+
+func (r *MemoriaDatabase[T]) all() []T {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]T, 0, len(r.data))
+	for _, v := range r.data {
+		list = append(list, v)
+	}
+	return list
+}
@@ -0,0 +1,190 @@
+package postgres
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// queryTimeout bounds every statement, since the Database interface carries
+// no context of its own.
+const queryTimeout = 10 * time.Second
+
+// PostgresDatabase stores records of type T in one table. The first column
+// is the primary key.
+type PostgresDatabase[T any] struct {
+	pool    *pgxpool.Pool
+	table   string
+	columns []string
+	// fields maps the struct fields usable in a domain.Query to SQL
+	// expressions.
+	fields map[string]string
+	values func(T) []any
+	scan   func(row pgx.Row) (T, error)
+}
+
+func (r *PostgresDatabase[T]) Save(id string, v T) error {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	placeholders := make([]string, len(r.columns))
+	updates := make([]string, 0, len(r.columns)-1)
+	for i, column := range r.columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		if i > 0 {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+		}
+	}
+	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s",
+		r.table, strings.Join(r.columns, ", "), strings.Join(placeholders, ", "), r.columns[0], strings.Join(updates, ", "))
+
+	args := append([]any{id}, r.values(v)...)
+	if _, err := r.pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed to save %s: %w", id, err)
+	}
+	return nil
+}
+
+func (r *PostgresDatabase[T]) Get(id string) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	sql := fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1", strings.Join(r.columns, ", "), r.table, r.columns[0])
+	v, err := r.scan(r.pool.QueryRow(ctx, sql, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return v, errors.New("not found")
+	}
+	if err != nil {
+		return v, fmt.Errorf("failed to get %s: %w", id, err)
+	}
+	return v, nil
+}
+
+func (r *PostgresDatabase[T]) Remove(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s = $1", r.table, r.columns[0]), id)
+	if err != nil {
+		return fmt.Errorf("failed to remove %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("not found")
+	}
+	return nil
+}
+
+func (r *PostgresDatabase[T]) List(q domain.Query) ([]T, error) {
+	where, args, err := r.where(q.Filters)
+	if err != nil {
+		return nil, err
+	}
+	sql := fmt.Sprintf("SELECT %s FROM %s%s", strings.Join(r.columns, ", "), r.table, where)
+
+	if q.OrderBy != "" {
+		column, ok := r.fields[q.OrderBy]
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot order by %s", domain.ErrInvalidQuery, q.OrderBy)
+		}
+		sql += " ORDER BY " + column
+		if q.Descending {
+			sql += " DESC"
+		}
+	}
+	if q.Offset < 0 || q.Limit < 0 {
+		return nil, fmt.Errorf("%w: negative offset or limit", domain.ErrInvalidQuery)
+	}
+	if q.Limit > 0 {
+		sql += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+	if q.Offset > 0 {
+		sql += fmt.Sprintf(" OFFSET %d", q.Offset)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", r.table, err)
+	}
+	defer rows.Close()
+
+	list := []T{}
+	for rows.Next() {
+		v, err := r.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", r.table, err)
+		}
+		list = append(list, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", r.table, err)
+	}
+	return list, nil
+}
+
+func (r *PostgresDatabase[T]) Count(q domain.Query) (int, error) {
+	where, args, err := r.where(q.Filters)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	var count int
+	if err := r.pool.QueryRow(ctx, fmt.Sprintf("SELECT count(*) FROM %s%s", r.table, where), args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count %s: %w", r.table, err)
+	}
+	return count, nil
+}
+
+// Health reports whether the database answers.
+func (r *PostgresDatabase[T]) Health(ctx context.Context) error {
+	return r.pool.Ping(ctx)
+}
+
+// where translates the filters into a WHERE clause and its arguments.
+func (r *PostgresDatabase[T]) where(filters []domain.Filter) (string, []any, error) {
+	if len(filters) == 0 {
+		return "", nil, nil
+	}
+
+	clauses := make([]string, 0, len(filters))
+	args := make([]any, 0, len(filters))
+	for _, f := range filters {
+		column, ok := r.fields[f.Field]
+		if !ok {
+			return "", nil, fmt.Errorf("%w: unknown field %s", domain.ErrInvalidQuery, f.Field)
+		}
+
+		var operator string
+		value := f.Value
+		switch f.Op {
+		case domain.OpEqual:
+			operator = "="
+		case domain.OpGreaterOrEqual:
+			operator = ">="
+		case domain.OpLessOrEqual:
+			operator = "<="
+		case domain.OpContains:
+			operator = "ILIKE"
+			value = "%" + likeEscaper.Replace(fmt.Sprint(f.Value)) + "%"
+		default:
+			return "", nil, fmt.Errorf("%w: unknown operator %q", domain.ErrInvalidQuery, f.Op)
+		}
+
+		args = append(args, value)
+		clauses = append(clauses, fmt.Sprintf("%s %s $%d", column, operator, len(args)))
+	}
+	return " WHERE " + strings.Join(clauses, " AND "), args, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
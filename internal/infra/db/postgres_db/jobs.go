@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// JobDatabase stores the jobs shared by every instance. Each unfinished job
+// belongs to the instance that saved or claimed it last, which holds it
+// while it keeps renewing its lease.
+type JobDatabase struct {
+	*PostgresDatabase[domain.Job]
+	owner string
+}
+
+// NewJobDatabase stores jobs with their video as JSON, on behalf of the
+// instance named owner. The fields of the video used to filter jobs are
+// reachable through the JSON document.
+func NewJobDatabase(pool *pgxpool.Pool, owner string) *JobDatabase {
+	return &JobDatabase{
+		owner: owner,
+		PostgresDatabase: &PostgresDatabase[domain.Job]{
+			pool:  pool,
+			table: "jobs",
+			columns: []string{"id", "video", "priority", "state", "bytes_done", "bytes_total", "speed",
+				"eta", "error", "video_id", "created_at", "updated_at", "owner", "lease_until"},
+			fields: map[string]string{
+				"ID":               "id",
+				"Priority":         "priority",
+				"State":            "state",
+				"BytesDone":        "bytes_done",
+				"BytesTotal":       "bytes_total",
+				"Error":            "error",
+				"VideoID":          "video_id",
+				"CreatedAt":        "created_at",
+				"UpdatedAt":        "updated_at",
+				"Video.URL":        "video->>'URL'",
+				"Video.Requester":  "video->>'Requester'",
+				"Video.PlaylistID": "video->>'PlaylistID'",
+			},
+			values: func(j domain.Job) []any {
+				return []any{j.Video, j.Priority, string(j.State), j.BytesDone, j.BytesTotal, j.Speed,
+					int64(j.ETA), j.Error, j.VideoID, j.CreatedAt, j.UpdatedAt, owner, time.Now().Add(domain.JobLease)}
+			},
+			scan: func(row pgx.Row) (domain.Job, error) {
+				var j domain.Job
+				var state, jobOwner string
+				var eta int64
+				var leaseUntil time.Time
+				err := row.Scan(&j.ID, &j.Video, &j.Priority, &state, &j.BytesDone, &j.BytesTotal, &j.Speed,
+					&eta, &j.Error, &j.VideoID, &j.CreatedAt, &j.UpdatedAt, &jobOwner, &leaseUntil)
+				j.State, j.ETA = domain.JobState(state), time.Duration(eta)
+				return j, err
+			},
+		},
+	}
+}
+
+// unfinished matches the jobs that did not reach a final state.
+const unfinished = "state NOT IN ('done', 'failed', 'cancelled')"
+
+// ClaimJobs takes the unfinished jobs of this instance and the ones whose
+// owner stopped renewing them. Rows another instance is claiming at the
+// same time are skipped rather than waited for.
+func (r *JobDatabase) ClaimJobs() ([]domain.Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	now := time.Now()
+	sql := fmt.Sprintf(`UPDATE jobs SET owner = $1, lease_until = $2 WHERE id IN (
+			SELECT id FROM jobs WHERE %s AND (owner = $1 OR owner = '' OR lease_until < $3)
+			FOR UPDATE SKIP LOCKED)
+		RETURNING %s`, unfinished, strings.Join(r.columns, ", "))
+	rows, err := r.pool.Query(ctx, sql, r.owner, now.Add(domain.JobLease), now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []domain.Job{}
+	for rows.Next() {
+		job, err := r.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read jobs: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// RenewJobs extends the lease of the unfinished jobs of this instance.
+func (r *JobDatabase) RenewJobs() error {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	sql := fmt.Sprintf("UPDATE jobs SET lease_until = $2 WHERE owner = $1 AND %s", unfinished)
+	if _, err := r.pool.Exec(ctx, sql, r.owner, time.Now().Add(domain.JobLease)); err != nil {
+		return fmt.Errorf("failed to renew jobs: %w", err)
+	}
+	return nil
+}
+
+// RequestCancel flags the job id for the instance holding it, which cancels
+// it when it next renews its lease. The flag is not part of the saved
+// columns, so the owner saving the job meanwhile keeps it.
+func (r *JobDatabase) RequestCancel(id string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	sql := fmt.Sprintf("UPDATE jobs SET cancel_requested = true WHERE id = $1 AND %s", unfinished)
+	tag, err := r.pool.Exec(ctx, sql, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to request cancel: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// CancelRequests returns the unfinished jobs of this instance flagged by
+// RequestCancel.
+func (r *JobDatabase) CancelRequests() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	sql := fmt.Sprintf("SELECT id FROM jobs WHERE owner = $1 AND cancel_requested AND %s", unfinished)
+	rows, err := r.pool.Query(ctx, sql, r.owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cancel requests: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cancel requests: %w", err)
+	}
+	return ids, nil
+}
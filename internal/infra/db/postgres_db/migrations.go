@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationLock is the advisory lock held while migrating, so instances
+// starting together do not apply the same migration twice.
+const migrationLock = 7_346_021
+
+// migration changes the schema from the previous version. New migrations
+// go at the end of the list and are never edited once released.
+type migration struct {
+	name string
+	sql  string
+}
+
+var migrations = []migration{
+	{name: "create videos table", sql: `
+		CREATE TABLE videos (
+			id             text PRIMARY KEY,
+			url            text NOT NULL,
+			title          text NOT NULL DEFAULT '',
+			filename       text NOT NULL DEFAULT '',
+			requester      text NOT NULL DEFAULT '',
+			format         text NOT NULL DEFAULT '',
+			audio_only     boolean NOT NULL DEFAULT false,
+			extension      text NOT NULL DEFAULT '',
+			mime_type      text NOT NULL DEFAULT '',
+			source_id      text NOT NULL DEFAULT '',
+			playlist_id    text NOT NULL DEFAULT '',
+			playlist_title text NOT NULL DEFAULT '',
+			created_at     timestamptz NOT NULL
+		);
+		CREATE INDEX videos_requester_created_at ON videos (requester, created_at);
+		CREATE INDEX videos_created_at ON videos (created_at);`},
+	{name: "create jobs table", sql: `
+		CREATE TABLE jobs (
+			id          text PRIMARY KEY,
+			video       jsonb NOT NULL,
+			priority    integer NOT NULL DEFAULT 0,
+			state       text NOT NULL,
+			bytes_done  bigint NOT NULL DEFAULT 0,
+			bytes_total bigint NOT NULL DEFAULT 0,
+			speed       double precision NOT NULL DEFAULT 0,
+			eta         bigint NOT NULL DEFAULT 0,
+			error       text NOT NULL DEFAULT '',
+			video_id    text NOT NULL DEFAULT '',
+			created_at  timestamptz NOT NULL,
+			updated_at  timestamptz NOT NULL
+		);
+		CREATE INDEX jobs_state ON jobs (state);`},
+	{name: "add video metadata", sql: `
+		ALTER TABLE videos
+			ADD COLUMN metadata     jsonb NOT NULL DEFAULT '{}',
+			ADD COLUMN stream       jsonb NOT NULL DEFAULT '{}',
+			ADD COLUMN path         text NOT NULL DEFAULT '',
+			ADD COLUMN size         bigint NOT NULL DEFAULT 0,
+			ADD COLUMN checksum     text NOT NULL DEFAULT '',
+			ADD COLUMN requested_at timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00+00',
+			ADD COLUMN started_at   timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00+00',
+			ADD COLUMN finished_at  timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00+00';`},
+	{name: "add video subtitles", sql: `
+		ALTER TABLE videos ADD COLUMN subtitles jsonb NOT NULL DEFAULT '[]';`},
+	{name: "add video thumbnail", sql: `
+		ALTER TABLE videos
+			ADD COLUMN embed_thumbnail boolean NOT NULL DEFAULT false,
+			ADD COLUMN thumbnail       text NOT NULL DEFAULT '',
+			ADD COLUMN thumbnail_type  text NOT NULL DEFAULT '';`},
+	{name: "add video clip range", sql: `
+		ALTER TABLE videos
+			ADD COLUMN clip_start bigint NOT NULL DEFAULT 0,
+			ADD COLUMN clip_end   bigint NOT NULL DEFAULT 0;`},
+	{name: "add video chapters", sql: `
+		ALTER TABLE videos
+			ADD COLUMN split_chapters boolean NOT NULL DEFAULT false,
+			ADD COLUMN parent_id      text NOT NULL DEFAULT '',
+			ADD COLUMN chapter_index  integer NOT NULL DEFAULT 0;
+		CREATE INDEX videos_parent_id ON videos (parent_id);`},
+	{name: "add video live recording", sql: `
+		ALTER TABLE videos
+			ADD COLUMN live_from_start   boolean NOT NULL DEFAULT false,
+			ADD COLUMN live_max_duration bigint NOT NULL DEFAULT 0,
+			ADD COLUMN live              boolean NOT NULL DEFAULT false;`},
+	{name: "add job owner", sql: `
+		ALTER TABLE jobs
+			ADD COLUMN owner       text NOT NULL DEFAULT '',
+			ADD COLUMN lease_until timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00+00';
+		CREATE INDEX jobs_owner ON jobs (owner);`},
+	{name: "add job cancel request", sql: `
+		ALTER TABLE jobs ADD COLUMN cancel_requested boolean NOT NULL DEFAULT false;`},
+}
+
+// migrate applies the migrations newer than the recorded schema version,
+// each in its own transaction.
+func migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLock); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLock)
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    integer PRIMARY KEY,
+		name       text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var version int
+	if err := conn.QueryRow(ctx, "SELECT coalesce(max(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than this program (%d)", version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to start migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(ctx, migrations[i].sql); err != nil {
+			tx.Rollback(ctx)
+			return fmt.Errorf("migration %d (%s) failed: %w", i+1, migrations[i].name, err)
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", i+1, migrations[i].name); err != nil {
+			tx.Rollback(ctx)
+			return fmt.Errorf("migration %d (%s) failed: %w", i+1, migrations[i].name, err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", i+1, migrations[i].name, err)
+		}
+		log.Info(fmt.Sprintf("Migração %d aplicada: %s", i+1, migrations[i].name))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"downloader/pkg/config"
+	logger "downloader/pkg/log"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var log = logger.GetLogger("postgres")
+
+const (
+	connectTimeout    = 10 * time.Second
+	healthCheckPeriod = 30 * time.Second
+)
+
+// Open connects a pool to the database described by cfg and brings its
+// schema up to date.
+func Open(ctx context.Context, cfg config.ConfigDatabase) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(connString(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("invalid database config: %w", err)
+	}
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = int32(cfg.MaxConns)
+	}
+	poolConfig.HealthCheckPeriod = healthCheckPeriod
+
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pool: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to %s: %w", poolConfig.ConnConfig.Host, err)
+	}
+	if err := migrate(ctx, pool); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	log.Info(fmt.Sprintf("Conectado ao postgres em %s", poolConfig.ConnConfig.Host))
+	return pool, nil
+}
+
+// connString accepts a full postgres:// URL in cfg.URL, or builds one when
+// it only holds the host.
+func connString(cfg config.ConfigDatabase) string {
+	if strings.Contains(cfg.URL, "://") {
+		return cfg.URL
+	}
+
+	u := url.URL{Scheme: "postgres", Host: net.JoinHostPort(cfg.URL, cfg.Port), Path: "/" + cfg.Name}
+	if cfg.User != "" {
+		u.User = url.UserPassword(cfg.User, cfg.Psw)
+	}
+	return u.String()
+}
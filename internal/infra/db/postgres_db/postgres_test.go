@@ -0,0 +1,257 @@
+package postgres
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"downloader/pkg/config"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// openTestDatabase connects to the postgres:// URL in DB_URL, skipping the
+// test when it is not set. Each test gets a schema of its own, migrated
+// from scratch and dropped at the end. `make test-postgres` runs these
+// tests against the container of docker-compose.dev.yml.
+func openTestDatabase(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	dbURL := os.Getenv("DB_URL")
+	if dbURL == "" {
+		t.Skip("DB_URL not set")
+	}
+	ctx := context.Background()
+
+	admin, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(admin.Close)
+	schema := "test_" + uuid.NewString()[:8]
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE") })
+
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+
+	pool, err := Open(ctx, config.ConfigDatabase{URL: u.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestMigrate(t *testing.T) {
+	pool := openTestDatabase(t)
+	ctx := context.Background()
+
+	version := func() int {
+		var v int
+		if err := pool.QueryRow(ctx, "SELECT max(version) FROM schema_migrations").Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	if got := version(); got != len(migrations) {
+		t.Fatalf("schema version = %d, want %d", got, len(migrations))
+	}
+
+	// Migrating an up to date schema changes nothing.
+	if err := migrate(ctx, pool); err != nil {
+		t.Fatal(err)
+	}
+	if got := version(); got != len(migrations) {
+		t.Fatalf("schema version after a second migrate = %d, want %d", got, len(migrations))
+	}
+
+	if _, err := pool.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, 'from the future')", len(migrations)+1); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate(ctx, pool); err == nil {
+		t.Fatal("migrate accepted a schema newer than the program")
+	}
+}
+
+func TestVideoDatabase(t *testing.T) {
+	videos := NewVideoDatabase(openTestDatabase(t))
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, requester := range []string{"ana", "bia", "ana"} {
+		v := domain.Video{
+			ID:        fmt.Sprintf("v%d", i),
+			URL:       fmt.Sprintf("https://example.com/%d", i),
+			Title:     fmt.Sprintf("Vídeo %d", i),
+			Requester: requester,
+			ClipEnd:   time.Duration(i) * time.Minute,
+			Metadata:  domain.VideoMetadata{Author: "Canal " + requester, Duration: time.Hour},
+			Size:      int64(i * 100),
+			CreatedAt: created.Add(time.Duration(i) * time.Hour),
+		}
+		// The first video asked for no subtitles.
+		if i > 0 {
+			v.Subtitles = []domain.Subtitle{{Language: "pt"}}
+		}
+		if err := videos.Save(v.ID, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := videos.Get("v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Requester != "bia" || got.ClipEnd != time.Minute || got.Metadata.Author != "Canal bia" ||
+		len(got.Subtitles) != 1 || !got.CreatedAt.Equal(created.Add(time.Hour)) {
+		t.Fatalf("Get returned %+v", got)
+	}
+
+	if noSubtitles, err := videos.Get("v0"); err != nil || len(noSubtitles.Subtitles) != 0 {
+		t.Fatalf("Get of a video without subtitles = %+v, %v", noSubtitles, err)
+	}
+
+	// Saving an existing ID updates it.
+	got.Title = "Renomeado"
+	if err := videos.Save(got.ID, got); err != nil {
+		t.Fatal(err)
+	}
+	if renamed, _ := videos.Get("v1"); renamed.Title != "Renomeado" {
+		t.Fatalf("title after update = %q", renamed.Title)
+	}
+
+	cases := []struct {
+		name  string
+		query domain.Query
+		want  []string
+		count int
+	}{
+		{"everything by date", domain.Query{OrderBy: "CreatedAt"}, []string{"v0", "v1", "v2"}, 3},
+		{"descending", domain.Query{OrderBy: "CreatedAt", Descending: true}, []string{"v2", "v1", "v0"}, 3},
+		{"page", domain.Query{OrderBy: "CreatedAt", Offset: 1, Limit: 1}, []string{"v1"}, 3},
+		{"equal", domain.Query{Filters: []domain.Filter{{Field: "Requester", Op: domain.OpEqual, Value: "ana"}}, OrderBy: "ID"}, []string{"v0", "v2"}, 2},
+		{"contains ignoring case", domain.Query{Filters: []domain.Filter{{Field: "Title", Op: domain.OpContains, Value: "RENOM"}}}, []string{"v1"}, 1},
+		{"range", domain.Query{Filters: []domain.Filter{{Field: "Size", Op: domain.OpGreaterOrEqual, Value: 100}}, OrderBy: "ID"}, []string{"v1", "v2"}, 2},
+		{"json field", domain.Query{Filters: []domain.Filter{{Field: "Metadata.Author", Op: domain.OpEqual, Value: "Canal bia"}}}, []string{"v1"}, 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			list, err := videos.List(c.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, len(list))
+			for i, v := range list {
+				ids[i] = v.ID
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(c.want) {
+				t.Fatalf("List = %v, want %v", ids, c.want)
+			}
+
+			count, err := videos.Count(c.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != c.count {
+				t.Fatalf("Count = %d, want %d", count, c.count)
+			}
+		})
+	}
+
+	if _, err := videos.List(domain.Query{OrderBy: "Unknown"}); err == nil {
+		t.Fatal("List accepted an unknown field")
+	}
+	if err := videos.Remove("v0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := videos.Get("v0"); err == nil {
+		t.Fatal("Get found a removed video")
+	}
+	if err := videos.Remove("v0"); err == nil {
+		t.Fatal("Remove of a missing video succeeded")
+	}
+}
+
+func TestJobDatabaseClaims(t *testing.T) {
+	pool := openTestDatabase(t)
+	first, second := NewJobDatabase(pool, "first"), NewJobDatabase(pool, "second")
+
+	for _, job := range []domain.Job{
+		{ID: "running", State: domain.JobDownloading, Video: domain.Video{Requester: "ana"}, ETA: time.Minute},
+		{ID: "done", State: domain.JobDone},
+	} {
+		if err := first.Save(job.ID, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := second.Get("running")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.State != domain.JobDownloading || got.ETA != time.Minute || got.Video.Requester != "ana" {
+		t.Fatalf("Get returned %+v", got)
+	}
+	jobs, err := second.List(domain.Query{Filters: []domain.Filter{{Field: "Video.Requester", Op: domain.OpEqual, Value: "ana"}}})
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("List by requester = %v, %v", jobs, err)
+	}
+
+	claimed := func(store *JobDatabase) []string {
+		t.Helper()
+		jobs, err := store.ClaimJobs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, job := range jobs {
+			ids = append(ids, job.ID)
+		}
+		return ids
+	}
+
+	if ids := claimed(second); len(ids) != 0 {
+		t.Fatalf("second claimed %v while first holds them", ids)
+	}
+	if ids := claimed(first); fmt.Sprint(ids) != "[running]" {
+		t.Fatalf("first claimed %v, want its unfinished job", ids)
+	}
+	if err := first.RenewJobs(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A cancel request reaches the owner, and only for unfinished jobs.
+	if ok, err := second.RequestCancel("running"); err != nil || !ok {
+		t.Fatalf("RequestCancel(running) = %v, %v", ok, err)
+	}
+	if ok, err := second.RequestCancel("done"); err != nil || ok {
+		t.Fatalf("RequestCancel(done) = %v, %v, want false", ok, err)
+	}
+	if ids, err := first.CancelRequests(); err != nil || fmt.Sprint(ids) != "[running]" {
+		t.Fatalf("first cancel requests = %v, %v, want [running]", ids, err)
+	}
+	if ids, err := second.CancelRequests(); err != nil || len(ids) != 0 {
+		t.Fatalf("second cancel requests = %v, %v, want none", ids, err)
+	}
+
+	// Once first stops renewing, second takes the job over.
+	if _, err := pool.Exec(context.Background(), "UPDATE jobs SET lease_until = now() - interval '1 second'"); err != nil {
+		t.Fatal(err)
+	}
+	if ids := claimed(second); fmt.Sprint(ids) != "[running]" {
+		t.Fatalf("second claimed %v, want the abandoned job", ids)
+	}
+	if ids := claimed(first); len(ids) != 0 {
+		t.Fatalf("first claimed %v back from second", ids)
+	}
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"downloader/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func NewVideoDatabase(pool *pgxpool.Pool) *PostgresDatabase[domain.Video] {
+	return &PostgresDatabase[domain.Video]{
+		pool:  pool,
+		table: "videos",
+		columns: []string{"id", "url", "title", "filename", "requester", "format", "audio_only",
+			"extension", "mime_type", "source_id", "playlist_id", "playlist_title", "created_at",
+			"metadata", "stream", "path", "size", "checksum", "requested_at", "started_at", "finished_at",
+			"subtitles", "embed_thumbnail", "thumbnail", "thumbnail_type",
+			"clip_start", "clip_end", "split_chapters", "parent_id", "chapter_index",
+			"live_from_start", "live_max_duration", "live"},
+		fields: map[string]string{
+			"ID":            "id",
+			"URL":           "url",
+			"Title":         "title",
+			"Filename":      "filename",
+			"Requester":     "requester",
+			"Format":        "format",
+			"AudioOnly":     "audio_only",
+			"Extension":     "extension",
+			"MimeType":      "mime_type",
+			"SourceID":      "source_id",
+			"PlaylistID":    "playlist_id",
+			"PlaylistTitle": "playlist_title",
+			"CreatedAt":     "created_at",
+			"Path":          "path",
+			"Size":          "size",
+			"Checksum":      "checksum",
+			"RequestedAt":   "requested_at",
+			"StartedAt":     "started_at",
+			"FinishedAt":    "finished_at",
+			"Thumbnail":     "thumbnail",
+			"ParentID":      "parent_id",
+			"ChapterIndex":  "chapter_index",
+			"Live":          "live",
+			// Fields inside the JSON columns compare as text.
+			"Metadata.Author":    "metadata->>'Author'",
+			"Metadata.ChannelID": "metadata->>'ChannelID'",
+			"Stream.Resolution":  "stream->>'Resolution'",
+		},
+		values: func(v domain.Video) []any {
+			// pgx encodes a nil slice as NULL, which the column refuses.
+			if v.Subtitles == nil {
+				v.Subtitles = []domain.Subtitle{}
+			}
+			return []any{v.URL, v.Title, v.Filename, v.Requester, v.Format, v.AudioOnly,
+				v.Extension, v.MimeType, v.SourceID, v.PlaylistID, v.PlaylistTitle, v.CreatedAt,
+				v.Metadata, v.Stream, v.Path, v.Size, v.Checksum, v.RequestedAt, v.StartedAt, v.FinishedAt,
+				v.Subtitles, v.EmbedThumbnail, v.Thumbnail, v.ThumbnailType,
+				v.ClipStart, v.ClipEnd, v.SplitChapters, v.ParentID, v.ChapterIndex,
+				v.LiveFromStart, v.LiveMaxDuration, v.Live}
+		},
+		scan: func(row pgx.Row) (domain.Video, error) {
+			var v domain.Video
+			err := row.Scan(&v.ID, &v.URL, &v.Title, &v.Filename, &v.Requester, &v.Format, &v.AudioOnly,
+				&v.Extension, &v.MimeType, &v.SourceID, &v.PlaylistID, &v.PlaylistTitle, &v.CreatedAt,
+				&v.Metadata, &v.Stream, &v.Path, &v.Size, &v.Checksum, &v.RequestedAt, &v.StartedAt, &v.FinishedAt,
+				&v.Subtitles, &v.EmbedThumbnail, &v.Thumbnail, &v.ThumbnailType,
+				&v.ClipStart, &v.ClipEnd, &v.SplitChapters, &v.ParentID, &v.ChapterIndex,
+				&v.LiveFromStart, &v.LiveMaxDuration, &v.Live)
+			return v, err
+		},
+	}
+}
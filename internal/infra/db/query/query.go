@@ -0,0 +1,204 @@
+// Package query runs a domain.Query over records held in memory, for the
+// databases that have no query language of their own.
+package query
+
+import (
+	"cmp"
+	"downloader/internal/domain"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Apply returns the records of items selected by q, in its order and page.
+func Apply[T any](items []T, q domain.Query) ([]T, error) {
+	matched, err := filter(items, q.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.OrderBy != "" {
+		if err := sortBy(matched, q.OrderBy, q.Descending); err != nil {
+			return nil, err
+		}
+	}
+
+	if q.Offset < 0 || q.Limit < 0 {
+		return nil, fmt.Errorf("%w: negative offset or limit", domain.ErrInvalidQuery)
+	}
+	matched = matched[min(q.Offset, len(matched)):]
+	if q.Limit > 0 {
+		matched = matched[:min(q.Limit, len(matched))]
+	}
+	return matched, nil
+}
+
+// Count returns how many records of items match the filters of q,
+// ignoring its pagination.
+func Count[T any](items []T, q domain.Query) (int, error) {
+	matched, err := filter(items, q.Filters)
+	return len(matched), err
+}
+
+func filter[T any](items []T, filters []domain.Filter) ([]T, error) {
+	recordType := reflect.TypeOf((*T)(nil)).Elem()
+	for _, f := range filters {
+		if err := validate(recordType, f); err != nil {
+			return nil, err
+		}
+	}
+
+	matched := make([]T, 0, len(items))
+	for _, item := range items {
+		ok, err := matches(reflect.ValueOf(item), filters)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, item)
+		}
+	}
+	return matched, nil
+}
+
+func matches(record reflect.Value, filters []domain.Filter) (bool, error) {
+	for _, f := range filters {
+		value := fieldValue(record, f.Field)
+		if f.Op == domain.OpContains {
+			if !strings.Contains(strings.ToLower(value.String()), strings.ToLower(fmt.Sprint(f.Value))) {
+				return false, nil
+			}
+			continue
+		}
+
+		order := compare(value, reflect.ValueOf(f.Value).Convert(value.Type()))
+		switch f.Op {
+		case domain.OpEqual:
+			if order != 0 {
+				return false, nil
+			}
+		case domain.OpGreaterOrEqual:
+			if order < 0 {
+				return false, nil
+			}
+		case domain.OpLessOrEqual:
+			if order > 0 {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+func sortBy[T any](items []T, field string, descending bool) error {
+	recordType := reflect.TypeOf((*T)(nil)).Elem()
+	fieldType, err := fieldType(recordType, field)
+	if err != nil {
+		return err
+	}
+	if !orderable(fieldType) {
+		return fmt.Errorf("%w: cannot order by %s", domain.ErrInvalidQuery, field)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		order := compare(fieldValue(reflect.ValueOf(items[i]), field), fieldValue(reflect.ValueOf(items[j]), field))
+		if descending {
+			return order > 0
+		}
+		return order < 0
+	})
+	return nil
+}
+
+// validate checks that the filter names an existing field and that its
+// value can be compared with it.
+func validate(recordType reflect.Type, f domain.Filter) error {
+	fieldType, err := fieldType(recordType, f.Field)
+	if err != nil {
+		return err
+	}
+
+	switch f.Op {
+	case domain.OpContains:
+		if fieldType.Kind() != reflect.String {
+			return fmt.Errorf("%w: %s is not a text field", domain.ErrInvalidQuery, f.Field)
+		}
+		return nil
+	case domain.OpEqual, domain.OpGreaterOrEqual, domain.OpLessOrEqual:
+	default:
+		return fmt.Errorf("%w: unknown operator %q", domain.ErrInvalidQuery, f.Op)
+	}
+
+	// Numbers convert to strings as runes, which is never what is meant.
+	valueType := reflect.TypeOf(f.Value)
+	if !orderable(fieldType) || valueType == nil || !valueType.ConvertibleTo(fieldType) ||
+		(fieldType.Kind() == reflect.String) != (valueType.Kind() == reflect.String) {
+		return fmt.Errorf("%w: cannot compare %s with %v", domain.ErrInvalidQuery, f.Field, f.Value)
+	}
+	return nil
+}
+
+func fieldType(recordType reflect.Type, path string) (reflect.Type, error) {
+	t := recordType
+	for _, name := range strings.Split(path, ".") {
+		if t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%w: unknown field %s", domain.ErrInvalidQuery, path)
+		}
+		field, ok := t.FieldByName(name)
+		if !ok || !field.IsExported() {
+			return nil, fmt.Errorf("%w: unknown field %s", domain.ErrInvalidQuery, path)
+		}
+		t = field.Type
+	}
+	return t, nil
+}
+
+// fieldValue returns the field at path, which must have been validated.
+func fieldValue(record reflect.Value, path string) reflect.Value {
+	for _, name := range strings.Split(path, ".") {
+		record = record.FieldByName(name)
+	}
+	return record
+}
+
+func orderable(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// compare orders two values of the same comparable type.
+func compare(a, b reflect.Value) int {
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time))
+	}
+
+	switch a.Kind() {
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case reflect.Bool:
+		switch {
+		case a.Bool() == b.Bool():
+			return 0
+		case b.Bool():
+			return -1
+		}
+		return 1
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(a.Uint(), b.Uint())
+	}
+	return cmp.Compare(a.Int(), b.Int())
+}
@@ -0,0 +1,184 @@
+package query
+
+import (
+	"downloader/internal/domain"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type owner struct {
+	Name string
+}
+
+type record struct {
+	ID      string
+	Title   string
+	Size    int64
+	Rating  float64
+	Views   uint
+	Live    bool
+	Created time.Time
+	Owner   owner
+	Tags    []string
+	private string
+}
+
+var base = time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+
+var records = []record{
+	{ID: "a", Title: "Aula de Go", Size: 30, Rating: 4.5, Views: 7, Live: false, Created: base.Add(2 * time.Hour), Owner: owner{"ana"}},
+	{ID: "b", Title: "Podcast", Size: 10, Rating: 3, Views: 70, Live: true, Created: base, Owner: owner{"bia"}},
+	{ID: "c", Title: "go em produção", Size: 20, Rating: 5, Views: 1, Live: false, Created: base.Add(time.Hour), Owner: owner{"ana"}},
+	{ID: "d", Title: "Show", Size: 20, Rating: 1.5, Views: 30, Live: true, Created: base.Add(3 * time.Hour), Owner: owner{"caio"}},
+}
+
+func ids(items []record) string {
+	s := ""
+	for _, item := range items {
+		s += item.ID
+	}
+	return s
+}
+
+func TestApplyOrder(t *testing.T) {
+	// Ties keep the order of the records, and strings order by bytes.
+	tests := []struct {
+		field      string
+		ascending  string
+		descending string
+	}{
+		{"ID", "abcd", "dcba"},
+		{"Title", "abdc", "cdba"},
+		{"Size", "bcda", "acdb"},
+		{"Rating", "dbac", "cabd"},
+		{"Views", "cadb", "bdac"},
+		{"Live", "acbd", "bdac"},
+		{"Created", "bcad", "dacb"},
+		{"Owner.Name", "acbd", "dbac"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got, err := Apply(records, domain.Query{OrderBy: tt.field})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids(got) != tt.ascending {
+				t.Errorf("ascending = %s, want %s", ids(got), tt.ascending)
+			}
+
+			got, err = Apply(records, domain.Query{OrderBy: tt.field, Descending: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids(got) != tt.descending {
+				t.Errorf("descending = %s, want %s", ids(got), tt.descending)
+			}
+		})
+	}
+}
+
+func TestApplyPage(t *testing.T) {
+	tests := []struct {
+		offset, limit int
+		want          string
+	}{
+		{0, 0, "abcd"},
+		{0, 2, "ab"},
+		{1, 2, "bc"},
+		{3, 2, "d"},
+		{4, 0, ""},
+		{10, 0, ""},
+		{10, 5, ""},
+		{2, 10, "cd"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("offset %d limit %d", tt.offset, tt.limit), func(t *testing.T) {
+			got, err := Apply(records, domain.Query{OrderBy: "ID", Offset: tt.offset, Limit: tt.limit})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids(got) != tt.want {
+				t.Errorf("page = %q, want %q", ids(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyFilters(t *testing.T) {
+	eq := func(field string, value any) domain.Filter {
+		return domain.Filter{Field: field, Op: domain.OpEqual, Value: value}
+	}
+	tests := []struct {
+		name    string
+		filters []domain.Filter
+		want    string
+	}{
+		{"none", nil, "abcd"},
+		{"equal text", []domain.Filter{eq("Owner.Name", "ana")}, "ac"},
+		{"equal bool", []domain.Filter{eq("Live", true)}, "bd"},
+		{"equal int from another int type", []domain.Filter{eq("Size", 20)}, "cd"},
+		{"contains ignores case", []domain.Filter{{Field: "Title", Op: domain.OpContains, Value: "GO"}}, "ac"},
+		{"range", []domain.Filter{
+			{Field: "Size", Op: domain.OpGreaterOrEqual, Value: 15},
+			{Field: "Size", Op: domain.OpLessOrEqual, Value: 25},
+		}, "cd"},
+		{"time range", []domain.Filter{
+			{Field: "Created", Op: domain.OpGreaterOrEqual, Value: base.Add(time.Hour)},
+			{Field: "Created", Op: domain.OpLessOrEqual, Value: base.Add(2 * time.Hour)},
+		}, "ac"},
+		{"every filter must match", []domain.Filter{eq("Owner.Name", "ana"), eq("Size", 20)}, "c"},
+		{"float and uint", []domain.Filter{
+			{Field: "Rating", Op: domain.OpGreaterOrEqual, Value: 3.0},
+			{Field: "Views", Op: domain.OpLessOrEqual, Value: 10},
+		}, "ac"},
+		{"nothing matches", []domain.Filter{eq("Live", true), eq("Owner.Name", "ana")}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := domain.Query{Filters: tt.filters, OrderBy: "ID"}
+			got, err := Apply(records, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids(got) != tt.want {
+				t.Errorf("Apply = %q, want %q", ids(got), tt.want)
+			}
+
+			// Count ignores the page.
+			q.Offset, q.Limit = 1, 1
+			if n, err := Count(records, q); err != nil || n != len(tt.want) {
+				t.Errorf("Count = %d, %v, want %d", n, err, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestApplyInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		q    domain.Query
+	}{
+		{"unknown field", domain.Query{Filters: []domain.Filter{{Field: "Missing", Op: domain.OpEqual, Value: "x"}}}},
+		{"unexported field", domain.Query{Filters: []domain.Filter{{Field: "private", Op: domain.OpEqual, Value: "x"}}}},
+		{"field of a text", domain.Query{Filters: []domain.Filter{{Field: "Title.Length", Op: domain.OpEqual, Value: 1}}}},
+		{"unknown operator", domain.Query{Filters: []domain.Filter{{Field: "Size", Op: "ne", Value: 1}}}},
+		{"contains on a number", domain.Query{Filters: []domain.Filter{{Field: "Size", Op: domain.OpContains, Value: "1"}}}},
+		{"number against text", domain.Query{Filters: []domain.Filter{{Field: "Title", Op: domain.OpEqual, Value: 65}}}},
+		{"text against number", domain.Query{Filters: []domain.Filter{{Field: "Size", Op: domain.OpEqual, Value: "10"}}}},
+		{"nil value", domain.Query{Filters: []domain.Filter{{Field: "Size", Op: domain.OpEqual}}}},
+		{"unorderable filter field", domain.Query{Filters: []domain.Filter{{Field: "Tags", Op: domain.OpEqual, Value: []string{}}}}},
+		{"unorderable sort key", domain.Query{OrderBy: "Tags"}},
+		{"unknown sort key", domain.Query{OrderBy: "Missing"}},
+		{"negative offset", domain.Query{Offset: -1}},
+		{"negative limit", domain.Query{Limit: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Apply(records, tt.q); !errors.Is(err, domain.ErrInvalidQuery) {
+				t.Fatalf("Apply = %v, %v, want ErrInvalidQuery", ids(got), err)
+			}
+		})
+	}
+}
@@ -1,16 +1,125 @@
 package dependencyinjections
 
 import (
+	"context"
 	"downloader/internal/domain"
+	"downloader/internal/infra/archive"
+	embutido "downloader/internal/infra/db/bolt_db"
+	arquivo "downloader/internal/infra/db/file_db"
 	memoria "downloader/internal/infra/db/mem_db"
+	postgres "downloader/internal/infra/db/postgres_db"
+	"downloader/pkg/config"
+	logger "downloader/pkg/log"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
 )
 
+var log = logger.GetLogger("dependency_injections")
+
 var db domain.Database[domain.Video]
+var jobs domain.JobStore
+var downloads domain.Archive
+var subscriptions domain.SubscriptionStore
 
 func init() {
-	db = memoria.NewMemoriaDatabase[domain.Video]()
+	cfg := config.GetConfig()
+	if cfg.Database.Driver == "postgres" {
+		openPostgres(cfg.Database)
+	}
+	if db == nil {
+		db = openVideoDatabase(cfg.Database)
+	}
+	if jobs == nil {
+		jobs = openJobStore(filepath.Join(cfg.ConfigDir, "jobs.json"))
+	}
+
+	archivePath := filepath.Join(cfg.ConfigDir, "archive.txt")
+	if fileArchive, err := archive.NewFileArchive(archivePath); err != nil {
+		log.Error(fmt.Sprintf("Erro ao abrir arquivo de downloads: %v", err))
+	} else {
+		downloads = fileArchive
+	}
+
+	subscriptionsPath := filepath.Join(cfg.ConfigDir, "subscriptions.json")
+	if subscriptionStore, err := arquivo.NewArquivoDatabase[domain.Subscription](subscriptionsPath); err != nil {
+		log.Error(fmt.Sprintf("Erro ao abrir inscrições persistidas, usando memória: %v", err))
+		subscriptions = memoria.NewMemoriaDatabase[domain.Subscription]()
+	} else {
+		subscriptions = subscriptionStore
+	}
 }
 
 func GetVideoDatabase() *domain.Database[domain.Video] {
 	return &db
 }
+
+func GetJobStore() *domain.JobStore {
+	return &jobs
+}
+
+func GetSubscriptionStore() *domain.SubscriptionStore {
+	return &subscriptions
+}
+
+func GetArchive() *domain.Archive {
+	return &downloads
+}
+
+// openPostgres keeps the videos and the jobs in postgres, so every instance
+// shares them. An unreachable database stops the process: falling back to
+// local storage would split the catalog and the queue between instances
+// while reporting them healthy.
+func openPostgres(cfg config.ConfigDatabase) {
+	pool, err := postgres.Open(context.Background(), cfg)
+	if err != nil {
+		log.Error(fmt.Sprintf("Erro ao abrir postgres: %v", err))
+		os.Exit(1)
+	}
+	db = postgres.NewVideoDatabase(pool)
+	jobs = postgres.NewJobDatabase(pool, instanceName())
+}
+
+// instanceName tells apart the instances sharing the job store. It stays
+// the same across restarts of a container, so a restarted instance takes
+// its jobs back without waiting for their lease to expire.
+func instanceName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = uuid.NewString()
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// openVideoDatabase opens the local video catalog chosen in the config,
+// falling back to memory when it cannot be opened.
+func openVideoDatabase(cfg config.ConfigDatabase) domain.Database[domain.Video] {
+	switch cfg.Driver {
+	case "memory":
+		return memoria.NewMemoriaDatabase[domain.Video]()
+	case "bolt":
+		store, err := embutido.Open(cfg.Path)
+		if err == nil {
+			var videos *embutido.EmbutidoDatabase[domain.Video]
+			if videos, err = embutido.NewEmbutidoDatabase[domain.Video](store, embutido.VideosBucket); err == nil {
+				log.Info(fmt.Sprintf("Catálogo de vídeos em %s", cfg.Path))
+				return videos
+			}
+		}
+		log.Error(fmt.Sprintf("Erro ao abrir banco de vídeos, usando memória: %v", err))
+	default:
+		log.Error(fmt.Sprintf("Driver de banco desconhecido %q, usando memória", cfg.Driver))
+	}
+	return memoria.NewMemoriaDatabase[domain.Video]()
+}
+
+func openJobStore(path string) domain.JobStore {
+	jobStore, err := arquivo.NewArquivoDatabase[domain.Job](path)
+	if err != nil {
+		log.Error(fmt.Sprintf("Erro ao abrir fila persistida, usando memória: %v", err))
+		return memoria.NewMemoriaDatabase[domain.Job]()
+	}
+	return jobStore
+}
@@ -0,0 +1,252 @@
+package direct
+
+import (
+	"context"
+	"crypto/sha256"
+	"downloader/internal/domain"
+	"downloader/internal/infra/download"
+	"downloader/internal/infra/tagger"
+	"downloader/pkg/config"
+	logger "downloader/pkg/log"
+	"downloader/pkg/utils"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var log = logger.GetLogger("direct")
+
+// ErrWebPage is returned for URLs that answer with a web page, which is
+// not a file to download.
+var ErrWebPage = fmt.Errorf("%w: the url serves a web page", domain.ErrUnsupportedSource)
+
+// probeTimeout bounds the HEAD request made before accepting a URL.
+const probeTimeout = 10 * time.Second
+
+// StatusError is an HTTP response other than 200 OK.
+type StatusError int
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d", int(e))
+}
+
+// HTTPDownloader saves the file served at an HTTP(S) URL as it is. Options
+// that need to know the media, such as clips, subtitles or chapters, do not
+// apply.
+type HTTPDownloader struct {
+	client   *http.Client
+	notifyer domain.Notifyer
+	db       domain.Database[domain.Video]
+}
+
+func NewHTTPDownloader(notifyer domain.Notifyer, db domain.Database[domain.Video]) *HTTPDownloader {
+	return &HTTPDownloader{
+		client:   http.DefaultClient,
+		notifyer: notifyer,
+		db:       db,
+	}
+}
+
+// Supports asks the server what rawURL serves with a HEAD request, so web
+// pages and missing files of sites no other downloader knows are refused
+// when the download is requested instead of when it runs. Only clear
+// answers refuse the URL: network failures, server errors and servers that
+// do not handle HEAD as they handle GET, such as presigned URLs answering
+// 403, are left to the download, which is retried.
+func (d *HTTPDownloader) Supports(ctx context.Context, rawURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrUnsupportedSource, err)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		log.Info(fmt.Sprintf("Não foi possível verificar %s antes do download: %v", rawURL, err))
+		return nil
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return fmt.Errorf("%w: %s answered %w", domain.ErrUnsupportedSource, rawURL, StatusError(resp.StatusCode))
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil
+	}
+	mimeType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if webPage(mimeType) {
+		return ErrWebPage
+	}
+	return nil
+}
+
+func (d *HTTPDownloader) Download(ctx context.Context, video domain.Video, progress domain.ProgressBar) error {
+	cfg := config.GetConfig()
+
+	domain.ReportStage(progress, domain.JobFetchingMetadata)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, video.URL, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrUnsupportedSource, err)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return download.Stopped(ctx, d, video, video.URL)
+		}
+		return fmt.Errorf("error fetching %s: %w", video.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error fetching %s: %w", video.URL, StatusError(resp.StatusCode))
+	}
+
+	mimeType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if webPage(mimeType) {
+		return ErrWebPage
+	}
+	name := responseFilename(resp)
+	extension := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	if extension == "" {
+		if extensions, _ := mime.ExtensionsByType(mimeType); len(extensions) > 0 {
+			extension = strings.TrimPrefix(extensions[0], ".")
+		} else {
+			extension = "bin"
+		}
+	}
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		if byExtension := mime.TypeByExtension("." + extension); byExtension != "" {
+			mimeType, _, _ = mime.ParseMediaType(byExtension)
+		}
+	}
+	if video.Clipped() || video.SplitChapters || len(video.SubtitleLanguages) > 0 {
+		log.Info(fmt.Sprintf("Trecho, capítulos e legendas ignorados no download direto de %s", video.URL))
+	}
+
+	id := video.ID
+	if id == "" {
+		id = uuid.NewString()
+	}
+	video.ID = id
+	video.Title = strings.TrimSuffix(name, filepath.Ext(name))
+	video.Filename = utils.SanitizeFilename(video.Title)
+	video.Extension = extension
+	video.MimeType = mimeType
+	outputPath := filepath.Join(cfg.VideoDir, utils.SanitizeFilename(id+"."+extension))
+
+	log.Info(fmt.Sprintf("Download do arquivo %s iniciado!", name))
+	domain.ReportStage(progress, domain.JobDownloading)
+	size, checksum, err := d.save(ctx, resp, outputPath, progress)
+	if err != nil {
+		if ctx.Err() != nil {
+			return download.Stopped(ctx, d, video, name)
+		}
+		return err
+	}
+	progress.Finish()
+
+	video.Path, video.Size, video.Checksum = outputPath, size, checksum
+	if video.Extension == "mp3" {
+		writeTags(&video)
+	}
+	video.FinishedAt = time.Now()
+	video.CreatedAt = video.FinishedAt
+	if err := download.Save(d.db, video); err != nil {
+		return err
+	}
+	if d.notifyer != nil && !video.InPlaylist() {
+		d.Finalize(domain.Notification{
+			Title:   video.Title,
+			Message: id,
+			To:      video.Requester,
+			Kind:    domain.NotificationDone,
+		})
+	}
+	return nil
+}
+
+// save writes the body of resp to a .part file, moved to path once it is
+// complete, and returns its size and SHA-256.
+func (d *HTTPDownloader) save(ctx context.Context, resp *http.Response, path string, progress domain.ProgressBar) (int64, string, error) {
+	partPath := path + ".part"
+	file, err := os.Create(partPath)
+	if err != nil {
+		return 0, "", fmt.Errorf("error creating file: %w", err)
+	}
+
+	progress.Start(max(resp.ContentLength, 0))
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash, download.NewProgressWriter(progress, 0)), resp.Body)
+	if err == nil && resp.ContentLength > 0 && size != resp.ContentLength {
+		err = io.ErrUnexpectedEOF
+	}
+	if errors.Is(context.Cause(ctx), domain.ErrDownloadCancelled) {
+		if cancelErr := d.Cancel(file); cancelErr != nil {
+			log.Error(fmt.Sprintf("Erro ao descartar download cancelado: %v", cancelErr))
+		}
+		return 0, "", context.Cause(ctx)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(partPath)
+		return 0, "", fmt.Errorf("error saving file: %w", err)
+	}
+	if err := os.Rename(partPath, path); err != nil {
+		os.Remove(partPath)
+		return 0, "", fmt.Errorf("error moving %s: %w", partPath, err)
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeTags tags the downloaded MP3 with its title and source URL, and
+// updates its size and checksum to the tagged file. Failures are logged, as
+// the audio itself was downloaded.
+func writeTags(video *domain.Video) {
+	if err := tagger.Write(*video, nil, ""); err != nil {
+		log.Error(fmt.Sprintf("Erro ao gravar tags em %s: %v", video.Title, err))
+		return
+	}
+	size, checksum, err := download.FileChecksum(video.Path)
+	if err != nil {
+		log.Error(fmt.Sprintf("Erro ao calcular checksum de %s: %v", video.Path, err))
+		return
+	}
+	video.Size, video.Checksum = size, checksum
+}
+
+// webPage reports whether mimeType is the one of a web page rather than of
+// a file.
+func webPage(mimeType string) bool {
+	return mimeType == "text/html" || mimeType == "application/xhtml+xml"
+}
+
+// responseFilename is the name the server gives to the file, or else the
+// last element of the path it was served from.
+func responseFilename(resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		return filepath.Base(params["filename"])
+	}
+	if name := path.Base(resp.Request.URL.Path); name != "/" && name != "." {
+		return name
+	}
+	return resp.Request.URL.Hostname()
+}
+
+func (d *HTTPDownloader) Finalize(notification domain.Notification) error {
+	return download.Finalize(d.notifyer, notification)
+}
+
+func (d *HTTPDownloader) Cancel(file *os.File) error {
+	return download.Cancel(file)
+}
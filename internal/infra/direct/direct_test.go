@@ -0,0 +1,160 @@
+package direct
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"downloader/internal/domain"
+	memoria "downloader/internal/infra/db/mem_db"
+	"downloader/internal/infra/download"
+	"downloader/pkg/config"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSupports(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/video.mp4":
+			w.Header().Set("Content-Type", "video/mp4")
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		case "/moved":
+			http.Redirect(w, r, "/video.mp4", http.StatusFound)
+		case "/no-head/watch":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case "/presigned.mp4":
+			w.WriteHeader(http.StatusForbidden)
+		case "/broken":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/removed.mp4":
+			w.WriteHeader(http.StatusGone)
+		case "/not-a-page":
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		name        string
+		path        string
+		unsupported bool
+	}{
+		{"file", "/video.mp4", false},
+		{"redirect to a file", "/moved", false},
+		{"web page", "/page", true},
+		{"missing file", "/missing.mp4", true},
+		{"removed file", "/removed.mp4", true},
+		{"no HEAD", "/no-head/watch", false},
+		{"HEAD forbidden", "/presigned.mp4", false},
+		{"error page of another status", "/not-a-page", false},
+		{"server error", "/broken", false},
+	}
+	d := NewHTTPDownloader(nil, nil)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := d.Supports(context.Background(), server.URL+c.path)
+			if !c.unsupported {
+				if err != nil {
+					t.Fatalf("got %v, want the URL left to the download", err)
+				}
+				return
+			}
+			if !errors.Is(err, domain.ErrUnsupportedSource) {
+				t.Fatalf("got %v, want ErrUnsupportedSource", err)
+			}
+		})
+	}
+
+	// Unreachable servers are left to the download, which is retried.
+	server.Close()
+	if err := d.Supports(context.Background(), server.URL+"/video.mp4"); err != nil {
+		t.Fatalf("unreachable server: got %v, want the URL left to the download", err)
+	}
+	if err := d.Supports(context.Background(), "http://%zz"); !errors.Is(err, domain.ErrUnsupportedSource) {
+		t.Fatalf("malformed URL: got %v, want ErrUnsupportedSource", err)
+	}
+}
+
+type silentBar struct{}
+
+func (silentBar) Start(total int64)    {}
+func (silentBar) Update(current int64) {}
+func (silentBar) Finish()              {}
+
+// failingStore refuses every video saved to it.
+type failingStore struct {
+	*memoria.MemoriaDatabase[domain.Video]
+}
+
+func (failingStore) Save(id string, v domain.Video) error { return errors.New("disk full") }
+
+func TestDownloadFailsWhenNotSaved(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "video/mp4")
+		w.Write([]byte("video"))
+	}))
+	defer server.Close()
+
+	d := NewHTTPDownloader(nil, failingStore{memoria.NewMemoriaDatabase[domain.Video]()})
+	video := domain.Video{ID: uuid.NewString(), URL: server.URL + "/video.mp4"}
+	err := d.Download(context.Background(), video, silentBar{})
+	if !errors.Is(err, download.ErrSaveFailed) {
+		t.Fatalf("Download = %v, want ErrSaveFailed", err)
+	}
+	if Retryable(err) {
+		t.Fatal("a video that could not be saved is retried")
+	}
+	path := filepath.Join(config.GetConfig().VideoDir, video.ID+".mp4")
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("file of the unsaved video left at %s: %v", path, err)
+	}
+}
+
+func TestDownloadTagsMP3(t *testing.T) {
+	audio := []byte("\xff\xfbaudio frames")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Write(audio)
+	}))
+	defer server.Close()
+
+	db := memoria.NewMemoriaDatabase[domain.Video]()
+	d := NewHTTPDownloader(nil, db)
+	video := domain.Video{ID: uuid.NewString(), URL: server.URL + "/Canção.mp3"}
+	if err := d.Download(context.Background(), video, silentBar{}); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := db.Get(video.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(saved.Path)
+
+	data, err := os.ReadFile(saved.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("ID3")) || !bytes.HasSuffix(data, audio) {
+		t.Fatalf("downloaded file = %q, want an ID3 tag followed by the audio", data)
+	}
+	for _, field := range []string{"Canção", video.URL} {
+		if !bytes.Contains(data, []byte(field)) {
+			t.Errorf("tag does not hold %q", field)
+		}
+	}
+	sum := sha256.Sum256(data)
+	if saved.Size != int64(len(data)) || saved.Checksum != hex.EncodeToString(sum[:]) {
+		t.Fatalf("saved size %d and checksum %s are not the ones of the tagged file", saved.Size, saved.Checksum)
+	}
+}
@@ -0,0 +1,41 @@
+package direct
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"downloader/internal/infra/download"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+)
+
+// Retryable reports whether a download that failed with err may succeed
+// when attempted again. Network failures, server errors and throttling
+// are transient; missing files and web pages are not.
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var status StatusError
+	var netErr net.Error
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, domain.ErrDownloadCancelled),
+		errors.Is(err, domain.ErrUnsupportedSource),
+		errors.Is(err, download.ErrSaveFailed):
+		return false
+	case errors.As(err, &status):
+		return status >= 500 || status == 408 || status == 429
+	case errors.As(err, &netErr),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	case errors.As(err, &pathErr):
+		// Local file system errors do not go away by downloading again.
+		return false
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+// Package download holds the pieces every downloader shares: telling the
+// requester about a stopped download, discarding partial files and
+// counting and checking what was written.
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"downloader/internal/domain"
+	logger "downloader/pkg/log"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+var log = logger.GetLogger("download")
+
+// ErrSaveFailed means the file was downloaded but could not be recorded,
+// which downloading it again does not fix.
+var ErrSaveFailed = errors.New("error saving video")
+
+// Stopped handles a download of d interrupted through its context. The
+// requester is only told when the download was cancelled on purpose, not
+// when the process is shutting down, and playlist entries are left to the
+// playlist summary.
+func Stopped(ctx context.Context, d domain.Downloader, video domain.Video, title string) error {
+	cause := context.Cause(ctx)
+	log.Info(fmt.Sprintf("Download de %s interrompido: %v", title, cause))
+
+	if errors.Is(cause, domain.ErrDownloadCancelled) && !video.InPlaylist() {
+		if err := d.Finalize(domain.Notification{
+			Title:   title,
+			Message: "Download cancelado",
+			To:      video.Requester,
+			Kind:    domain.NotificationCancelled,
+		}); err != nil {
+			log.Error(fmt.Sprintf("Erro ao notificar cancelamento de %s: %v", title, err))
+		}
+	}
+	return fmt.Errorf("download of %s stopped: %w", title, cause)
+}
+
+// Finalize sends notification through notifyer, which may be nil.
+func Finalize(notifyer domain.Notifyer, notification domain.Notification) error {
+	if notifyer == nil {
+		return nil
+	}
+	if err := notifyer.Notify(notification); err != nil {
+		return fmt.Errorf("error notifying user: %w", err)
+	}
+	return nil
+}
+
+// Save records the finished video in db, which may be nil. A video that
+// cannot be recorded would never be listed, so its file is removed and the
+// download fails.
+func Save(db domain.Database[domain.Video], video domain.Video) error {
+	if db == nil {
+		return nil
+	}
+	if err := db.Save(video.ID, video); err != nil {
+		log.Error(fmt.Sprintf("Erro ao salvar o vídeo %s: %v", video.Title, err))
+		if video.Path != "" {
+			os.Remove(video.Path)
+		}
+		return fmt.Errorf("%w %s: %w", ErrSaveFailed, video.ID, err)
+	}
+	return nil
+}
+
+// Cancel closes and deletes the partial file of a cancelled download.
+func Cancel(file *os.File) error {
+	if err := file.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return fmt.Errorf("error closing file: %w", err)
+	}
+	if err := os.Remove(file.Name()); err != nil {
+		return fmt.Errorf("error deleting file: %w", err)
+	}
+	return nil
+}
+
+// ProgressWriter reports to a progress bar the bytes written to it, which
+// may come from every stream of a download at once.
+type ProgressWriter struct {
+	current  atomic.Int64
+	progress domain.ProgressBar
+}
+
+// NewProgressWriter counts from start, the bytes already downloaded by an
+// earlier attempt.
+func NewProgressWriter(progress domain.ProgressBar, start int64) *ProgressWriter {
+	pw := &ProgressWriter{progress: progress}
+	pw.current.Store(start)
+	return pw
+}
+
+func (pw *ProgressWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	pw.progress.Update(pw.current.Add(int64(n)))
+	return n, nil
+}
+
+// FileChecksum returns the size and the SHA-256 of the file at path.
+func FileChecksum(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("error opening %s: %w", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,99 @@
+package download
+
+import (
+	"context"
+	"downloader/internal/domain"
+	memoria "downloader/internal/infra/db/mem_db"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type recordingDownloader struct {
+	notifications []domain.Notification
+}
+
+func (d *recordingDownloader) Download(ctx context.Context, video domain.Video, progress domain.ProgressBar) error {
+	return nil
+}
+
+func (d *recordingDownloader) Finalize(notification domain.Notification) error {
+	d.notifications = append(d.notifications, notification)
+	return nil
+}
+
+func (d *recordingDownloader) Cancel(file *os.File) error { return nil }
+
+func TestStopped(t *testing.T) {
+	shutdown := errors.New("shutting down")
+	cases := []struct {
+		name     string
+		cause    error
+		playlist string
+		notified bool
+	}{
+		{"cancelled", domain.ErrDownloadCancelled, "", true},
+		{"cancelled playlist entry", domain.ErrDownloadCancelled, "PL1", false},
+		{"shutdown", shutdown, "", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancelCause(context.Background())
+			cancel(c.cause)
+			d := &recordingDownloader{}
+			video := domain.Video{Requester: "ana", PlaylistID: c.playlist}
+
+			err := Stopped(ctx, d, video, "Vídeo")
+			if !errors.Is(err, c.cause) {
+				t.Fatalf("got %v, want it to wrap %v", err, c.cause)
+			}
+			if notified := len(d.notifications) > 0; notified != c.notified {
+				t.Fatalf("notified = %v, want %v", notified, c.notified)
+			}
+			if c.notified && (d.notifications[0].Kind != domain.NotificationCancelled || d.notifications[0].To != "ana") {
+				t.Fatalf("notification = %+v", d.notifications[0])
+			}
+		})
+	}
+}
+
+// failingStore refuses every video saved to it.
+type failingStore struct {
+	*memoria.MemoriaDatabase[domain.Video]
+}
+
+func (failingStore) Save(id string, v domain.Video) error { return errors.New("disk full") }
+
+func TestSave(t *testing.T) {
+	cases := []struct {
+		name   string
+		db     domain.Database[domain.Video]
+		failed bool
+	}{
+		{"no database", nil, false},
+		{"saved", memoria.NewMemoriaDatabase[domain.Video](), false},
+		{"refused", failingStore{memoria.NewMemoriaDatabase[domain.Video]()}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "video.mp4")
+			if err := os.WriteFile(path, []byte("video"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := Save(c.db, domain.Video{ID: "v1", Path: path})
+			if failed := errors.Is(err, ErrSaveFailed); failed != c.failed {
+				t.Fatalf("Save = %v, want failed %v", err, c.failed)
+			}
+			if _, statErr := os.Stat(path); (statErr != nil) != c.failed {
+				t.Fatalf("file kept = %v after Save = %v", statErr == nil, err)
+			}
+			if c.db != nil && !c.failed {
+				if _, err := c.db.Get("v1"); err != nil {
+					t.Fatalf("saved video not found: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package hls
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// MethodAES128 encrypts whole segments with AES-128 in CBC mode and PKCS#7
+// padding. SAMPLE-AES, which encrypts the samples inside the segments, is
+// not supported.
+const MethodAES128 = "AES-128"
+
+var ErrUnsupportedEncryption = errors.New("unsupported hls encryption")
+
+// Decrypt decrypts an AES-128 segment in place with key, returning the data
+// without its padding. The IV of the key is used when it has one, else the
+// sequence number of the segment.
+func Decrypt(data []byte, k *Key, key []byte, sequence int64) ([]byte, error) {
+	if k.Method != MethodAES128 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncryption, k.Method)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("encrypted segment of %d bytes is not a multiple of the block size", len(data))
+	}
+
+	iv := k.IV
+	if iv == nil {
+		iv = make([]byte, aes.BlockSize)
+		binary.BigEndian.PutUint64(iv[8:], uint64(sequence))
+	}
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(data, data)
+
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(data) {
+		return nil, errors.New("invalid padding, the key is probably wrong")
+	}
+	return data[:len(data)-padding], nil
+}
@@ -0,0 +1,71 @@
+package hls
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// encrypt pads data with PKCS#7 and encrypts it as an AES-128 segment.
+func encrypt(t *testing.T, data, key, iv []byte) []byte {
+	t.Helper()
+	padding := aes.BlockSize - len(data)%aes.BlockSize
+	return encryptRaw(t, append(bytes.Clone(data), bytes.Repeat([]byte{byte(padding)}, padding)...), key, iv)
+}
+
+// encryptRaw encrypts whole blocks of data, leaving the padding to it.
+func encryptRaw(t *testing.T, data, key, iv []byte) []byte {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := bytes.Clone(data)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, out)
+	return out
+}
+
+func TestDecrypt(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	iv := []byte("fedcba9876543210")
+	sequenceIV := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint64(sequenceIV[8:], 42)
+	segment := []byte("transport stream packets")
+
+	tests := []struct {
+		name string
+		k    *Key
+		data []byte
+		key  []byte
+		want []byte
+		err  error
+	}{
+		{name: "explicit IV", k: &Key{Method: MethodAES128, IV: iv}, data: encrypt(t, segment, key, iv), key: key, want: segment},
+		{name: "sequence number IV", k: &Key{Method: MethodAES128}, data: encrypt(t, segment, key, sequenceIV), key: key, want: segment},
+		{name: "whole block of padding", k: &Key{Method: MethodAES128, IV: iv}, data: encrypt(t, segment[:16], key, iv), key: key, want: segment[:16]},
+		{name: "SAMPLE-AES", k: &Key{Method: "SAMPLE-AES"}, data: make([]byte, 16), key: key, err: ErrUnsupportedEncryption},
+		{name: "short key", k: &Key{Method: MethodAES128, IV: iv}, data: make([]byte, 16), key: key[:5]},
+		{name: "empty segment", k: &Key{Method: MethodAES128, IV: iv}, key: key},
+		{name: "partial block", k: &Key{Method: MethodAES128, IV: iv}, data: make([]byte, 20), key: key},
+		{name: "zero padding", k: &Key{Method: MethodAES128, IV: iv}, data: encryptRaw(t, make([]byte, 16), key, iv), key: key},
+		{name: "padding over a block", k: &Key{Method: MethodAES128, IV: iv}, data: encryptRaw(t, bytes.Repeat([]byte{17}, 32), key, iv), key: key},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decrypt(tt.data, tt.k, tt.key, 42)
+			switch {
+			case tt.want == nil && err == nil:
+				t.Fatalf("Decrypt = %q, want an error", got)
+			case tt.err != nil && !errors.Is(err, tt.err):
+				t.Fatalf("Decrypt = %v, want %v", err, tt.err)
+			case tt.want != nil && err != nil:
+				t.Fatal(err)
+			case !bytes.Equal(got, tt.want):
+				t.Fatalf("Decrypt = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
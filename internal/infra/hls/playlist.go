@@ -0,0 +1,261 @@
+// Package hls reads HTTP Live Streaming playlists (RFC 8216).
+package hls
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidPlaylist = errors.New("invalid hls playlist")
+
+// Playlist is either a master playlist, which lists Variants and the
+// Renditions they refer to, or a media playlist, which lists Segments.
+type Playlist struct {
+	Variants   []Variant
+	Renditions []Rendition
+
+	TargetDuration time.Duration
+	MediaSequence  int64
+	Segments       []Segment
+	// Ended is set by EXT-X-ENDLIST: no segment will be added to the
+	// playlist. Playlists that have not ended are live.
+	Ended bool
+}
+
+// IsMaster reports whether the playlist lists variants instead of media.
+func (p *Playlist) IsMaster() bool {
+	return len(p.Variants) > 0
+}
+
+// Duration is the sum of the durations of the segments.
+func (p *Playlist) Duration() time.Duration {
+	var total time.Duration
+	for _, s := range p.Segments {
+		total += s.Duration
+	}
+	return total
+}
+
+// Variant is a version of the stream in a master playlist.
+type Variant struct {
+	URI       string
+	Bandwidth int
+	Width     int
+	Height    int
+	Codecs    string
+	// Audio is the group of the renditions that carry its audio, when it
+	// is not muxed in the variant itself.
+	Audio string
+}
+
+// Rendition is an alternative audio, video or subtitle stream of a master
+// playlist. Renditions without a URI are muxed in the variants.
+type Rendition struct {
+	Type     string
+	GroupID  string
+	Name     string
+	Language string
+	Default  bool
+	URI      string
+}
+
+// Segment is a part of the media. Length is 0 when the whole resource is
+// the segment.
+type Segment struct {
+	URI      string
+	Duration time.Duration
+	Sequence int64
+	Offset   int64
+	Length   int64
+	// Key decrypts the segment, when it is encrypted.
+	Key *Key
+	// Map is the initialization section the segment needs.
+	Map *Map
+}
+
+// Key is an EXT-X-KEY. IV is nil when the sequence number is the IV.
+type Key struct {
+	Method string
+	URI    string
+	IV     []byte
+}
+
+// Map is an EXT-X-MAP initialization section.
+type Map struct {
+	URI    string
+	Offset int64
+	Length int64
+}
+
+// Parse reads a playlist, resolving its URIs against base.
+func Parse(data []byte, base *url.URL) (*Playlist, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	if !scanner.Scan() || strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff")) != "#EXTM3U" {
+		return nil, fmt.Errorf("%w: missing #EXTM3U", ErrInvalidPlaylist)
+	}
+
+	p := &Playlist{}
+	var (
+		pending   *Variant
+		segment   Segment
+		key       *Key
+		initMap   *Map
+		sequence  int64
+		nextStart int64
+	)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		tag, value, _ := strings.Cut(line, ":")
+		var err error
+		switch {
+		case line == "":
+		case tag == "#EXT-X-STREAM-INF":
+			attrs := parseAttributes(value)
+			v := Variant{Codecs: attrs["CODECS"], Audio: attrs["AUDIO"]}
+			v.Bandwidth, _ = strconv.Atoi(attrs["BANDWIDTH"])
+			if w, h, ok := strings.Cut(attrs["RESOLUTION"], "x"); ok {
+				v.Width, _ = strconv.Atoi(w)
+				v.Height, _ = strconv.Atoi(h)
+			}
+			pending = &v
+		case tag == "#EXT-X-MEDIA":
+			attrs := parseAttributes(value)
+			r := Rendition{
+				Type:     attrs["TYPE"],
+				GroupID:  attrs["GROUP-ID"],
+				Name:     attrs["NAME"],
+				Language: attrs["LANGUAGE"],
+				Default:  attrs["DEFAULT"] == "YES",
+			}
+			if attrs["URI"] != "" {
+				r.URI, err = resolve(base, attrs["URI"])
+			}
+			p.Renditions = append(p.Renditions, r)
+		case tag == "#EXT-X-TARGETDURATION":
+			var seconds int
+			seconds, err = strconv.Atoi(value)
+			p.TargetDuration = time.Duration(seconds) * time.Second
+		case tag == "#EXT-X-MEDIA-SEQUENCE":
+			p.MediaSequence, err = strconv.ParseInt(value, 10, 64)
+			sequence = p.MediaSequence
+		case tag == "#EXTINF":
+			seconds, _, _ := strings.Cut(value, ",")
+			var d float64
+			d, err = strconv.ParseFloat(strings.TrimSpace(seconds), 64)
+			segment.Duration = time.Duration(d * float64(time.Second))
+		case tag == "#EXT-X-BYTERANGE":
+			segment.Length, segment.Offset, err = parseByteRange(value, nextStart)
+		case tag == "#EXT-X-KEY":
+			key, err = parseKey(parseAttributes(value), base)
+		case tag == "#EXT-X-MAP":
+			attrs := parseAttributes(value)
+			m := &Map{}
+			if m.URI, err = resolve(base, attrs["URI"]); err == nil && attrs["BYTERANGE"] != "" {
+				m.Length, m.Offset, err = parseByteRange(attrs["BYTERANGE"], 0)
+			}
+			initMap = m
+		case tag == "#EXT-X-ENDLIST":
+			p.Ended = true
+		case strings.HasPrefix(line, "#"):
+			// Other tags and comments do not change what is downloaded.
+		case pending != nil:
+			pending.URI, err = resolve(base, line)
+			p.Variants = append(p.Variants, *pending)
+			pending = nil
+		default:
+			segment.URI, err = resolve(base, line)
+			segment.Sequence = sequence
+			segment.Key, segment.Map = key, initMap
+			p.Segments = append(p.Segments, segment)
+			if segment.Length > 0 {
+				nextStart = segment.Offset + segment.Length
+			}
+			segment = Segment{}
+			sequence++
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidPlaylist, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading playlist: %w", err)
+	}
+	return p, nil
+}
+
+func parseKey(attrs map[string]string, base *url.URL) (*Key, error) {
+	if attrs["METHOD"] == "NONE" {
+		return nil, nil
+	}
+	key := &Key{Method: attrs["METHOD"]}
+	var err error
+	if key.URI, err = resolve(base, attrs["URI"]); err != nil {
+		return nil, err
+	}
+	if iv := attrs["IV"]; iv != "" {
+		iv = strings.TrimPrefix(strings.TrimPrefix(iv, "0x"), "0X")
+		if key.IV, err = hex.DecodeString(iv); err != nil || len(key.IV) != 16 {
+			return nil, fmt.Errorf("invalid iv %q", attrs["IV"])
+		}
+	}
+	return key, nil
+}
+
+// parseByteRange reads "length[@offset]". Without an offset the range
+// starts at next, where the previous one ended.
+func parseByteRange(value string, next int64) (int64, int64, error) {
+	length, offset, hasOffset := strings.Cut(value, "@")
+	n, err := strconv.ParseInt(length, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !hasOffset {
+		return n, next, nil
+	}
+	o, err := strconv.ParseInt(offset, 10, 64)
+	return n, o, err
+}
+
+// parseAttributes splits an attribute list such as
+// `BANDWIDTH=1280000,CODECS="avc1.4d401f,mp4a.40.2"`.
+func parseAttributes(list string) map[string]string {
+	attrs := map[string]string{}
+	for list != "" {
+		name, rest, ok := strings.Cut(list, "=")
+		if !ok {
+			break
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			value, rest = rest[1:end+1], rest[min(end+2, len(rest)):]
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		attrs[strings.TrimSpace(name)] = value
+		list = strings.TrimPrefix(rest, ",")
+	}
+	return attrs
+}
+
+func resolve(base *url.URL, ref string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	if base == nil {
+		return u.String(), nil
+	}
+	return base.ResolveReference(u).String(), nil
+}
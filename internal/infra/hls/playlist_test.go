@@ -0,0 +1,156 @@
+package hls
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseMaster(t *testing.T) {
+	base, _ := url.Parse("https://cdn.example.com/live/master.m3u8")
+	data := "\ufeff#EXTM3U\n" +
+		`#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="Português",LANGUAGE="pt",DEFAULT=YES,URI="audio/pt.m3u8"` + "\n" +
+		`#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="Muxed"` + "\n" +
+		`#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS="avc1.4d401f,mp4a.40.2",AUDIO="aac"` + "\n" +
+		"720p/index.m3u8\n" +
+		"\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=640000\n" +
+		"https://other.example.com/low.m3u8\n"
+
+	p, err := Parse([]byte(data), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsMaster() {
+		t.Fatal("master playlist not reported as master")
+	}
+
+	wantVariants := []Variant{
+		{URI: "https://cdn.example.com/live/720p/index.m3u8", Bandwidth: 1280000, Width: 1280, Height: 720, Codecs: "avc1.4d401f,mp4a.40.2", Audio: "aac"},
+		{URI: "https://other.example.com/low.m3u8", Bandwidth: 640000},
+	}
+	if len(p.Variants) != len(wantVariants) {
+		t.Fatalf("variants = %+v", p.Variants)
+	}
+	for i, want := range wantVariants {
+		if p.Variants[i] != want {
+			t.Errorf("variant %d = %+v, want %+v", i, p.Variants[i], want)
+		}
+	}
+
+	wantRenditions := []Rendition{
+		{Type: "AUDIO", GroupID: "aac", Name: "Português", Language: "pt", Default: true, URI: "https://cdn.example.com/live/audio/pt.m3u8"},
+		{Type: "AUDIO", GroupID: "aac", Name: "Muxed"},
+	}
+	if len(p.Renditions) != len(wantRenditions) {
+		t.Fatalf("renditions = %+v", p.Renditions)
+	}
+	for i, want := range wantRenditions {
+		if p.Renditions[i] != want {
+			t.Errorf("rendition %d = %+v, want %+v", i, p.Renditions[i], want)
+		}
+	}
+}
+
+func TestParseMedia(t *testing.T) {
+	base, _ := url.Parse("https://cdn.example.com/vod/index.m3u8")
+	data := `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:10
+#EXT-X-MAP:URI="init.mp4",BYTERANGE="720@0"
+#EXTINF:6.0,
+#EXT-X-BYTERANGE:1000@720
+media.mp4
+#EXTINF:5.5,title
+#EXT-X-BYTERANGE:500
+media.mp4
+#EXT-X-KEY:METHOD=AES-128,URI="key.bin",IV=0x000102030405060708090A0B0C0D0E0F
+#EXTINF:4,
+seg3.ts
+#EXT-X-KEY:METHOD=NONE
+#EXTINF:2.5,
+seg4.ts
+#EXT-X-ENDLIST
+`
+	p, err := Parse([]byte(data), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.IsMaster() || !p.Ended || p.TargetDuration != 6*time.Second || p.MediaSequence != 10 {
+		t.Fatalf("playlist = %+v", p)
+	}
+	if p.Duration() != 18*time.Second {
+		t.Errorf("Duration = %v, want 18s", p.Duration())
+	}
+
+	initMap := &Map{URI: "https://cdn.example.com/vod/init.mp4", Length: 720}
+	want := []Segment{
+		{URI: "https://cdn.example.com/vod/media.mp4", Duration: 6 * time.Second, Sequence: 10, Offset: 720, Length: 1000},
+		{URI: "https://cdn.example.com/vod/media.mp4", Duration: 5500 * time.Millisecond, Sequence: 11, Offset: 1720, Length: 500},
+		{URI: "https://cdn.example.com/vod/seg3.ts", Duration: 4 * time.Second, Sequence: 12},
+		{URI: "https://cdn.example.com/vod/seg4.ts", Duration: 2500 * time.Millisecond, Sequence: 13},
+	}
+	if len(p.Segments) != len(want) {
+		t.Fatalf("segments = %+v", p.Segments)
+	}
+	for i, w := range want {
+		s := p.Segments[i]
+		if s.URI != w.URI || s.Duration != w.Duration || s.Sequence != w.Sequence || s.Offset != w.Offset || s.Length != w.Length {
+			t.Errorf("segment %d = %+v, want %+v", i, s, w)
+		}
+		if s.Map == nil || *s.Map != *initMap {
+			t.Errorf("segment %d map = %+v, want %+v", i, s.Map, initMap)
+		}
+	}
+
+	if p.Segments[1].Key != nil || p.Segments[3].Key != nil {
+		t.Error("unencrypted segment has a key")
+	}
+	key := p.Segments[2].Key
+	if key == nil || key.Method != MethodAES128 || key.URI != "https://cdn.example.com/vod/key.bin" || len(key.IV) != 16 || key.IV[15] != 0x0F {
+		t.Errorf("segment 2 key = %+v", key)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"no header", "#EXTINF:1,\nseg.ts\n"},
+		{"bad duration", "#EXTM3U\n#EXTINF:long,\nseg.ts\n"},
+		{"bad target duration", "#EXTM3U\n#EXT-X-TARGETDURATION:six\n"},
+		{"bad sequence", "#EXTM3U\n#EXT-X-MEDIA-SEQUENCE:x\n"},
+		{"bad byte range", "#EXTM3U\n#EXT-X-BYTERANGE:10@x\n"},
+		{"short iv", "#EXTM3U\n#EXT-X-KEY:METHOD=AES-128,URI=\"k\",IV=0x0102\n"},
+		{"bad uri", "#EXTM3U\n#EXTINF:1,\n%zz\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if p, err := Parse([]byte(tt.data), nil); !errors.Is(err, ErrInvalidPlaylist) {
+				t.Fatalf("Parse = %+v, %v, want ErrInvalidPlaylist", p, err)
+			}
+		})
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	attrs := parseAttributes(`BANDWIDTH=1280000,CODECS="avc1.4d401f,mp4a.40.2",NAME="a=b",LAST="unterminated`)
+	want := map[string]string{
+		"BANDWIDTH": "1280000",
+		"CODECS":    "avc1.4d401f,mp4a.40.2",
+		"NAME":      "a=b",
+		"LAST":      "unterminated",
+	}
+	if len(attrs) != len(want) {
+		t.Fatalf("attributes = %q", attrs)
+	}
+	for name, value := range want {
+		if attrs[name] != value {
+			t.Errorf("%s = %q, want %q", name, attrs[name], value)
+		}
+	}
+}
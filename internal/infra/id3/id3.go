@@ -0,0 +1,161 @@
+package id3
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var ErrInvalidTag = errors.New("invalid id3 tag")
+
+const (
+	headerSize   = 10
+	flagFooter   = 0x10
+	encodingUTF8 = 3
+	// pictureFrontCover is the APIC picture type of a front cover.
+	pictureFrontCover = 3
+)
+
+// Tags are the frames written by Write. Empty fields are left out.
+type Tags struct {
+	Title       string
+	Artist      string
+	Date        string
+	Description string
+	URL         string
+	VideoID     string
+	// Cover is an image of type CoverType.
+	Cover     []byte
+	CoverType string
+}
+
+// Write puts an ID3v2.4 tag with tags at the start of the file at path, in
+// place of the ID3v2 tag it may already have.
+func Write(path string, tags Tags) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", path, err)
+	}
+	defer file.Close()
+
+	start, err := existingTagSize(file)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	_, err = out.Write(encode(tags))
+	if err == nil {
+		_, err = file.Seek(start, io.SeekStart)
+	}
+	if err == nil {
+		_, err = io.Copy(out, file)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error moving %s: %w", tmp, err)
+	}
+	return nil
+}
+
+// existingTagSize returns the size of the ID3v2 tag at the start of r, or 0
+// when there is none.
+func existingTagSize(r io.ReaderAt) (int64, error) {
+	header := make([]byte, headerSize)
+	if n, err := r.ReadAt(header, 0); n < headerSize || !bytes.HasPrefix(header, []byte("ID3")) {
+		if err != nil && err != io.EOF {
+			return 0, fmt.Errorf("error reading id3 header: %w", err)
+		}
+		return 0, nil
+	}
+
+	size, ok := syncsafe(header[6:10])
+	if !ok {
+		return 0, fmt.Errorf("%w: bad tag size", ErrInvalidTag)
+	}
+	size += headerSize
+	if header[5]&flagFooter != 0 {
+		size += headerSize
+	}
+	return size, nil
+}
+
+func encode(tags Tags) []byte {
+	var frames []byte
+	for _, frame := range []struct{ id, value string }{
+		{"TIT2", tags.Title},
+		{"TPE1", tags.Artist},
+		{"TDRC", tags.Date},
+	} {
+		if frame.value != "" {
+			frames = appendFrame(frames, frame.id, textFrame(frame.value))
+		}
+	}
+	if tags.Description != "" {
+		// Comments carry a language before an empty short description.
+		frames = appendFrame(frames, "COMM", append([]byte{encodingUTF8}, "eng\x00"+tags.Description...))
+	}
+	if tags.URL != "" {
+		frames = appendFrame(frames, "WXXX", textFrame("", tags.URL))
+	}
+	if tags.VideoID != "" {
+		frames = appendFrame(frames, "TXXX", textFrame("VIDEO_ID", tags.VideoID))
+	}
+	if tags.Cover != nil {
+		picture := append([]byte{encodingUTF8}, tags.CoverType...)
+		picture = append(picture, 0, pictureFrontCover, 0)
+		frames = appendFrame(frames, "APIC", append(picture, tags.Cover...))
+	}
+
+	tag := append([]byte("ID3"), 4, 0, 0)
+	tag = appendSyncsafe(tag, len(frames))
+	return append(tag, frames...)
+}
+
+// textFrame builds the body of a UTF-8 frame made of the given strings,
+// separated by NUL bytes.
+func textFrame(fields ...string) []byte {
+	body := []byte{encodingUTF8}
+	for i, field := range fields {
+		if i > 0 {
+			body = append(body, 0)
+		}
+		body = append(body, field...)
+	}
+	return body
+}
+
+func appendFrame(buf []byte, id string, body []byte) []byte {
+	buf = append(buf, id...)
+	buf = appendSyncsafe(buf, len(body))
+	buf = append(buf, 0, 0) // flags
+	return append(buf, body...)
+}
+
+func appendSyncsafe(buf []byte, n int) []byte {
+	return append(buf, byte(n>>21&0x7F), byte(n>>14&0x7F), byte(n>>7&0x7F), byte(n&0x7F))
+}
+
+func syncsafe(b []byte) (int64, bool) {
+	var n int64
+	for _, c := range b {
+		if c&0x80 != 0 {
+			return 0, false
+		}
+		n = n<<7 | int64(c)
+	}
+	return n, true
+}
@@ -0,0 +1,128 @@
+package id3
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// frames returns the bodies of the frames of the ID3v2.4 tag at the start
+// of data, keyed by frame ID, and the bytes that follow the tag.
+func frames(t *testing.T, data []byte) (map[string][]byte, []byte) {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("ID3\x04\x00")) {
+		t.Fatalf("no ID3v2.4 header in %q", data[:min(len(data), headerSize)])
+	}
+	size, ok := syncsafe(data[6:10])
+	if !ok {
+		t.Fatal("tag size is not syncsafe")
+	}
+	body, rest := data[headerSize:headerSize+size], data[headerSize+size:]
+
+	found := map[string][]byte{}
+	for len(body) > 0 {
+		n, ok := syncsafe(body[4:8])
+		if !ok {
+			t.Fatalf("frame %s size is not syncsafe", body[:4])
+		}
+		found[string(body[:4])] = body[headerSize : headerSize+n]
+		body = body[headerSize+n:]
+	}
+	return found, rest
+}
+
+func TestWrite(t *testing.T) {
+	audio := []byte("\xff\xfbaudio frames")
+	tags := Tags{
+		Title:       "Título",
+		Artist:      "Canal",
+		Date:        "2026-05-10",
+		Description: "Descrição",
+		URL:         "https://example.com/v",
+		VideoID:     "abc",
+		Cover:       []byte("\xff\xd8\xff"),
+		CoverType:   "image/jpeg",
+	}
+	want := map[string]string{
+		"TIT2": "\x03Título",
+		"TPE1": "\x03Canal",
+		"TDRC": "\x032026-05-10",
+		"COMM": "\x03eng\x00Descrição",
+		"WXXX": "\x03\x00https://example.com/v",
+		"TXXX": "\x03VIDEO_ID\x00abc",
+		"APIC": "\x03image/jpeg\x00\x03\x00\xff\xd8\xff",
+	}
+
+	// A tag of 200 bytes, syncsafe encoded, with a footer.
+	oldTag := append([]byte("ID3\x04\x00\x10\x00\x00\x01\x48"), make([]byte, 200+headerSize)...)
+
+	tests := []struct {
+		name     string
+		existing []byte
+	}{
+		{"untagged", audio},
+		{"tagged", append(oldTag, audio...)},
+		{"tag only", oldTag[:headerSize+200+headerSize]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audio.mp3")
+			if err := os.WriteFile(path, tt.existing, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := Write(path, tags); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found, rest := frames(t, data)
+			for id, body := range want {
+				if string(found[id]) != body {
+					t.Errorf("frame %s = %q, want %q", id, found[id], body)
+				}
+			}
+			if len(found) != len(want) {
+				t.Errorf("wrote frames %v", found)
+			}
+			wantRest := audio
+			if tt.name == "tag only" {
+				wantRest = nil
+			}
+			if !bytes.Equal(rest, wantRest) {
+				t.Errorf("audio after the tag = %q, want %q", rest, wantRest)
+			}
+		})
+	}
+}
+
+func TestWriteLeavesOutEmptyFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audio.mp3")
+	if err := os.WriteFile(path, []byte("audio"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(path, Tags{Title: "Só o título"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := frames(t, data); len(found) != 1 || found["TIT2"] == nil {
+		t.Fatalf("wrote frames %v, want only TIT2", found)
+	}
+}
+
+func TestWriteRefusesInvalidTag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audio.mp3")
+	if err := os.WriteFile(path, []byte("ID3\x04\x00\x00\x80\x00\x00\x00audio"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(path, Tags{Title: "x"}); !errors.Is(err, ErrInvalidTag) {
+		t.Fatalf("Write over a tag with a bad size = %v, want ErrInvalidTag", err)
+	}
+}
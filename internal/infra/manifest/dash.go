@@ -0,0 +1,121 @@
+package manifest
+
+import (
+	"downloader/internal/domain"
+	"downloader/internal/infra/dash"
+	"fmt"
+	"strings"
+)
+
+// planDASH chooses the representations to download: a video one and the
+// audio one in the same container to merge with it, or a single audio one.
+func planDASH(m *dash.Manifest, video domain.Video, sel *selector) (*plan, error) {
+	if m.Dynamic {
+		return nil, ErrLive
+	}
+
+	var videos, audios []dash.Representation
+	for _, rep := range m.Representations {
+		switch rep.ContentType {
+		case "video":
+			videos = append(videos, rep)
+		case "audio":
+			audios = append(audios, rep)
+		}
+	}
+
+	p := &plan{duration: m.Duration}
+	if video.AudioOnly || len(videos) == 0 {
+		if len(audios) == 0 {
+			return nil, ErrNoAudioOnly
+		}
+		audio, err := chooseRepresentation(audios, sel)
+		if err != nil {
+			return nil, err
+		}
+		p.video = dashTrack(audio)
+		p.videoExt = dashExtension(audio)
+		p.extension, p.mimeType = p.videoExt, audio.MimeType
+		p.bandwidth = audio.Bandwidth
+		p.stream = domain.StreamInfo{Bitrate: audio.Bandwidth, AudioCodec: audio.Codecs}
+		log.Info(fmt.Sprintf("Representação selecionada: %s (%d bps)", audio.ID, audio.Bandwidth))
+		return p, nil
+	}
+
+	chosen, err := chooseRepresentation(videos, sel)
+	if err != nil {
+		return nil, err
+	}
+	p.video = dashTrack(chosen)
+	p.videoExt = dashExtension(chosen)
+	p.extension, p.mimeType = p.videoExt, chosen.MimeType
+	p.bandwidth = chosen.Bandwidth
+	p.stream = domain.StreamInfo{
+		Bitrate:    chosen.Bandwidth,
+		Width:      chosen.Width,
+		Height:     chosen.Height,
+		Resolution: resolution(chosen.Width, chosen.Height),
+		VideoCodec: chosen.Codecs,
+	}
+	log.Info(fmt.Sprintf("Representação selecionada: %s, %s (%d bps)", chosen.ID, p.stream.Resolution, chosen.Bandwidth))
+
+	// Only audio in the same container can be merged without converting.
+	container := mimeSubtype(chosen.MimeType)
+	var companions []dash.Representation
+	for _, audio := range audios {
+		if mimeSubtype(audio.MimeType) == container {
+			companions = append(companions, audio)
+		}
+	}
+	if len(companions) > 0 {
+		audio, err := chooseRepresentation(companions, &selector{raw: sel.raw, worst: sel.worst})
+		if err != nil {
+			return nil, err
+		}
+		p.audio = dashTrack(audio)
+		p.audioExt = dashExtension(audio)
+		p.bandwidth += audio.Bandwidth
+		p.stream.AudioCodec = audio.Codecs
+	}
+	return p, nil
+}
+
+func chooseRepresentation(reps []dash.Representation, sel *selector) (dash.Representation, error) {
+	candidates := make([]variant, len(reps))
+	for i, rep := range reps {
+		candidates[i] = variant{bandwidth: rep.Bandwidth, width: rep.Width, height: rep.Height}
+	}
+	i, err := sel.choose(candidates)
+	if err != nil {
+		return dash.Representation{}, err
+	}
+	return reps[i], nil
+}
+
+func dashTrack(rep dash.Representation) *track {
+	t := &track{}
+	if rep.Init != nil {
+		t.segments = append(t.segments, segment{url: rep.Init.URL, offset: rep.Init.Offset, length: rep.Init.Length})
+	}
+	for _, s := range rep.Segments {
+		t.segments = append(t.segments, segment{url: s.URL, offset: s.Offset, length: s.Length})
+	}
+	return t
+}
+
+// dashExtension names the file the segments of rep make once joined.
+func dashExtension(rep dash.Representation) string {
+	switch {
+	case mimeSubtype(rep.MimeType) == "webm":
+		return "webm"
+	case rep.ContentType == "audio":
+		return "m4a"
+	default:
+		return "mp4"
+	}
+}
+
+func mimeSubtype(mimeType string) string {
+	_, subtype, _ := strings.Cut(mimeType, "/")
+	return subtype
+}
@@ -0,0 +1,311 @@
+// Package manifest downloads media delivered in segments through HLS
+// playlists or DASH manifests.
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"downloader/internal/domain"
+	"downloader/internal/infra/dash"
+	"downloader/internal/infra/direct"
+	"downloader/internal/infra/download"
+	"downloader/internal/infra/hls"
+	"downloader/internal/infra/muxer"
+	"downloader/pkg/config"
+	logger "downloader/pkg/log"
+	"downloader/pkg/utils"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var log = logger.GetLogger("manifest")
+
+// maxManifestSize bounds the size of a playlist or MPD.
+const maxManifestSize = 16 << 20
+
+var (
+	ErrLive        = errors.New("manifest is live")
+	ErrMergeFailed = errors.New("error merging streams")
+)
+
+var mimeTypes = map[string]string{
+	"mp4":  "video/mp4",
+	"m4a":  "audio/mp4",
+	"webm": "video/webm",
+	"ts":   "video/mp2t",
+	"aac":  "audio/aac",
+	"mp3":  "audio/mpeg",
+}
+
+// Extensions are the URL path extensions of the manifests it downloads.
+var Extensions = []string{".m3u8", ".mpd"}
+
+// plan is what a manifest resolves to: a main track, which may carry the
+// audio too, and a separate audio track to merge with it, or nil. Live
+// tracks are recorded from the playlists at videoURI and audioURI.
+type plan struct {
+	video, audio       *track
+	videoExt, audioExt string
+	videoURI, audioURI string
+	live               bool
+	extension          string
+	mimeType           string
+	stream             domain.StreamInfo
+	bandwidth          int
+	duration           time.Duration
+}
+
+// ManifestDownloader downloads the variant of a HLS or DASH manifest chosen
+// by the format of the video, a selector such as "best,height<=720" or
+// "worst". Live HLS playlists are recorded until they end. Options that
+// need a single media file, such as clips, subtitles or chapters, do not
+// apply.
+type ManifestDownloader struct {
+	client   *http.Client
+	notifyer domain.Notifyer
+	db       domain.Database[domain.Video]
+	muxer    domain.Muxer
+}
+
+func NewManifestDownloader(notifyer domain.Notifyer, db domain.Database[domain.Video]) *ManifestDownloader {
+	return &ManifestDownloader{
+		client:   http.DefaultClient,
+		notifyer: notifyer,
+		db:       db,
+		muxer:    muxer.NewMuxer(config.GetConfig().FFmpegPath),
+	}
+}
+
+func (d *ManifestDownloader) Download(ctx context.Context, video domain.Video, progress domain.ProgressBar) error {
+	domain.ReportStage(progress, domain.JobFetchingMetadata)
+	sel, err := parseSelector(video.Format)
+	if err != nil {
+		return err
+	}
+	data, base, err := d.fetchManifest(ctx, video.URL)
+	if err != nil {
+		if ctx.Err() != nil {
+			return download.Stopped(ctx, d, video, video.URL)
+		}
+		return fmt.Errorf("error fetching manifest: %w", err)
+	}
+
+	var p *plan
+	switch {
+	case isPlaylist(data):
+		var playlist *hls.Playlist
+		if playlist, err = hls.Parse(data, base); err == nil {
+			p, err = d.planHLS(ctx, playlist, base.String(), video, sel)
+		}
+	case isMPD(data):
+		var m *dash.Manifest
+		if m, err = dash.Parse(data, base); err == nil {
+			p, err = planDASH(m, video, sel)
+		}
+	default:
+		return fmt.Errorf("%w: %s is not a HLS or DASH manifest", domain.ErrUnsupportedSource, video.URL)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return download.Stopped(ctx, d, video, video.URL)
+		}
+		return err
+	}
+	video.Title = manifestTitle(base)
+	return d.run(ctx, video, p, progress)
+}
+
+// Record records the livestream whose HLS playlist is at playlistURL as
+// video, keeping the title and metadata the caller found for it. A format
+// that is not a variant selector picks the best variant.
+func (d *ManifestDownloader) Record(ctx context.Context, video domain.Video, playlistURL string, progress domain.ProgressBar) error {
+	sel, err := parseSelector(video.Format)
+	if err != nil {
+		log.Info(fmt.Sprintf("Formato %q não se aplica à transmissão de %s, gravando a melhor variante", video.Format, video.Title))
+		sel = &selector{}
+	}
+	data, base, err := d.fetchManifest(ctx, playlistURL)
+	if err != nil {
+		if ctx.Err() != nil {
+			return download.Stopped(ctx, d, video, video.Title)
+		}
+		return fmt.Errorf("error fetching live playlist: %w", err)
+	}
+	if !isPlaylist(data) {
+		return fmt.Errorf("%w: live manifest is not a HLS playlist", hls.ErrInvalidPlaylist)
+	}
+	playlist, err := hls.Parse(data, base)
+	if err == nil {
+		var p *plan
+		if p, err = d.planHLS(ctx, playlist, base.String(), video, sel); err == nil {
+			return d.run(ctx, video, p, progress)
+		}
+	}
+	if ctx.Err() != nil {
+		return download.Stopped(ctx, d, video, video.Title)
+	}
+	return err
+}
+
+// run downloads, or records when live, the tracks of p as video and saves
+// it.
+func (d *ManifestDownloader) run(ctx context.Context, video domain.Video, p *plan, progress domain.ProgressBar) error {
+	cfg := config.GetConfig()
+	if video.Clipped() || video.SplitChapters || len(video.SubtitleLanguages) > 0 {
+		log.Info(fmt.Sprintf("Trecho, capítulos e legendas ignorados no download de %s", video.Title))
+	}
+
+	id := video.ID
+	if id == "" {
+		id = uuid.NewString()
+	}
+	video.ID = id
+	video.Filename = utils.SanitizeFilename(video.Title)
+	video.Extension, video.MimeType = p.extension, p.mimeType
+	if video.MimeType == "" {
+		video.MimeType = mimeTypes[p.extension]
+	}
+	video.Stream = p.stream
+	video.Metadata.Duration = p.duration
+	outputPath := filepath.Join(cfg.VideoDir, utils.SanitizeFilename(id+"."+p.extension))
+
+	tracks := []*track{p.video}
+	p.video.path = outputPath
+	if p.audio != nil {
+		p.video.path = filepath.Join(cfg.VideoDir, id+".video."+p.videoExt)
+		p.audio.path = filepath.Join(cfg.VideoDir, id+".audio."+p.audioExt)
+		tracks = append(tracks, p.audio)
+	}
+
+	domain.ReportStage(progress, domain.JobDownloading)
+	f := newFetcher(d.client, cfg.Chunks, progress, tracks)
+	var err error
+	if p.live {
+		log.Info(fmt.Sprintf("Gravação de %s iniciada!", video.Title))
+		video.Live = true
+		progress.Start(int64(p.bandwidth) / 8 * int64(video.LiveMaxDuration.Seconds()))
+		video.Metadata.Duration, err = d.record(ctx, f, p, video)
+	} else {
+		log.Info(fmt.Sprintf("Download de %s iniciado!", video.Title))
+		progress.Start(int64(p.bandwidth) / 8 * int64(p.duration.Seconds()))
+		for _, t := range tracks {
+			if err = f.save(ctx, t); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		removeTracks(tracks)
+		if ctx.Err() != nil {
+			return download.Stopped(ctx, d, video, video.Title)
+		}
+		return err
+	}
+
+	if p.audio != nil {
+		domain.ReportStage(progress, domain.JobPostProcessing)
+		log.Info(fmt.Sprintf("Unindo áudio e vídeo de %s", video.Title))
+		err := d.muxer.Merge(p.video.path, p.audio.path, outputPath)
+		removeTracks(tracks)
+		if err != nil {
+			os.Remove(outputPath)
+			return fmt.Errorf("%w: %w", ErrMergeFailed, err)
+		}
+	}
+	progress.Finish()
+
+	video.Path = outputPath
+	if video.Size, video.Checksum, err = download.FileChecksum(outputPath); err != nil {
+		log.Error(fmt.Sprintf("Erro ao calcular checksum de %s: %v", outputPath, err))
+	}
+	video.FinishedAt = time.Now()
+	video.CreatedAt = video.FinishedAt
+	if err := download.Save(d.db, video); err != nil {
+		return err
+	}
+	if d.notifyer != nil && !video.InPlaylist() {
+		d.Finalize(domain.Notification{
+			Title:   video.Title,
+			Message: id,
+			To:      video.Requester,
+			Kind:    domain.NotificationDone,
+		})
+	}
+	return nil
+}
+
+// fetchManifest returns the manifest at rawURL and the URL its references
+// are relative to, which is where a redirect led.
+func (d *ManifestDownloader) fetchManifest(ctx context.Context, rawURL string) ([]byte, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", domain.ErrUnsupportedSource, err)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, direct.StatusError(resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, resp.Request.URL, nil
+}
+
+func isPlaylist(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\ufeff")), " \t\r\n"), []byte("#EXTM3U"))
+}
+
+func isMPD(data []byte) bool {
+	head := data[:min(len(data), 1024)]
+	return bytes.Contains(head, []byte("<MPD"))
+}
+
+// manifestTitle names the download after the manifest file, or after its
+// directory when the file has a generic name such as "master.m3u8".
+func manifestTitle(u *url.URL) string {
+	name := strings.TrimSuffix(path.Base(u.Path), path.Ext(u.Path))
+	switch strings.ToLower(name) {
+	case "master", "index", "playlist", "manifest", "prog_index", "stream", "/", ".", "":
+		if dir := path.Base(path.Dir(u.Path)); dir != "/" && dir != "." {
+			return dir
+		}
+		return u.Hostname()
+	}
+	return name
+}
+
+func resolution(width, height int) string {
+	if height == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%dp", height)
+}
+
+func removeTracks(tracks []*track) {
+	for _, t := range tracks {
+		os.Remove(t.path)
+	}
+}
+
+func (d *ManifestDownloader) Finalize(notification domain.Notification) error {
+	return download.Finalize(d.notifyer, notification)
+}
+
+func (d *ManifestDownloader) Cancel(file *os.File) error {
+	return download.Cancel(file)
+}
@@ -0,0 +1,29 @@
+package manifest
+
+import (
+	"downloader/internal/infra/dash"
+	"downloader/internal/infra/direct"
+	"downloader/internal/infra/hls"
+	"errors"
+)
+
+// Retryable reports whether a download that failed with err may succeed
+// when attempted again. Segments are already retried one by one, so a
+// failed segment is not, and neither are manifests that cannot be read or
+// have nothing that matches the request.
+func Retryable(err error) bool {
+	switch {
+	case errors.Is(err, ErrSegmentFailed),
+		errors.Is(err, ErrLive),
+		errors.Is(err, ErrNoAudioOnly),
+		errors.Is(err, ErrMergeFailed),
+		errors.Is(err, ErrInvalidSelector),
+		errors.Is(err, ErrNoMatchingVariant),
+		errors.Is(err, hls.ErrInvalidPlaylist),
+		errors.Is(err, hls.ErrUnsupportedEncryption),
+		errors.Is(err, dash.ErrInvalidManifest),
+		errors.Is(err, dash.ErrUnsupportedManifest):
+		return false
+	}
+	return direct.Retryable(err)
+}
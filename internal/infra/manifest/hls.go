@@ -0,0 +1,192 @@
+package manifest
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"downloader/internal/infra/hls"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+var ErrNoAudioOnly = errors.New("manifest has no audio-only stream")
+
+// audioCodecs are the prefixes of the codecs that carry only audio.
+var audioCodecs = []string{"mp4a", "ac-3", "ec-3", "opus", "mp3", "flac"}
+
+// planHLS chooses the playlists to download from a master or media
+// playlist found at uri.
+func (d *ManifestDownloader) planHLS(ctx context.Context, playlist *hls.Playlist, uri string, video domain.Video, sel *selector) (*plan, error) {
+	p := &plan{videoURI: uri}
+	media := playlist
+	var audio *hls.Playlist
+	if playlist.IsMaster() {
+		variants := playlist.Variants
+		if video.AudioOnly {
+			if rendition := audioRendition(playlist, ""); rendition != nil {
+				log.Info(fmt.Sprintf("Áudio selecionado: %s", rendition.Name))
+				media, err := d.loadPlaylist(ctx, rendition.URI)
+				if err != nil {
+					return nil, err
+				}
+				p.videoURI = rendition.URI
+				return planHLSMedia(p, media, nil, true)
+			}
+			variants = slices.DeleteFunc(slices.Clone(variants), func(v hls.Variant) bool { return !audioOnly(v) })
+			if len(variants) == 0 {
+				return nil, ErrNoAudioOnly
+			}
+		}
+
+		candidates := make([]variant, len(variants))
+		for i, v := range variants {
+			candidates[i] = variant{bandwidth: v.Bandwidth, width: v.Width, height: v.Height}
+		}
+		i, err := sel.choose(candidates)
+		if err != nil {
+			return nil, err
+		}
+		chosen := variants[i]
+		log.Info(fmt.Sprintf("Variante selecionada: %s (%d bps)", resolution(chosen.Width, chosen.Height), chosen.Bandwidth))
+		p.stream = domain.StreamInfo{Bitrate: chosen.Bandwidth, Width: chosen.Width, Height: chosen.Height, Resolution: resolution(chosen.Width, chosen.Height)}
+		p.stream.VideoCodec, p.stream.AudioCodec = splitCodecs(chosen.Codecs)
+		p.bandwidth = chosen.Bandwidth
+
+		if media, err = d.loadPlaylist(ctx, chosen.URI); err != nil {
+			return nil, err
+		}
+		p.videoURI = chosen.URI
+		if rendition := audioRendition(playlist, chosen.Audio); rendition != nil && !video.AudioOnly {
+			if audio, err = d.loadPlaylist(ctx, rendition.URI); err != nil {
+				return nil, err
+			}
+			p.audioURI = rendition.URI
+		}
+	}
+	return planHLSMedia(p, media, audio, video.AudioOnly)
+}
+
+// planHLSMedia turns the media playlist, and the one of its separate audio
+// when not nil, into tracks. Playlists that have not ended are live, and
+// their tracks are left to be recorded.
+func planHLSMedia(p *plan, media, audio *hls.Playlist, audioOnly bool) (*plan, error) {
+	if len(media.Segments) == 0 {
+		return nil, fmt.Errorf("%w: empty playlist", hls.ErrInvalidPlaylist)
+	}
+
+	p.live = !media.Ended || (audio != nil && !audio.Ended)
+	p.duration = media.Duration()
+	p.video = &track{playlist: media}
+	p.videoExt = hlsExtension(media, audioOnly)
+	p.extension, p.mimeType = p.videoExt, mimeTypes[p.videoExt]
+	if !p.live {
+		p.video.segments, _ = hlsSegments(media.Segments, nil)
+	}
+	if audio != nil && len(audio.Segments) > 0 {
+		p.audio = &track{playlist: audio}
+		if !p.live {
+			p.audio.segments, _ = hlsSegments(audio.Segments, nil)
+		}
+		p.audioExt = hlsExtension(audio, true)
+		p.extension, p.mimeType = "mp4", mimeTypes["mp4"]
+	}
+	return p, nil
+}
+
+func (d *ManifestDownloader) loadPlaylist(ctx context.Context, uri string) (*hls.Playlist, error) {
+	data, base, err := d.fetchManifest(ctx, uri)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching playlist: %w", err)
+	}
+	playlist, err := hls.Parse(data, base)
+	if err != nil {
+		return nil, err
+	}
+	if playlist.IsMaster() {
+		return nil, fmt.Errorf("%w: nested master playlist", hls.ErrInvalidPlaylist)
+	}
+	return playlist, nil
+}
+
+// hlsSegments lists the segments of a media playlist, each initialization
+// section before the first segment that needs it unless it is current, the
+// one already written. It returns the section current after them.
+func hlsSegments(list []hls.Segment, current *hls.Map) ([]segment, *hls.Map) {
+	var segments []segment
+	for _, s := range list {
+		if s.Map != nil && (current == nil || *s.Map != *current) {
+			segments = append(segments, segment{url: s.Map.URI, offset: s.Map.Offset, length: s.Map.Length})
+			current = s.Map
+		}
+		segments = append(segments, segment{url: s.URI, offset: s.Offset, length: s.Length, key: s.Key, sequence: s.Sequence, duration: s.Duration})
+	}
+	return segments, current
+}
+
+// audioRendition returns the audio rendition of the group to download
+// apart, the default one first. An empty group looks in every group.
+func audioRendition(playlist *hls.Playlist, group string) *hls.Rendition {
+	var found *hls.Rendition
+	for i, r := range playlist.Renditions {
+		if r.Type != "AUDIO" || r.URI == "" || (group != "" && r.GroupID != group) {
+			continue
+		}
+		if found == nil || (r.Default && !found.Default) {
+			found = &playlist.Renditions[i]
+		}
+	}
+	return found
+}
+
+func audioOnly(v hls.Variant) bool {
+	if v.Width > 0 || v.Height > 0 || v.Codecs == "" {
+		return false
+	}
+	for _, codec := range strings.Split(v.Codecs, ",") {
+		codec = strings.TrimSpace(codec)
+		if !slices.ContainsFunc(audioCodecs, func(prefix string) bool { return strings.HasPrefix(codec, prefix) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// hlsExtension names the file the segments of media make once joined.
+func hlsExtension(media *hls.Playlist, audioOnly bool) string {
+	if media.Segments[0].Map != nil {
+		if audioOnly {
+			return "m4a"
+		}
+		return "mp4"
+	}
+	switch strings.ToLower(path.Ext(strings.Split(media.Segments[0].URI, "?")[0])) {
+	case ".aac":
+		return "aac"
+	case ".mp3":
+		return "mp3"
+	case ".m4s", ".mp4", ".m4a":
+		if audioOnly {
+			return "m4a"
+		}
+		return "mp4"
+	}
+	return "ts"
+}
+
+// splitCodecs separates the video and audio codecs of a CODECS attribute.
+func splitCodecs(codecs string) (string, string) {
+	var videoList, audioList []string
+	for _, codec := range strings.Split(codecs, ",") {
+		codec = strings.TrimSpace(codec)
+		switch {
+		case codec == "":
+		case slices.ContainsFunc(audioCodecs, func(prefix string) bool { return strings.HasPrefix(codec, prefix) }):
+			audioList = append(audioList, codec)
+		default:
+			videoList = append(videoList, codec)
+		}
+	}
+	return strings.Join(videoList, ","), strings.Join(audioList, ",")
+}
@@ -0,0 +1,218 @@
+package manifest
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"downloader/internal/infra/hls"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// liveEdgeSegments is how far from the end of a live playlist a recording
+// starts, since RFC 8216 advises against starting closer than three target
+// durations from it.
+const liveEdgeSegments = 3
+
+// minReloadDelay bounds how often a live playlist is fetched again.
+const minReloadDelay = time.Second
+
+// record follows the live tracks of p, appending the segments their
+// playlists add until the stream ends, video.LiveMaxDuration is reached or
+// the requester cancels. A cancelled recording keeps what it got, so only
+// other interruptions, or cancelling before anything was recorded, are
+// errors. It returns the length of the video track recorded.
+func (d *ManifestDownloader) record(ctx context.Context, f *fetcher, p *plan, video domain.Video) (time.Duration, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	type result struct {
+		recorded time.Duration
+		err      error
+	}
+	results := make([]result, 2)
+	var wg sync.WaitGroup
+	for i, t := range []*track{p.video, p.audio} {
+		if t == nil {
+			continue
+		}
+		uri := p.videoURI
+		if t == p.audio {
+			uri = p.audioURI
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recorded, err := d.recordTrack(ctx, f, t, uri, video)
+			if err != nil {
+				cancel(err)
+			}
+			results[i] = result{recorded, err}
+		}()
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		if r.err != nil {
+			return 0, r.err
+		}
+	}
+	if results[0].recorded == 0 && cancelled(ctx) {
+		return 0, context.Cause(ctx)
+	}
+	log.Info(fmt.Sprintf("Gravação de %s encerrada com %s", video.Title, results[0].recorded.Round(time.Second)))
+	return results[0].recorded, nil
+}
+
+// recordTrack records t to a .part file, moved to its path once the
+// recording stops.
+func (d *ManifestDownloader) recordTrack(ctx context.Context, f *fetcher, t *track, uri string, video domain.Video) (time.Duration, error) {
+	partPath := t.path + ".part"
+	file, err := os.Create(partPath)
+	if err != nil {
+		return 0, fmt.Errorf("error creating file: %w", err)
+	}
+	recorded, err := d.follow(ctx, f, t.playlist, uri, video, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(partPath)
+		return 0, err
+	}
+	if err := os.Rename(partPath, t.path); err != nil {
+		os.Remove(partPath)
+		return 0, fmt.Errorf("error moving %s: %w", partPath, err)
+	}
+	return recorded, nil
+}
+
+// follow writes to w the segments of the live playlist at uri, starting
+// with the ones of its first version, playlist, and reloading it about
+// once per target duration.
+func (d *ManifestDownloader) follow(ctx context.Context, f *fetcher, playlist *hls.Playlist, uri string, video domain.Video, w io.Writer) (time.Duration, error) {
+	var recorded time.Duration
+	var current *hls.Map
+	next := int64(-1)
+	for {
+		pending := newSegments(playlist, next)
+		if next < 0 && !video.LiveFromStart {
+			pending = pending[max(len(pending)-liveEdgeSegments, 0):]
+		}
+		if next >= 0 && len(pending) > 0 && pending[0].Sequence > next {
+			log.Info(fmt.Sprintf("Segmentos %d a %d de %s saíram da playlist antes de serem gravados", next, pending[0].Sequence-1, video.Title))
+		}
+		if video.LiveMaxDuration > 0 {
+			pending = limitSegments(pending, video.LiveMaxDuration-recorded)
+		}
+
+		if len(pending) > 0 {
+			segments, mapAfter := hlsSegments(pending, current)
+			f.total.Add(int64(len(segments)))
+			n, err := f.write(ctx, segments, w)
+			for _, s := range segments[:n] {
+				recorded += s.duration
+			}
+			if err != nil {
+				if cancelled(ctx) {
+					return recorded, nil
+				}
+				return 0, err
+			}
+			current = mapAfter
+			next = pending[len(pending)-1].Sequence + 1
+		}
+
+		switch {
+		case playlist.Ended:
+			log.Info(fmt.Sprintf("Transmissão de %s encerrada", video.Title))
+			return recorded, nil
+		case video.LiveMaxDuration > 0 && recorded >= video.LiveMaxDuration:
+			log.Info(fmt.Sprintf("Duração máxima de gravação de %s atingida", video.Title))
+			return recorded, nil
+		}
+
+		// A playlist that did not change is fetched again sooner.
+		delay := playlist.TargetDuration
+		if len(pending) == 0 {
+			delay /= 2
+		}
+		select {
+		case <-time.After(max(delay, minReloadDelay)):
+		case <-ctx.Done():
+			if cancelled(ctx) {
+				return recorded, nil
+			}
+			return 0, context.Cause(ctx)
+		}
+
+		reloaded, err := d.reloadPlaylist(ctx, f, uri)
+		switch {
+		case err == nil:
+			playlist = reloaded
+		case cancelled(ctx):
+			return recorded, nil
+		case ctx.Err() != nil:
+			return 0, context.Cause(ctx)
+		case recorded > 0:
+			// Ended streams often drop their playlist instead of closing it.
+			log.Info(fmt.Sprintf("Playlist de %s indisponível, encerrando a gravação: %v", video.Title, err))
+			return recorded, nil
+		default:
+			return 0, err
+		}
+	}
+}
+
+// reloadPlaylist fetches the live playlist at uri again, trying again
+// while the errors are transient.
+func (d *ManifestDownloader) reloadPlaylist(ctx context.Context, f *fetcher, uri string) (*hls.Playlist, error) {
+	for attempt := 1; ; attempt++ {
+		playlist, err := d.loadPlaylist(ctx, uri)
+		if err == nil {
+			return playlist, nil
+		}
+		if ctx.Err() != nil || attempt >= f.policy.MaxAttempts || !f.policy.Retryable(err) {
+			return nil, err
+		}
+
+		delay := f.policy.Delay(attempt)
+		log.Info(fmt.Sprintf("Tentativa %d de recarregar %s falhou, nova tentativa em %s: %v", attempt, uri, delay.Round(time.Millisecond), err))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, err
+		}
+	}
+}
+
+// newSegments returns the segments of playlist from sequence next on, or
+// all of them when next is negative.
+func newSegments(playlist *hls.Playlist, next int64) []hls.Segment {
+	for i, s := range playlist.Segments {
+		if s.Sequence >= next {
+			return playlist.Segments[i:]
+		}
+	}
+	return nil
+}
+
+// limitSegments keeps the segments needed to record remaining more.
+func limitSegments(segments []hls.Segment, remaining time.Duration) []hls.Segment {
+	for i, s := range segments {
+		if remaining <= 0 {
+			return segments[:i]
+		}
+		remaining -= s.Duration
+	}
+	return segments
+}
+
+// cancelled reports whether the requester stopped the download, as opposed
+// to the process shutting down or another track failing.
+func cancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), domain.ErrDownloadCancelled)
+}
@@ -0,0 +1,244 @@
+package manifest
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"downloader/internal/infra/direct"
+	"downloader/internal/infra/hls"
+	"downloader/internal/infra/retry"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxSegmentSize bounds the memory a segment may take while it waits for
+// the ones before it to be written.
+const maxSegmentSize = 256 << 20
+
+var ErrSegmentFailed = errors.New("error downloading segment")
+
+// segment is a resource of a stream, or the part of it starting at offset
+// when length is not 0. HLS segments may be encrypted with key.
+type segment struct {
+	url            string
+	offset, length int64
+	key            *hls.Key
+	sequence       int64
+	duration       time.Duration
+}
+
+// track is a stream saved to path by concatenating its segments. The
+// segments of a live track are read from playlist as it grows.
+type track struct {
+	segments []segment
+	playlist *hls.Playlist
+	path     string
+}
+
+// fetcher downloads the segments of the tracks of a download, several at
+// a time, retrying each one on transient errors.
+type fetcher struct {
+	client   *http.Client
+	workers  int
+	policy   retry.Policy
+	progress domain.ProgressBar
+
+	bytes atomic.Int64
+	done  atomic.Int64
+	total atomic.Int64
+
+	keysMu sync.Mutex
+	keys   map[string][]byte
+}
+
+func newFetcher(client *http.Client, workers int, progress domain.ProgressBar, tracks []*track) *fetcher {
+	f := &fetcher{
+		client:   client,
+		workers:  max(workers, 1),
+		policy:   retry.NewPolicy(retryableSegment),
+		progress: progress,
+		keys:     map[string][]byte{},
+	}
+	for _, t := range tracks {
+		f.total.Add(int64(len(t.segments)))
+	}
+	return f
+}
+
+type fetched struct {
+	data []byte
+	err  error
+}
+
+// save writes the segments of t in order to a .part file, moved to its
+// path once complete. Up to f.workers segments are fetched ahead.
+func (f *fetcher) save(ctx context.Context, t *track) error {
+	partPath := t.path + ".part"
+	file, err := os.Create(partPath)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	_, err = f.write(ctx, t.segments, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(partPath)
+		return err
+	}
+	if err := os.Rename(partPath, t.path); err != nil {
+		os.Remove(partPath)
+		return fmt.Errorf("error moving %s: %w", partPath, err)
+	}
+	return nil
+}
+
+// write appends segments to w in order, returning how many it wrote.
+func (f *fetcher) write(ctx context.Context, segments []segment, w io.Writer) (int, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	results := make([]chan fetched, len(segments))
+	for i := range results {
+		results[i] = make(chan fetched, 1)
+	}
+	slots := make(chan struct{}, f.workers)
+	go func() {
+		for i, s := range segments {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func() {
+				data, err := f.fetch(ctx, s)
+				results[i] <- fetched{data, err}
+			}()
+		}
+	}()
+
+	for i := range segments {
+		var r fetched
+		select {
+		case r = <-results[i]:
+		case <-ctx.Done():
+			return i, context.Cause(ctx)
+		}
+		<-slots
+		if r.err != nil {
+			cancel(r.err)
+			return i, r.err
+		}
+		if _, err := w.Write(r.data); err != nil {
+			return i, fmt.Errorf("error saving segment: %w", err)
+		}
+		f.progress.Update(f.bytes.Add(int64(len(r.data))))
+		domain.ReportSegments(f.progress, int(f.done.Add(1)), int(f.total.Load()))
+	}
+	return len(segments), nil
+}
+
+// fetch downloads and decrypts a segment, trying again while the errors
+// are transient.
+func (f *fetcher) fetch(ctx context.Context, s segment) ([]byte, error) {
+	for attempt := 1; ; attempt++ {
+		data, err := f.get(ctx, s.url, s.offset, s.length)
+		if err == nil && s.key != nil {
+			data, err = f.decrypt(ctx, s, data)
+		}
+		if err == nil {
+			return data, nil
+		}
+		if ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+		if attempt >= f.policy.MaxAttempts || !f.policy.Retryable(err) {
+			return nil, fmt.Errorf("%w %s: %w", ErrSegmentFailed, s.url, err)
+		}
+
+		delay := f.policy.Delay(attempt)
+		log.Info(fmt.Sprintf("Tentativa %d do segmento %s falhou, nova tentativa em %s: %v", attempt, s.url, delay.Round(time.Millisecond), err))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		}
+	}
+}
+
+func (f *fetcher) decrypt(ctx context.Context, s segment, data []byte) ([]byte, error) {
+	key, err := f.key(ctx, s.key.URI)
+	if err != nil {
+		return nil, err
+	}
+	return hls.Decrypt(data, s.key, key, s.sequence)
+}
+
+// key returns the AES key at uri, fetched once per download.
+func (f *fetcher) key(ctx context.Context, uri string) ([]byte, error) {
+	f.keysMu.Lock()
+	key, ok := f.keys[uri]
+	f.keysMu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	key, err := f.get(ctx, uri, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching key: %w", err)
+	}
+	if len(key) != 16 {
+		return nil, fmt.Errorf("%w: key of %d bytes", hls.ErrUnsupportedEncryption, len(key))
+	}
+	f.keysMu.Lock()
+	f.keys[uri] = key
+	f.keysMu.Unlock()
+	return key, nil
+}
+
+// get fetches url, or length bytes of it from offset when length is not 0.
+func (f *fetcher) get(ctx context.Context, url string, offset, length int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if length > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return nil, direct.StatusError(resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSegmentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSegmentSize {
+		return nil, fmt.Errorf("segment larger than %d bytes", maxSegmentSize)
+	}
+	if length > 0 && resp.StatusCode == http.StatusOK {
+		// The server ignored the range and sent the whole resource.
+		if int64(len(data)) < offset+length {
+			return nil, io.ErrUnexpectedEOF
+		}
+		data = data[offset : offset+length]
+	}
+	return data, nil
+}
+
+// retryableSegment tells the segment errors worth another try from the
+// ones that will not go away, such as a missing segment or a key of the
+// wrong size.
+func retryableSegment(err error) bool {
+	return !errors.Is(err, hls.ErrUnsupportedEncryption) && direct.Retryable(err)
+}
@@ -0,0 +1,125 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidSelector   = errors.New("invalid variant selector")
+	ErrNoMatchingVariant = errors.New("no variant matches the selector")
+)
+
+// variant is what the selector compares of a HLS variant or a DASH
+// representation.
+type variant struct {
+	bandwidth int
+	width     int
+	height    int
+}
+
+// selector is the parsed form of selectors such as "best,height<=720" or
+// "worst,bandwidth>=500000". Without terms the best variant is chosen:
+// the tallest one, then the one with the highest bandwidth.
+type selector struct {
+	raw     string
+	worst   bool
+	filters []func(variant) bool
+}
+
+func parseSelector(raw string) (*selector, error) {
+	s := &selector{raw: raw}
+	for _, term := range strings.Split(raw, ",") {
+		term = strings.ToLower(strings.TrimSpace(term))
+		switch {
+		case term == "":
+		case term == "best":
+			s.worst = false
+		case term == "worst":
+			s.worst = true
+		case strings.HasPrefix(term, "height"):
+			filter, err := parseComparison(strings.TrimPrefix(term, "height"), func(v variant) int { return v.height })
+			if err != nil {
+				return nil, fmt.Errorf("%w %q: %v", ErrInvalidSelector, raw, err)
+			}
+			s.filters = append(s.filters, filter)
+		case strings.HasPrefix(term, "bandwidth"):
+			filter, err := parseComparison(strings.TrimPrefix(term, "bandwidth"), func(v variant) int { return v.bandwidth })
+			if err != nil {
+				return nil, fmt.Errorf("%w %q: %v", ErrInvalidSelector, raw, err)
+			}
+			s.filters = append(s.filters, filter)
+		default:
+			return nil, fmt.Errorf("%w %q: unknown term %q", ErrInvalidSelector, raw, term)
+		}
+	}
+	return s, nil
+}
+
+func parseComparison(expr string, field func(variant) int) (func(variant) bool, error) {
+	var op string
+	for _, candidate := range []string{"<=", ">=", "<", ">", "="} {
+		if strings.HasPrefix(expr, candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return nil, fmt.Errorf("invalid comparison %q", expr)
+	}
+	limit, err := strconv.Atoi(strings.TrimPrefix(expr, op))
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", strings.TrimPrefix(expr, op))
+	}
+
+	compare := map[string]func(int) bool{
+		"<=": func(n int) bool { return n <= limit },
+		">=": func(n int) bool { return n >= limit },
+		"<":  func(n int) bool { return n < limit },
+		">":  func(n int) bool { return n > limit },
+		"=":  func(n int) bool { return n == limit },
+	}[op]
+	return func(v variant) bool { return compare(field(v)) }, nil
+}
+
+// choose returns the index of the variant that best satisfies the
+// selector.
+func (s *selector) choose(variants []variant) (int, error) {
+	var candidates []int
+	for i, v := range variants {
+		if !slices.ContainsFunc(s.filters, func(filter func(variant) bool) bool { return !filter(v) }) {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return 0, fmt.Errorf("%w %q (available: %s)", ErrNoMatchingVariant, s.raw, describeVariants(variants))
+	}
+
+	best := slices.MaxFunc(candidates, func(a, b int) int {
+		va, vb := variants[a], variants[b]
+		order := va.height - vb.height
+		if order == 0 {
+			order = va.bandwidth - vb.bandwidth
+		}
+		if s.worst {
+			return -order
+		}
+		return order
+	})
+	return best, nil
+}
+
+func describeVariants(variants []variant) string {
+	var list []string
+	for _, v := range variants {
+		if v.height > 0 {
+			list = append(list, fmt.Sprintf("%dp@%d", v.height, v.bandwidth))
+		} else {
+			list = append(list, strconv.Itoa(v.bandwidth))
+		}
+	}
+	return strings.Join(list, ", ")
+}
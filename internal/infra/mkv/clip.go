@@ -0,0 +1,378 @@
+package mkv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Segment is a cluster of a stream, as listed by its cues. Offset is where
+// it starts in the stream.
+type Segment struct {
+	Start    time.Duration
+	Duration time.Duration
+	Offset   int64
+	Size     int64
+}
+
+// Index is the layout of a stream.
+type Index struct {
+	Segments      []Segment
+	ebml          []byte
+	info          []Element
+	tracks        []byte
+	timecodeScale uint64
+}
+
+// ReadIndex reads the headers and the cues found in head, the first bytes
+// of a stream of the given size up to the end of its cues. Every cue point
+// starts a segment, which lasts until the next one.
+func ReadIndex(head []byte, size int64) (*Index, error) {
+	r := bytes.NewReader(head)
+	ebml, err := ReadHeader(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if ebml.ID != idEBML {
+		return nil, fmt.Errorf("%w: missing EBML header", ErrUnsupportedLayout)
+	}
+	segment, err := ReadHeader(r, ebml.End())
+	if err != nil {
+		return nil, err
+	}
+	if segment.ID != idSegment {
+		return nil, fmt.Errorf("%w: missing segment", ErrUnsupportedLayout)
+	}
+	end := size
+	if segment.Size != unknownSize {
+		end = min(end, segment.End())
+	}
+
+	idx := &Index{ebml: head[:ebml.End()], timecodeScale: defaultTimecodeScale}
+	var cues []Element
+	var duration float64
+	for offset := segment.DataOffset(); offset < int64(len(head)); {
+		h, err := ReadHeader(r, offset)
+		if err != nil {
+			return nil, err
+		}
+		if h.Size == unknownSize || h.End() > int64(len(head)) {
+			break
+		}
+
+		data := head[h.DataOffset():h.End()]
+		switch h.ID {
+		case idInfo:
+			if idx.info, err = ParseElements(data); err != nil {
+				return nil, err
+			}
+			for _, e := range idx.info {
+				switch e.ID {
+				case idTimecodeScale:
+					idx.timecodeScale = Uint(e.Data)
+				case idDuration:
+					duration = Float(e.Data)
+				}
+			}
+		case idTracks:
+			idx.tracks = head[h.Offset:h.End()]
+		case idCues:
+			if cues, err = ParseElements(data); err != nil {
+				return nil, err
+			}
+		}
+		offset = h.End()
+	}
+	if idx.tracks == nil || cues == nil {
+		return nil, fmt.Errorf("%w: missing tracks or cues", ErrUnsupportedLayout)
+	}
+
+	for _, point := range cues {
+		if point.ID != idCuePoint {
+			continue
+		}
+		cueTime, position, err := cuePoint(point)
+		if err != nil {
+			return nil, err
+		}
+
+		offset := segment.DataOffset() + int64(position)
+		if n := len(idx.Segments); n > 0 && idx.Segments[n-1].Offset >= offset {
+			continue
+		}
+		idx.Segments = append(idx.Segments, Segment{Start: idx.duration(float64(cueTime)), Offset: offset})
+	}
+	if len(idx.Segments) == 0 {
+		return nil, fmt.Errorf("%w: empty cues", ErrUnsupportedLayout)
+	}
+
+	for i := range idx.Segments {
+		s := &idx.Segments[i]
+		if i+1 < len(idx.Segments) {
+			next := idx.Segments[i+1]
+			s.Size, s.Duration = next.Offset-s.Offset, next.Start-s.Start
+			continue
+		}
+		s.Size = end - s.Offset
+		s.Duration = max(0, idx.duration(duration)-s.Start)
+	}
+	return idx, nil
+}
+
+// ReadClusters lists the clusters of a file of the given size. Unlike
+// ReadIndex it reads the whole file, which may hold several tracks.
+func ReadClusters(r io.ReaderAt, size int64) (*Index, error) {
+	ebml, err := ReadHeader(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if ebml.ID != idEBML {
+		return nil, fmt.Errorf("%w: missing EBML header", ErrUnsupportedLayout)
+	}
+	segment, err := ReadHeader(r, ebml.End())
+	if err != nil {
+		return nil, err
+	}
+	if segment.ID != idSegment {
+		return nil, fmt.Errorf("%w: missing segment", ErrUnsupportedLayout)
+	}
+	end := size
+	if segment.Size != unknownSize {
+		end = min(end, segment.End())
+	}
+	children, err := ScanElements(r, segment.DataOffset(), end)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &Index{ebml: make([]byte, ebml.End()), timecodeScale: defaultTimecodeScale}
+	if _, err := r.ReadAt(idx.ebml, 0); err != nil {
+		return nil, err
+	}
+	var duration float64
+	for _, h := range children {
+		switch h.ID {
+		case idInfo:
+			data := make([]byte, h.Size)
+			if _, err := r.ReadAt(data, h.DataOffset()); err != nil {
+				return nil, err
+			}
+			if idx.info, err = ParseElements(data); err != nil {
+				return nil, err
+			}
+			for _, e := range idx.info {
+				switch e.ID {
+				case idTimecodeScale:
+					idx.timecodeScale = Uint(e.Data)
+				case idDuration:
+					duration = Float(e.Data)
+				}
+			}
+		case idTracks:
+			idx.tracks = make([]byte, h.HeaderSize+h.Size)
+			if _, err := r.ReadAt(idx.tracks, h.Offset); err != nil {
+				return nil, err
+			}
+		case idCluster:
+			timecode, err := clusterTimecode(r, h)
+			if err != nil {
+				return nil, err
+			}
+			idx.Segments = append(idx.Segments, Segment{Start: time.Duration(timecode), Offset: h.Offset, Size: h.HeaderSize + h.Size})
+		}
+	}
+	if idx.tracks == nil || len(idx.Segments) == 0 {
+		return nil, fmt.Errorf("%w: missing tracks or clusters", ErrUnsupportedLayout)
+	}
+
+	for i := range idx.Segments {
+		idx.Segments[i].Start = idx.duration(float64(idx.Segments[i].Start))
+	}
+	for i := range idx.Segments {
+		s := &idx.Segments[i]
+		if i+1 < len(idx.Segments) {
+			s.Duration = idx.Segments[i+1].Start - s.Start
+		} else {
+			s.Duration = max(0, idx.duration(duration)-s.Start)
+		}
+	}
+	return idx, nil
+}
+
+func clusterTimecode(r io.ReaderAt, cluster Header) (uint64, error) {
+	for offset := cluster.DataOffset(); offset < cluster.End(); {
+		child, err := ReadHeader(r, offset)
+		if err != nil {
+			return 0, err
+		}
+		if child.ID == idTimecode {
+			data := make([]byte, child.Size)
+			if _, err := r.ReadAt(data, child.DataOffset()); err != nil {
+				return 0, err
+			}
+			return Uint(data), nil
+		}
+		offset = child.End()
+	}
+	return 0, nil
+}
+
+// Cut writes the part of the Matroska file at inputPath between start and
+// end to outputPath, widened to the clusters around it. A zero end runs to
+// the end of the file.
+func Cut(inputPath, outputPath string, start, end time.Duration) error {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", inputPath, err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	idx, err := ReadClusters(file, stat.Size())
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", inputPath, err)
+	}
+
+	first, last := 0, len(idx.Segments)-1
+	for i, s := range idx.Segments {
+		if s.Start <= start {
+			first = i
+		}
+	}
+	if end > 0 {
+		last = first
+		for i := first; i < len(idx.Segments) && idx.Segments[i].Start < end; i++ {
+			last = i
+		}
+	}
+	from, to := idx.Segments[first].Offset, idx.Segments[last].Offset+idx.Segments[last].Size
+	base := idx.Segments[first].Start
+	stop := idx.Segments[last].Start + idx.Segments[last].Duration
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	err = idx.WriteClip(out, io.NewSectionReader(file, from, to-from), to-from, base, stop-base)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(outputPath)
+		return fmt.Errorf("error writing %s: %w", outputPath, err)
+	}
+	return nil
+}
+
+func cuePoint(point Element) (uint64, uint64, error) {
+	children, err := ParseElements(point.Data)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var cueTime, position uint64
+	found := false
+	for _, e := range children {
+		switch e.ID {
+		case idCueTime:
+			cueTime = Uint(e.Data)
+		case idCueTrackPositions:
+			if found {
+				continue
+			}
+			positions, err := ParseElements(e.Data)
+			if err != nil {
+				return 0, 0, err
+			}
+			for _, p := range positions {
+				if p.ID == idCueClusterPosition {
+					position, found = Uint(p.Data), true
+				}
+			}
+		}
+	}
+	if !found {
+		return 0, 0, fmt.Errorf("%w: cue point without cluster position", ErrInvalidElement)
+	}
+	return cueTime, position, nil
+}
+
+func (idx *Index) duration(timecode float64) time.Duration {
+	return time.Duration(timecode * float64(idx.timecodeScale))
+}
+
+// WriteClip writes a file made of the headers of the stream and the
+// clusters stored in the first size bytes of data, which must start at a
+// segment. Cluster timecodes are moved back by base, so streams clipped
+// with the same base stay in sync, and the file is said to last duration.
+func (idx *Index) WriteClip(w io.Writer, data io.ReaderAt, size int64, base, duration time.Duration) error {
+	clusters, err := ScanElements(data, 0, size)
+	if err != nil {
+		return err
+	}
+
+	var info []byte
+	for _, e := range idx.info {
+		if e.ID != idDuration {
+			info = AppendElement(info, e.ID, e.Data)
+		}
+	}
+	info = AppendFloat(info, idDuration, float64(duration)/float64(idx.timecodeScale))
+	info = AppendElement(nil, idInfo, info)
+
+	// Timecodes are rewritten with their original length, so every cluster
+	// keeps its size and the segment size is known upfront.
+	segmentSize := int64(len(info) + len(idx.tracks))
+	for _, h := range clusters {
+		if h.ID == idCluster {
+			segmentSize += h.HeaderSize + h.Size
+		}
+	}
+
+	segment := appendID(nil, idSegment)
+	segment = appendVint(segment, uint64(segmentSize), 8)
+	for _, part := range [][]byte{idx.ebml, segment, info, idx.tracks} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+
+	shift := uint64(base) / idx.timecodeScale
+	for _, h := range clusters {
+		if h.ID != idCluster {
+			continue
+		}
+		raw := make([]byte, h.HeaderSize+h.Size)
+		if _, err := data.ReadAt(raw, h.Offset); err != nil {
+			return fmt.Errorf("error reading cluster: %w", err)
+		}
+		children, err := ParseElements(raw[h.HeaderSize:])
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			if child.ID == idTimecode {
+				timecode := Uint(child.Data)
+				putUint(child.Data, timecode-min(timecode, shift))
+			}
+		}
+		if _, err := w.Write(raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putUint stores value in the payload of an unsigned integer element,
+// keeping its length.
+func putUint(data []byte, value uint64) {
+	for i := len(data) - 1; i >= 0; i-- {
+		data[i] = byte(value)
+		value >>= 8
+	}
+}
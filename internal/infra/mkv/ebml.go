@@ -0,0 +1,286 @@
+package mkv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+var ErrInvalidElement = errors.New("invalid ebml element")
+
+// Element IDs used by the remuxer, as defined by the Matroska specification.
+const (
+	idEBML               = 0x1A45DFA3
+	idEBMLVersion        = 0x4286
+	idEBMLReadVersion    = 0x42F7
+	idEBMLMaxIDLength    = 0x42F2
+	idEBMLMaxSizeLength  = 0x42F3
+	idDocType            = 0x4282
+	idDocTypeVersion     = 0x4287
+	idDocTypeReadVersion = 0x4285
+	idSegment            = 0x18538067
+	idSeekHead           = 0x114D9B74
+	idSeek               = 0x4DBB
+	idSeekID             = 0x53AB
+	idSeekPosition       = 0x53AC
+	idInfo               = 0x1549A966
+	idTimecodeScale      = 0x2AD7B1
+	idDuration           = 0x4489
+	idTracks             = 0x1654AE6B
+	idTrackEntry         = 0xAE
+	idTrackNumber        = 0xD7
+	idTrackUID           = 0x73C5
+	idCues               = 0x1C53BB6B
+	idCuePoint           = 0xBB
+	idCueTime            = 0xB3
+	idCueTrackPositions  = 0xB7
+	idCueTrack           = 0xF7
+	idCueClusterPosition = 0xF1
+	idCluster            = 0x1F43B675
+	idTimecode           = 0xE7
+	idSimpleBlock        = 0xA3
+	idBlockGroup         = 0xA0
+	idBlock              = 0xA1
+	idVoid               = 0xEC
+)
+
+const unknownSize = -1
+
+// Header describes an element found while scanning a file.
+type Header struct {
+	ID         uint32
+	Offset     int64
+	Size       int64
+	HeaderSize int64
+}
+
+// DataOffset returns the offset of the first byte of the element payload.
+func (h Header) DataOffset() int64 {
+	return h.Offset + h.HeaderSize
+}
+
+// End returns the offset of the first byte after the element.
+func (h Header) End() int64 {
+	return h.DataOffset() + h.Size
+}
+
+// ReadHeader reads the element header stored at offset.
+func ReadHeader(r io.ReaderAt, offset int64) (Header, error) {
+	buf := make([]byte, 12)
+	n, err := r.ReadAt(buf, offset)
+	if n == 0 && err != nil {
+		return Header{}, err
+	}
+	buf = buf[:n]
+
+	id, idLen, err := readID(buf)
+	if err != nil {
+		return Header{}, fmt.Errorf("%w at %d: %v", ErrInvalidElement, offset, err)
+	}
+
+	size, sizeLen, err := readVint(buf[idLen:])
+	if err != nil {
+		return Header{}, fmt.Errorf("%w at %d: %v", ErrInvalidElement, offset, err)
+	}
+
+	return Header{ID: id, Offset: offset, Size: size, HeaderSize: int64(idLen + sizeLen)}, nil
+}
+
+// ScanElements lists the elements stored between start and end of r.
+func ScanElements(r io.ReaderAt, start, end int64) ([]Header, error) {
+	var headers []Header
+	for offset := start; offset < end; {
+		h, err := ReadHeader(r, offset)
+		if err != nil {
+			return nil, err
+		}
+		if h.Size == unknownSize {
+			return nil, fmt.Errorf("%w: element %X at %d has unknown size", ErrInvalidElement, h.ID, offset)
+		}
+		if h.End() > end {
+			return nil, fmt.Errorf("%w: element %X at %d overflows its parent", ErrInvalidElement, h.ID, offset)
+		}
+		headers = append(headers, h)
+		offset = h.End()
+	}
+	return headers, nil
+}
+
+// Element is an EBML element held in memory as its raw payload.
+type Element struct {
+	ID   uint32
+	Data []byte
+}
+
+// ParseElements splits a payload into its child elements.
+func ParseElements(data []byte) ([]Element, error) {
+	var elements []Element
+	for len(data) > 0 {
+		id, idLen, err := readID(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidElement, err)
+		}
+		size, sizeLen, err := readVint(data[idLen:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidElement, err)
+		}
+
+		start := idLen + sizeLen
+		if size == unknownSize || int64(start)+size > int64(len(data)) {
+			return nil, fmt.Errorf("%w: element %X has size %d", ErrInvalidElement, id, size)
+		}
+
+		elements = append(elements, Element{ID: id, Data: data[start : start+int(size)]})
+		data = data[start+int(size):]
+	}
+	return elements, nil
+}
+
+// Bytes serializes the element.
+func (e Element) Bytes() []byte {
+	return AppendElement(nil, e.ID, e.Data)
+}
+
+// AppendElement appends an element with the given payload to buf.
+func AppendElement(buf []byte, id uint32, data []byte) []byte {
+	buf = appendID(buf, id)
+	buf = appendVint(buf, uint64(len(data)), 0)
+	return append(buf, data...)
+}
+
+// AppendUint appends an unsigned integer element.
+func AppendUint(buf []byte, id uint32, value uint64) []byte {
+	var data []byte
+	for shift := 56; shift >= 0; shift -= 8 {
+		if b := byte(value >> shift); b != 0 || len(data) > 0 || shift == 0 {
+			data = append(data, b)
+		}
+	}
+	return AppendElement(buf, id, data)
+}
+
+// AppendFixedUint appends an unsigned integer element stored in eight
+// bytes, so it can be patched later without changing the layout.
+func AppendFixedUint(buf []byte, id uint32, value uint64) []byte {
+	return AppendElement(buf, id, binary.BigEndian.AppendUint64(nil, value))
+}
+
+// AppendFloat appends a 64 bit float element.
+func AppendFloat(buf []byte, id uint32, value float64) []byte {
+	return AppendElement(buf, id, binary.BigEndian.AppendUint64(nil, math.Float64bits(value)))
+}
+
+// AppendString appends a string element.
+func AppendString(buf []byte, id uint32, value string) []byte {
+	return AppendElement(buf, id, []byte(value))
+}
+
+// Uint decodes the payload of an unsigned integer element.
+func Uint(data []byte) uint64 {
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+// Float decodes the payload of a float element.
+func Float(data []byte) float64 {
+	switch len(data) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	default:
+		return 0
+	}
+}
+
+// readID reads an element ID, keeping its length marker as Matroska does.
+func readID(buf []byte) (uint32, int, error) {
+	if len(buf) == 0 {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+
+	length := vintLength(buf[0])
+	if length == 0 || length > 4 {
+		return 0, 0, fmt.Errorf("invalid id marker %#x", buf[0])
+	}
+	if len(buf) < length {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+
+	var id uint32
+	for _, b := range buf[:length] {
+		id = id<<8 | uint32(b)
+	}
+	return id, length, nil
+}
+
+// readVint reads a variable size integer, returning unknownSize when all
+// its value bits are set.
+func readVint(buf []byte) (int64, int, error) {
+	if len(buf) == 0 {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+
+	length := vintLength(buf[0])
+	if length == 0 {
+		return 0, 0, fmt.Errorf("invalid size marker %#x", buf[0])
+	}
+	if len(buf) < length {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+
+	value := uint64(buf[0]) & (0xFF >> length)
+	allOnes := value == 0xFF>>length
+	for _, b := range buf[1:length] {
+		value = value<<8 | uint64(b)
+		allOnes = allOnes && b == 0xFF
+	}
+	if allOnes {
+		return unknownSize, length, nil
+	}
+	return int64(value), length, nil
+}
+
+func vintLength(first byte) int {
+	for i := 0; i < 8; i++ {
+		if first&(0x80>>i) != 0 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func appendID(buf []byte, id uint32) []byte {
+	switch {
+	case id > 0xFFFFFF:
+		return append(buf, byte(id>>24), byte(id>>16), byte(id>>8), byte(id))
+	case id > 0xFFFF:
+		return append(buf, byte(id>>16), byte(id>>8), byte(id))
+	case id > 0xFF:
+		return append(buf, byte(id>>8), byte(id))
+	default:
+		return append(buf, byte(id))
+	}
+}
+
+// appendVint appends value as a variable size integer. A zero length picks
+// the shortest encoding.
+func appendVint(buf []byte, value uint64, length int) []byte {
+	if length == 0 {
+		length = 1
+		for length < 8 && value >= (1<<(7*length))-1 {
+			length++
+		}
+	}
+
+	encoded := value | 1<<(7*length)
+	for i := length - 1; i >= 0; i-- {
+		buf = append(buf, byte(encoded>>(8*i)))
+	}
+	return buf
+}
@@ -0,0 +1,415 @@
+package mkv
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var ErrUnsupportedLayout = errors.New("unsupported matroska layout")
+
+const (
+	videoTrackNumber = 1
+	audioTrackNumber = 2
+
+	defaultTimecodeScale = 1000000
+)
+
+// source is a WebM/Matroska input file with a single track.
+type source struct {
+	file          *os.File
+	info          []Element
+	track         []Element
+	clusters      []cluster
+	timecodeScale uint64
+	duration      float64
+}
+
+type cluster struct {
+	header   Header
+	timecode uint64
+}
+
+// Merge remuxes the video track of videoPath and the audio track of
+// audioPath into a single Matroska file at outputPath, without
+// re-encoding. docType is written in the EBML header ("matroska" or "webm").
+func Merge(videoPath, audioPath, outputPath, docType string) error {
+	video, err := openSource(videoPath)
+	if err != nil {
+		return err
+	}
+	defer video.file.Close()
+
+	audio, err := openSource(audioPath)
+	if err != nil {
+		return err
+	}
+	defer audio.file.Close()
+
+	if video.timecodeScale != audio.timecodeScale {
+		return fmt.Errorf("%w: inputs use different timecode scales", ErrUnsupportedLayout)
+	}
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer out.Close()
+
+	if err := writeMerged(out, video, audio, docType); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func openSource(path string) (*source, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", path, err)
+	}
+
+	src, err := readSource(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return src, nil
+}
+
+func readSource(file *os.File) (*source, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	ebml, err := ReadHeader(file, 0)
+	if err != nil {
+		return nil, err
+	}
+	if ebml.ID != idEBML {
+		return nil, fmt.Errorf("%w: missing EBML header", ErrUnsupportedLayout)
+	}
+
+	segment, err := ReadHeader(file, ebml.End())
+	if err != nil {
+		return nil, err
+	}
+	if segment.ID != idSegment {
+		return nil, fmt.Errorf("%w: missing segment", ErrUnsupportedLayout)
+	}
+
+	end := stat.Size()
+	if segment.Size != unknownSize {
+		end = min(end, segment.End())
+	}
+
+	children, err := ScanElements(file, segment.DataOffset(), end)
+	if err != nil {
+		return nil, err
+	}
+
+	src := &source{file: file, timecodeScale: defaultTimecodeScale}
+	for _, h := range children {
+		switch h.ID {
+		case idInfo:
+			err = src.readInfo(h)
+		case idTracks:
+			err = src.readTracks(h)
+		case idCluster:
+			err = src.addCluster(h)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if src.track == nil {
+		return nil, fmt.Errorf("%w: missing track entry", ErrUnsupportedLayout)
+	}
+	return src, nil
+}
+
+func (s *source) readElements(h Header) ([]Element, error) {
+	data := make([]byte, h.Size)
+	if _, err := s.file.ReadAt(data, h.DataOffset()); err != nil {
+		return nil, err
+	}
+	return ParseElements(data)
+}
+
+func (s *source) readInfo(h Header) error {
+	info, err := s.readElements(h)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range info {
+		switch e.ID {
+		case idTimecodeScale:
+			s.timecodeScale = Uint(e.Data)
+		case idDuration:
+			s.duration = Float(e.Data)
+		}
+	}
+	s.info = info
+	return nil
+}
+
+func (s *source) readTracks(h Header) error {
+	tracks, err := s.readElements(h)
+	if err != nil {
+		return err
+	}
+
+	var entries []Element
+	for _, e := range tracks {
+		if e.ID == idTrackEntry {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) != 1 {
+		return fmt.Errorf("%w: expected exactly one track", ErrUnsupportedLayout)
+	}
+
+	s.track, err = ParseElements(entries[0].Data)
+	return err
+}
+
+func (s *source) addCluster(h Header) error {
+	c := cluster{header: h}
+	for offset := h.DataOffset(); offset < h.End(); {
+		child, err := ReadHeader(s.file, offset)
+		if err != nil {
+			return err
+		}
+		if child.ID == idTimecode {
+			data := make([]byte, child.Size)
+			if _, err := s.file.ReadAt(data, child.DataOffset()); err != nil {
+				return err
+			}
+			c.timecode = Uint(data)
+			break
+		}
+		offset = child.End()
+	}
+
+	s.clusters = append(s.clusters, c)
+	return nil
+}
+
+func writeMerged(out *os.File, video, audio *source, docType string) error {
+	buffered := bufio.NewWriter(out)
+	w := &countingWriter{w: buffered}
+
+	var header []byte
+	header = AppendUint(header, idEBMLVersion, 1)
+	header = AppendUint(header, idEBMLReadVersion, 1)
+	header = AppendUint(header, idEBMLMaxIDLength, 4)
+	header = AppendUint(header, idEBMLMaxSizeLength, 8)
+	header = AppendString(header, idDocType, docType)
+	header = AppendUint(header, idDocTypeVersion, 4)
+	header = AppendUint(header, idDocTypeReadVersion, 2)
+	if _, err := w.Write(AppendElement(nil, idEBML, header)); err != nil {
+		return err
+	}
+
+	// The segment size and the seek positions are only known at the end,
+	// so they are written with fixed lengths and patched afterwards.
+	segmentSizeOffset := w.n + 4
+	segment := appendID(nil, idSegment)
+	segment = appendVint(segment, 0, 8)
+	if _, err := w.Write(segment); err != nil {
+		return err
+	}
+	segmentStart := w.n
+
+	seekIDs := []uint32{idInfo, idTracks, idCues}
+	seekOffsets := make(map[uint32]int64, len(seekIDs))
+	var seeks []byte
+	for _, id := range seekIDs {
+		var seek []byte
+		seek = AppendElement(seek, idSeekID, appendID(nil, id))
+		seek = AppendFixedUint(seek, idSeekPosition, 0)
+		seeks = AppendElement(seeks, idSeek, seek)
+		seekOffsets[id] = int64(len(seeks)) - 8
+	}
+	seekHead := AppendElement(nil, idSeekHead, seeks)
+	seeksStart := w.n + int64(len(seekHead)-len(seeks))
+	if _, err := w.Write(seekHead); err != nil {
+		return err
+	}
+
+	positions := map[uint32]int64{idInfo: w.n - segmentStart}
+	if _, err := w.Write(mergeInfo(video, audio)); err != nil {
+		return err
+	}
+
+	positions[idTracks] = w.n - segmentStart
+	var tracks []byte
+	tracks = AppendElement(tracks, idTrackEntry, trackEntry(video.track, videoTrackNumber, 0))
+	tracks = AppendElement(tracks, idTrackEntry, trackEntry(audio.track, audioTrackNumber, trackUID(video.track)))
+	if _, err := w.Write(AppendElement(nil, idTracks, tracks)); err != nil {
+		return err
+	}
+
+	cues, err := writeClusters(w, video, audio, segmentStart)
+	if err != nil {
+		return err
+	}
+
+	positions[idCues] = w.n - segmentStart
+	if _, err := w.Write(AppendElement(nil, idCues, cues)); err != nil {
+		return err
+	}
+
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+
+	if _, err := out.WriteAt(appendVint(nil, uint64(w.n-segmentStart), 8), segmentSizeOffset); err != nil {
+		return err
+	}
+	for id, offset := range seekOffsets {
+		position := binary.BigEndian.AppendUint64(nil, uint64(positions[id]))
+		if _, err := out.WriteAt(position, seeksStart+offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mergeInfo(video, audio *source) []byte {
+	var info []byte
+	for _, e := range video.info {
+		if e.ID != idDuration {
+			info = AppendElement(info, e.ID, e.Data)
+		}
+	}
+	info = AppendFloat(info, idDuration, max(video.duration, audio.duration))
+	return AppendElement(nil, idInfo, info)
+}
+
+// trackEntry rebuilds a TrackEntry with a new track number. When the UID
+// collides with avoidUID a new one is derived from the track number.
+func trackEntry(track []Element, number, avoidUID uint64) []byte {
+	var entry []byte
+	for _, e := range track {
+		switch e.ID {
+		case idTrackNumber:
+			entry = AppendUint(entry, idTrackNumber, number)
+		case idTrackUID:
+			uid := Uint(e.Data)
+			if uid == avoidUID {
+				uid = avoidUID + number
+			}
+			entry = AppendUint(entry, idTrackUID, uid)
+		default:
+			entry = AppendElement(entry, e.ID, e.Data)
+		}
+	}
+	return entry
+}
+
+func trackUID(track []Element) uint64 {
+	for _, e := range track {
+		if e.ID == idTrackUID {
+			return Uint(e.Data)
+		}
+	}
+	return 0
+}
+
+// writeClusters copies the clusters of both inputs ordered by timecode and
+// returns the payload of a Cues element indexing the video clusters.
+func writeClusters(w *countingWriter, video, audio *source, segmentStart int64) ([]byte, error) {
+	var cues []byte
+	i, j := 0, 0
+	for i < len(video.clusters) || j < len(audio.clusters) {
+		if j >= len(audio.clusters) || (i < len(video.clusters) && video.clusters[i].timecode <= audio.clusters[j].timecode) {
+			c := video.clusters[i]
+
+			var positions []byte
+			positions = AppendUint(positions, idCueTrack, videoTrackNumber)
+			positions = AppendUint(positions, idCueClusterPosition, uint64(w.n-segmentStart))
+			var point []byte
+			point = AppendUint(point, idCueTime, c.timecode)
+			point = AppendElement(point, idCueTrackPositions, positions)
+			cues = AppendElement(cues, idCuePoint, point)
+
+			if err := copyCluster(w, video, c, videoTrackNumber); err != nil {
+				return nil, err
+			}
+			i++
+			continue
+		}
+
+		if err := copyCluster(w, audio, audio.clusters[j], audioTrackNumber); err != nil {
+			return nil, err
+		}
+		j++
+	}
+	return cues, nil
+}
+
+// copyCluster writes a cluster with every block moved to the given track.
+// Track numbers are rewritten in place with the same length, so the
+// cluster keeps its size.
+func copyCluster(w io.Writer, src *source, c cluster, number uint64) error {
+	raw := make([]byte, c.header.HeaderSize+c.header.Size)
+	if _, err := src.file.ReadAt(raw, c.header.Offset); err != nil {
+		return fmt.Errorf("error reading cluster: %w", err)
+	}
+
+	children, err := ParseElements(raw[c.header.HeaderSize:])
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		switch child.ID {
+		case idSimpleBlock:
+			if err := setBlockTrack(child.Data, number); err != nil {
+				return err
+			}
+		case idBlockGroup:
+			group, err := ParseElements(child.Data)
+			if err != nil {
+				return err
+			}
+			for _, e := range group {
+				if e.ID != idBlock {
+					continue
+				}
+				if err := setBlockTrack(e.Data, number); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	_, err = w.Write(raw)
+	return err
+}
+
+func setBlockTrack(block []byte, number uint64) error {
+	_, length, err := readVint(block)
+	if err != nil {
+		return fmt.Errorf("%w: invalid block: %v", ErrInvalidElement, err)
+	}
+	copy(block[:length], appendVint(nil, number, length))
+	return nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
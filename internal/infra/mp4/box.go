@@ -0,0 +1,273 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrInvalidBox = errors.New("invalid mp4 box")
+
+// containers lists the box types whose payload is made only of child boxes.
+var containers = map[string]bool{
+	"moov": true,
+	"trak": true,
+	"mdia": true,
+	"minf": true,
+	"stbl": true,
+	"mvex": true,
+	"moof": true,
+	"traf": true,
+	"edts": true,
+	"dinf": true,
+	"udta": true,
+}
+
+// Box is an ISO BMFF box held in memory. Container boxes keep their
+// children parsed, every other box keeps its raw payload.
+type Box struct {
+	Type     string
+	Payload  []byte
+	Children []*Box
+}
+
+// Header describes a box found while scanning a file, without loading it.
+type Header struct {
+	Type       string
+	Offset     int64
+	Size       int64
+	HeaderSize int64
+}
+
+// End returns the offset of the first byte after the box.
+func (h Header) End() int64 {
+	return h.Offset + h.Size
+}
+
+// ScanBoxes lists the boxes stored between start and end of r.
+func ScanBoxes(r io.ReaderAt, start, end int64) ([]Header, error) {
+	var headers []Header
+	for offset := start; offset < end; {
+		h, err := readHeader(r, offset, end)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, h)
+		offset = h.End()
+	}
+	return headers, nil
+}
+
+func readHeader(r io.ReaderAt, offset, end int64) (Header, error) {
+	buf := make([]byte, 16)
+	if _, err := r.ReadAt(buf[:8], offset); err != nil {
+		return Header{}, fmt.Errorf("%w: reading header at %d: %v", ErrInvalidBox, offset, err)
+	}
+
+	h := Header{
+		Type:       string(buf[4:8]),
+		Offset:     offset,
+		Size:       int64(binary.BigEndian.Uint32(buf[:4])),
+		HeaderSize: 8,
+	}
+
+	switch h.Size {
+	case 0:
+		h.Size = end - offset
+	case 1:
+		if _, err := r.ReadAt(buf[8:16], offset+8); err != nil {
+			return Header{}, fmt.Errorf("%w: reading large size at %d: %v", ErrInvalidBox, offset, err)
+		}
+		h.Size = int64(binary.BigEndian.Uint64(buf[8:16]))
+		h.HeaderSize = 16
+	}
+
+	if h.Size < h.HeaderSize || h.End() > end {
+		return Header{}, fmt.Errorf("%w: box %q at %d has size %d", ErrInvalidBox, h.Type, offset, h.Size)
+	}
+	return h, nil
+}
+
+// ReadBox loads the box described by h.
+func ReadBox(r io.ReaderAt, h Header) (*Box, error) {
+	payload := make([]byte, h.Size-h.HeaderSize)
+	if _, err := r.ReadAt(payload, h.Offset+h.HeaderSize); err != nil {
+		return nil, fmt.Errorf("%w: reading %q at %d: %v", ErrInvalidBox, h.Type, h.Offset, err)
+	}
+	return NewBox(h.Type, payload)
+}
+
+// NewBox builds a box from its payload, parsing the children of containers.
+func NewBox(typ string, payload []byte) (*Box, error) {
+	if !containers[typ] {
+		return &Box{Type: typ, Payload: payload}, nil
+	}
+
+	children, err := parseBoxes(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{Type: typ, Children: children}, nil
+}
+
+func parseBoxes(data []byte) ([]*Box, error) {
+	var boxes []*Box
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, fmt.Errorf("%w: truncated header", ErrInvalidBox)
+		}
+
+		size := int64(binary.BigEndian.Uint32(data[:4]))
+		typ := string(data[4:8])
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			size = int64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return nil, fmt.Errorf("%w: truncated large size", ErrInvalidBox)
+			}
+			size = int64(binary.BigEndian.Uint64(data[8:16]))
+			headerSize = 16
+		}
+		if size < headerSize || size > int64(len(data)) {
+			return nil, fmt.Errorf("%w: box %q has size %d", ErrInvalidBox, typ, size)
+		}
+
+		box, err := NewBox(typ, data[headerSize:size])
+		if err != nil {
+			return nil, err
+		}
+		boxes = append(boxes, box)
+		data = data[size:]
+	}
+	return boxes, nil
+}
+
+// Size returns the serialized size of the box, header included.
+func (b *Box) Size() int64 {
+	size := b.payloadSize() + 8
+	if size > 0xFFFFFFFF {
+		size += 8
+	}
+	return size
+}
+
+func (b *Box) payloadSize() int64 {
+	if b.Children == nil {
+		return int64(len(b.Payload))
+	}
+
+	var size int64
+	for _, child := range b.Children {
+		size += child.Size()
+	}
+	return size
+}
+
+// Bytes serializes the box.
+func (b *Box) Bytes() []byte {
+	buf := make([]byte, 0, b.Size())
+	return b.appendTo(buf)
+}
+
+// WriteTo serializes the box into w.
+func (b *Box) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(b.Bytes())
+	return int64(n), err
+}
+
+func (b *Box) appendTo(buf []byte) []byte {
+	size := b.Size()
+	if size > 0xFFFFFFFF {
+		buf = binary.BigEndian.AppendUint32(buf, 1)
+		buf = append(buf, b.Type...)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(size))
+	} else {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(size))
+		buf = append(buf, b.Type...)
+	}
+
+	if b.Children == nil {
+		return append(buf, b.Payload...)
+	}
+	for _, child := range b.Children {
+		buf = child.appendTo(buf)
+	}
+	return buf
+}
+
+// Child returns the first direct child of the given type.
+func (b *Box) Child(typ string) *Box {
+	for _, child := range b.Children {
+		if child.Type == typ {
+			return child
+		}
+	}
+	return nil
+}
+
+// ChildrenOf returns all direct children of the given type.
+func (b *Box) ChildrenOf(typ string) []*Box {
+	var found []*Box
+	for _, child := range b.Children {
+		if child.Type == typ {
+			found = append(found, child)
+		}
+	}
+	return found
+}
+
+// Find follows the path of box types starting at b.
+func (b *Box) Find(path ...string) *Box {
+	current := b
+	for _, typ := range path {
+		if current = current.Child(typ); current == nil {
+			return nil
+		}
+	}
+	return current
+}
+
+// Remove drops every direct child of the given type.
+func (b *Box) Remove(typ string) {
+	children := b.Children[:0]
+	for _, child := range b.Children {
+		if child.Type != typ {
+			children = append(children, child)
+		}
+	}
+	b.Children = children
+}
+
+// Clone returns a deep copy of the box.
+func (b *Box) Clone() *Box {
+	clone := &Box{Type: b.Type}
+	if b.Payload != nil {
+		clone.Payload = append([]byte(nil), b.Payload...)
+	}
+	if b.Children != nil {
+		clone.Children = make([]*Box, len(b.Children))
+		for i, child := range b.Children {
+			clone.Children[i] = child.Clone()
+		}
+	}
+	return clone
+}
+
+// Version returns the version byte of a full box.
+func (b *Box) Version() byte {
+	if len(b.Payload) == 0 {
+		return 0
+	}
+	return b.Payload[0]
+}
+
+// Flags returns the 24 bit flags of a full box.
+func (b *Box) Flags() uint32 {
+	if len(b.Payload) < 4 {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b.Payload[:4]) & 0xFFFFFF
+}
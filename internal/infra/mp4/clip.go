@@ -0,0 +1,412 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Segment is a part of a fragmented stream that starts at a keyframe, as
+// listed by its sidx box or found in the file. Offset is where it starts in
+// the stream.
+type Segment struct {
+	Track    uint32
+	Start    time.Duration
+	Duration time.Duration
+	Offset   int64
+	Size     int64
+}
+
+// Index is the layout of a fragmented stream.
+type Index struct {
+	Segments []Segment
+	ftyp     *Box
+	moov     *Box
+	// timescales holds the media timescale of each track by track ID.
+	timescales map[uint32]uint32
+}
+
+// ReadIndex reads the init segment and the sidx box found in head, the
+// first bytes of a fragmented single track stream up to the end of its
+// index.
+func ReadIndex(head []byte) (*Index, error) {
+	r := bytes.NewReader(head)
+	headers, err := ScanBoxes(r, 0, int64(len(head)))
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &Index{}
+	var sidx *Box
+	var sidxEnd int64
+	for _, h := range headers {
+		switch h.Type {
+		case "ftyp":
+			idx.ftyp, err = ReadBox(r, h)
+		case "moov":
+			idx.moov, err = ReadBox(r, h)
+		case "sidx":
+			if sidx == nil {
+				sidx, err = ReadBox(r, h)
+				sidxEnd = h.End()
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if sidx == nil {
+		return nil, fmt.Errorf("%w: missing sidx", ErrUnsupportedLayout)
+	}
+	if err := idx.readTracks(); err != nil {
+		return nil, err
+	}
+	if len(idx.timescales) != 1 {
+		return nil, fmt.Errorf("%w: expected exactly one track", ErrUnsupportedLayout)
+	}
+
+	if idx.Segments, err = parseSegmentIndex(sidx, sidxEnd); err != nil {
+		return nil, err
+	}
+	for id := range idx.timescales {
+		for i := range idx.Segments {
+			idx.Segments[i].Track = id
+		}
+	}
+	return idx, nil
+}
+
+// ReadFragments lists the fragments of a fragmented file of the given size,
+// each one a moof box with the data that follows it.
+func ReadFragments(r io.ReaderAt, size int64) (*Index, error) {
+	headers, err := ScanBoxes(r, 0, size)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &Index{}
+	for i, h := range headers {
+		switch h.Type {
+		case "ftyp":
+			idx.ftyp, err = ReadBox(r, h)
+		case "moov":
+			idx.moov, err = ReadBox(r, h)
+		case "moof":
+			err = idx.addFragment(r, headers, i)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := idx.readTracks(); err != nil {
+		return nil, err
+	}
+	if len(idx.Segments) == 0 {
+		return nil, fmt.Errorf("%w: no fragments", ErrUnsupportedLayout)
+	}
+
+	// A fragment lasts until the next one of its track, the last ones until
+	// the end of the movie.
+	movieTimescale, movieDuration, err := MovieTimescale(idx.moov.Child("mvhd"))
+	if err != nil {
+		return nil, err
+	}
+	if mehd := idx.moov.Find("mvex", "mehd"); mehd != nil {
+		if movieDuration, err = FragmentDuration(mehd); err != nil {
+			return nil, err
+		}
+	}
+	total := toDuration(movieDuration, max(movieTimescale, 1))
+	next := map[uint32]time.Duration{}
+	for i := len(idx.Segments) - 1; i >= 0; i-- {
+		s := &idx.Segments[i]
+		end, ok := next[s.Track]
+		if !ok {
+			end = total
+		}
+		s.Duration = max(0, end-s.Start)
+		next[s.Track] = s.Start
+	}
+	return idx, nil
+}
+
+// addFragment adds the fragment starting at headers[i], which runs until
+// the next moof or the end of the file.
+func (idx *Index) addFragment(r io.ReaderAt, headers []Header, i int) error {
+	moof, err := ReadBox(r, headers[i])
+	if err != nil {
+		return err
+	}
+	traf := moof.Child("traf")
+	if traf == nil || traf.Child("tfhd") == nil || traf.Child("tfdt") == nil {
+		return fmt.Errorf("%w: fragment at %d without tfhd or tfdt", ErrUnsupportedLayout, headers[i].Offset)
+	}
+	track, err := FragmentTrackID(traf.Child("tfhd"))
+	if err != nil {
+		return err
+	}
+	start, err := BaseMediaDecodeTime(traf.Child("tfdt"))
+	if err != nil {
+		return err
+	}
+
+	end := headers[i].End()
+	for _, h := range headers[i+1:] {
+		if h.Type == "moof" || h.Type == "mfra" {
+			break
+		}
+		end = h.End()
+	}
+
+	// The start is kept in track units until the timescales are known.
+	idx.Segments = append(idx.Segments, Segment{
+		Track:  track,
+		Start:  time.Duration(start),
+		Offset: headers[i].Offset,
+		Size:   end - headers[i].Offset,
+	})
+	return nil
+}
+
+// readTracks checks the init segment and records the timescale of every
+// track, converting the start of the fragments found so far.
+func (idx *Index) readTracks() error {
+	if idx.ftyp == nil || idx.moov == nil {
+		return fmt.Errorf("%w: missing ftyp or moov", ErrUnsupportedLayout)
+	}
+	if idx.moov.Child("mvex") == nil {
+		return fmt.Errorf("%w: file is not fragmented", ErrUnsupportedLayout)
+	}
+
+	idx.timescales = map[uint32]uint32{}
+	for _, trak := range idx.moov.ChildrenOf("trak") {
+		tkhd, mdhd := trak.Child("tkhd"), trak.Find("mdia", "mdhd")
+		if tkhd == nil || mdhd == nil {
+			return fmt.Errorf("%w: missing tkhd or mdhd", ErrUnsupportedLayout)
+		}
+		id, err := TrackID(tkhd)
+		if err != nil {
+			return err
+		}
+		timescale, err := MediaTimescale(mdhd)
+		if err != nil {
+			return err
+		}
+		idx.timescales[id] = max(timescale, 1)
+	}
+
+	for i := range idx.Segments {
+		s := &idx.Segments[i]
+		timescale, ok := idx.timescales[s.Track]
+		if !ok {
+			return fmt.Errorf("%w: fragment of unknown track %d", ErrUnsupportedLayout, s.Track)
+		}
+		s.Start = toDuration(uint64(s.Start), timescale)
+	}
+	return nil
+}
+
+// parseSegmentIndex lists the references of a sidx box ending at end.
+func parseSegmentIndex(sidx *Box, end int64) ([]Segment, error) {
+	p := sidx.Payload
+	invalid := fmt.Errorf("%w: truncated sidx", ErrInvalidBox)
+	if len(p) < 20 {
+		return nil, invalid
+	}
+
+	timescale := binary.BigEndian.Uint32(p[8:12])
+	var t, firstOffset uint64
+	pos := 20
+	if sidx.Version() == 0 {
+		t = uint64(binary.BigEndian.Uint32(p[12:16]))
+		firstOffset = uint64(binary.BigEndian.Uint32(p[16:20]))
+	} else {
+		if len(p) < 28 {
+			return nil, invalid
+		}
+		t = binary.BigEndian.Uint64(p[12:20])
+		firstOffset = binary.BigEndian.Uint64(p[20:28])
+		pos = 28
+	}
+	if len(p) < pos+4 || timescale == 0 {
+		return nil, invalid
+	}
+	count := int(binary.BigEndian.Uint16(p[pos+2 : pos+4]))
+	pos += 4
+	if len(p) < pos+count*12 {
+		return nil, invalid
+	}
+
+	offset := end + int64(firstOffset)
+	segments := make([]Segment, 0, count)
+	for i := 0; i < count; i++ {
+		ref := binary.BigEndian.Uint32(p[pos:])
+		if ref&0x80000000 != 0 {
+			return nil, fmt.Errorf("%w: nested sidx", ErrUnsupportedLayout)
+		}
+		size := int64(ref & 0x7FFFFFFF)
+		duration := uint64(binary.BigEndian.Uint32(p[pos+4:]))
+
+		segments = append(segments, Segment{
+			Start:    toDuration(t, timescale),
+			Duration: toDuration(duration, timescale),
+			Offset:   offset,
+			Size:     size,
+		})
+		offset += size
+		t += duration
+		pos += 12
+	}
+	return segments, nil
+}
+
+// Cut writes the part of the fragmented MP4 file at inputPath between start
+// and end to outputPath, widened to the fragments around it. A zero end
+// runs to the end of the file.
+func Cut(inputPath, outputPath string, start, end time.Duration) error {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", inputPath, err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	idx, err := ReadFragments(file, stat.Size())
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", inputPath, err)
+	}
+
+	// Every track starts at its last fragment before start, so the cut
+	// begins at a keyframe of each one.
+	first, last := map[uint32]int{}, map[uint32]int{}
+	for i, s := range idx.Segments {
+		if _, ok := first[s.Track]; !ok || s.Start <= start {
+			first[s.Track] = i
+		}
+		if _, ok := last[s.Track]; !ok || end == 0 || s.Start < end {
+			last[s.Track] = i
+		}
+	}
+
+	from, to := stat.Size(), int64(0)
+	base, stop := time.Duration(-1), time.Duration(0)
+	for track, i := range first {
+		s, l := idx.Segments[i], idx.Segments[last[track]]
+		from, to = min(from, s.Offset), max(to, l.Offset+l.Size)
+		if base < 0 || s.Start < base {
+			base = s.Start
+		}
+		stop = max(stop, l.Start+l.Duration)
+	}
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	err = idx.WriteClip(out, io.NewSectionReader(file, from, to-from), from, to-from, base, stop-base)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(outputPath)
+		return fmt.Errorf("error writing %s: %w", outputPath, err)
+	}
+	return nil
+}
+
+// WriteClip writes a fragmented file made of the init segment and the
+// fragments stored in data, the size bytes of the stream found at offset,
+// which must start at a segment. Decode times are moved back by base, so
+// streams clipped with the same base stay in sync, and the file is said to
+// last duration.
+func (idx *Index) WriteClip(out io.Writer, data io.ReaderAt, offset, size int64, base, duration time.Duration) error {
+	moov := idx.moov.Clone()
+	if mehd := moov.Find("mvex", "mehd"); mehd != nil {
+		movieTimescale, _, err := MovieTimescale(moov.Child("mvhd"))
+		if err != nil {
+			return err
+		}
+		if err := SetFragmentDuration(mehd, fromDuration(duration, movieTimescale)); err != nil {
+			return err
+		}
+	}
+
+	w := &countingWriter{w: out}
+	if _, err := idx.ftyp.WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := moov.WriteTo(w); err != nil {
+		return err
+	}
+
+	headers, err := ScanBoxes(data, 0, size)
+	if err != nil {
+		return err
+	}
+	for _, h := range headers {
+		switch h.Type {
+		case "moof":
+			moof, err := ReadBox(data, h)
+			if err != nil {
+				return err
+			}
+			for _, traf := range moof.ChildrenOf("traf") {
+				tfhd := traf.Child("tfhd")
+				if tfhd == nil {
+					continue
+				}
+				if err := ShiftBaseDataOffset(tfhd, w.n-offset-h.Offset); err != nil {
+					return err
+				}
+				if tfdt := traf.Child("tfdt"); tfdt != nil {
+					if err := idx.rebase(tfhd, tfdt, base); err != nil {
+						return err
+					}
+				}
+			}
+			if _, err := moof.WriteTo(w); err != nil {
+				return err
+			}
+		case "sidx", "mfra":
+			// The indexes point at the offsets of the whole stream.
+		default:
+			if _, err := io.Copy(w, io.NewSectionReader(data, h.Offset, h.Size)); err != nil {
+				return fmt.Errorf("error copying fragment data: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// rebase moves the decode time in tfdt back by base, in the timescale of
+// the track tfhd belongs to.
+func (idx *Index) rebase(tfhd, tfdt *Box, base time.Duration) error {
+	track, err := FragmentTrackID(tfhd)
+	if err != nil {
+		return err
+	}
+	t, err := BaseMediaDecodeTime(tfdt)
+	if err != nil {
+		return err
+	}
+	shift := fromDuration(base, idx.timescales[track])
+	return SetBaseMediaDecodeTime(tfdt, t-min(t, shift))
+}
+
+func toDuration(value uint64, timescale uint32) time.Duration {
+	ts := uint64(timescale)
+	return time.Duration(value/ts)*time.Second + time.Duration(value%ts*uint64(time.Second)/ts)
+}
+
+func fromDuration(d time.Duration, timescale uint32) uint64 {
+	seconds, rest := uint64(d/time.Second), uint64(d%time.Second)
+	return seconds*uint64(timescale) + rest*uint64(timescale)/uint64(time.Second)
+}
@@ -0,0 +1,153 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fullBox returns the payload of a version 0 full box followed by fields.
+func fullBox(fields ...uint32) []byte {
+	p := make([]byte, 4)
+	for _, f := range fields {
+		p = binary.BigEndian.AppendUint32(p, f)
+	}
+	return p
+}
+
+// fragmentedFile builds a single track fragmented file with a timescale of
+// 1000 and one fragment per tfdt payload, each followed by a small mdat.
+func fragmentedFile(tfdts ...[]byte) []byte {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:], uint32(len(tfdts))*2000)
+	tkhd := make([]byte, 84)
+	binary.BigEndian.PutUint32(tkhd[12:], 1)
+	mdhd := fullBox(0, 0, 1000, 0, 0)
+
+	moov := &Box{Type: "moov", Children: []*Box{
+		{Type: "mvhd", Payload: mvhd},
+		{Type: "trak", Children: []*Box{
+			{Type: "tkhd", Payload: tkhd},
+			{Type: "mdia", Children: []*Box{{Type: "mdhd", Payload: mdhd}}},
+		}},
+		{Type: "mvex", Children: []*Box{{Type: "trex", Payload: fullBox(1, 1, 0, 0, 0)}}},
+	}}
+
+	buf := (&Box{Type: "ftyp", Payload: []byte("iso6\x00\x00\x00\x00")}).Bytes()
+	buf = append(buf, moov.Bytes()...)
+	for i, tfdt := range tfdts {
+		moof := &Box{Type: "moof", Children: []*Box{
+			{Type: "mfhd", Payload: fullBox(uint32(i + 1))},
+			{Type: "traf", Children: []*Box{
+				{Type: "tfhd", Payload: fullBox(1)},
+				{Type: "tfdt", Payload: tfdt},
+			}},
+		}}
+		buf = append(buf, moof.Bytes()...)
+		buf = append(buf, (&Box{Type: "mdat", Payload: []byte{byte(i), byte(i)}}).Bytes()...)
+	}
+	return buf
+}
+
+func TestReadFragments(t *testing.T) {
+	cases := []struct {
+		name    string
+		tfdts   [][]byte
+		want    []time.Duration
+		wantErr error
+	}{
+		{"version 0", [][]byte{fullBox(0), fullBox(2000)}, []time.Duration{0, 2 * time.Second}, nil},
+		{"version 1", [][]byte{{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x07, 0xD0}}, []time.Duration{2 * time.Second}, nil},
+		{"tfdt of 4 bytes", [][]byte{fullBox(0), {0, 0, 0, 0}}, nil, ErrInvalidBox},
+		{"version 1 tfdt of 8 bytes", [][]byte{{1, 0, 0, 0, 0, 0, 0, 0}}, nil, ErrInvalidBox},
+		{"empty tfdt", [][]byte{{}}, nil, ErrInvalidBox},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			file := fragmentedFile(c.tfdts...)
+			idx, err := ReadFragments(bytes.NewReader(file), int64(len(file)))
+			if c.wantErr != nil {
+				if !errors.Is(err, c.wantErr) {
+					t.Fatalf("got %v, want %v", err, c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(idx.Segments) != len(c.want) {
+				t.Fatalf("got %d segments, want %d", len(idx.Segments), len(c.want))
+			}
+			for i, s := range idx.Segments {
+				if s.Start != c.want[i] {
+					t.Errorf("segment %d starts at %s, want %s", i, s.Start, c.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadFragmentsRejectsShortInitBoxes(t *testing.T) {
+	cases := []struct {
+		name  string
+		strip string
+	}{
+		{"mvhd", "mvhd"},
+		{"tkhd", "tkhd"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			file := fragmentedFile(fullBox(0))
+			headers, err := ScanBoxes(bytes.NewReader(file), 0, int64(len(file)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			moov, err := ReadBox(bytes.NewReader(file), headers[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			box := moov.Child(c.strip)
+			if box == nil {
+				box = moov.Find("trak", c.strip)
+			}
+			box.Payload = box.Payload[:4]
+
+			var out []byte
+			out = append(out, file[:headers[1].Offset]...)
+			out = append(out, moov.Bytes()...)
+			out = append(out, file[headers[1].End():]...)
+			if _, err := ReadFragments(bytes.NewReader(out), int64(len(out))); !errors.Is(err, ErrInvalidBox) {
+				t.Fatalf("got %v, want ErrInvalidBox", err)
+			}
+		})
+	}
+}
+
+func TestCut(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "in.mp4"), filepath.Join(dir, "out.mp4")
+	if err := os.WriteFile(input, fragmentedFile(fullBox(0), fullBox(2000), fullBox(4000)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Cut(input, output, 2500*time.Millisecond, 3*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := ReadFragments(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Segments) != 1 || idx.Segments[0].Start != 0 {
+		t.Fatalf("got segments %+v, want one starting at 0", idx.Segments)
+	}
+}
@@ -0,0 +1,296 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The helpers below read and patch the few fixed position fields of full
+// boxes that remuxing needs. Offsets follow ISO/IEC 14496-12. Boxes that
+// are missing or too short for a field give ErrInvalidBox.
+
+// need checks that box exists and its payload holds at least n bytes.
+func need(box *Box, typ string, n int) error {
+	if box == nil {
+		return fmt.Errorf("%w: missing %s", ErrInvalidBox, typ)
+	}
+	if len(box.Payload) < n {
+		return fmt.Errorf("%w: %s of %d bytes, want %d", ErrInvalidBox, box.Type, len(box.Payload), n)
+	}
+	return nil
+}
+
+// versioned returns the offset of a field stored at v0 in version 0 boxes
+// and at v1 in version 1 ones, checking that size bytes fit there.
+func versioned(box *Box, typ string, v0, v1, size0, size1 int) (int, bool, error) {
+	if err := need(box, typ, 1); err != nil {
+		return 0, false, err
+	}
+	if box.Version() == 1 {
+		return v1, true, need(box, typ, v1+size1)
+	}
+	return v0, false, need(box, typ, v0+size0)
+}
+
+// MovieTimescale returns the timescale and duration of a mvhd box.
+func MovieTimescale(mvhd *Box) (timescale uint32, duration uint64, err error) {
+	at, long, err := versioned(mvhd, "mvhd", 12, 20, 8, 12)
+	if err != nil {
+		return 0, 0, err
+	}
+	p := mvhd.Payload
+	if long {
+		return binary.BigEndian.Uint32(p[at : at+4]), binary.BigEndian.Uint64(p[at+4 : at+12]), nil
+	}
+	return binary.BigEndian.Uint32(p[at : at+4]), uint64(binary.BigEndian.Uint32(p[at+4 : at+8])), nil
+}
+
+// SetMovieDuration updates the duration of a mvhd box.
+func SetMovieDuration(mvhd *Box, duration uint64) error {
+	at, long, err := versioned(mvhd, "mvhd", 16, 24, 4, 8)
+	if err != nil {
+		return err
+	}
+	putTime(mvhd.Payload[at:], long, duration)
+	return nil
+}
+
+// SetNextTrackID updates the last field of a mvhd box.
+func SetNextTrackID(mvhd *Box, id uint32) error {
+	if err := need(mvhd, "mvhd", 24); err != nil {
+		return err
+	}
+	p := mvhd.Payload
+	binary.BigEndian.PutUint32(p[len(p)-4:], id)
+	return nil
+}
+
+// TrackID returns the track ID of a tkhd box.
+func TrackID(tkhd *Box) (uint32, error) {
+	at, _, err := versioned(tkhd, "tkhd", 12, 20, 4, 4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(tkhd.Payload[at : at+4]), nil
+}
+
+// SetTrackID updates the track ID of a tkhd box.
+func SetTrackID(tkhd *Box, id uint32) error {
+	at, _, err := versioned(tkhd, "tkhd", 12, 20, 4, 4)
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint32(tkhd.Payload[at:at+4], id)
+	return nil
+}
+
+// TrackDuration returns the duration of a tkhd box, in movie timescale.
+func TrackDuration(tkhd *Box) (uint64, error) {
+	at, long, err := versioned(tkhd, "tkhd", 20, 28, 4, 8)
+	if err != nil {
+		return 0, err
+	}
+	return readTime(tkhd.Payload[at:], long), nil
+}
+
+// SetTrackDuration updates the duration of a tkhd box.
+func SetTrackDuration(tkhd *Box, duration uint64) error {
+	at, long, err := versioned(tkhd, "tkhd", 20, 28, 4, 8)
+	if err != nil {
+		return err
+	}
+	putTime(tkhd.Payload[at:], long, duration)
+	return nil
+}
+
+// MediaTimescale returns the timescale of a mdhd box.
+func MediaTimescale(mdhd *Box) (uint32, error) {
+	at, _, err := versioned(mdhd, "mdhd", 12, 20, 4, 4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(mdhd.Payload[at : at+4]), nil
+}
+
+// SetTrexTrackID updates the track ID of a trex box.
+func SetTrexTrackID(trex *Box, id uint32) error {
+	if err := need(trex, "trex", 8); err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint32(trex.Payload[4:8], id)
+	return nil
+}
+
+// FragmentTrackID returns the track ID of a tfhd box.
+func FragmentTrackID(tfhd *Box) (uint32, error) {
+	if err := need(tfhd, "tfhd", 8); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(tfhd.Payload[4:8]), nil
+}
+
+// SetFragmentTrackID updates the track ID of a tfhd box.
+func SetFragmentTrackID(tfhd *Box, id uint32) error {
+	if err := need(tfhd, "tfhd", 8); err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint32(tfhd.Payload[4:8], id)
+	return nil
+}
+
+// ShiftBaseDataOffset moves the explicit base data offset of a tfhd box,
+// when present, by delta bytes.
+func ShiftBaseDataOffset(tfhd *Box, delta int64) error {
+	if err := need(tfhd, "tfhd", 8); err != nil {
+		return err
+	}
+	if tfhd.Flags()&0x000001 == 0 {
+		return nil
+	}
+	if err := need(tfhd, "tfhd", 16); err != nil {
+		return err
+	}
+	offset := int64(binary.BigEndian.Uint64(tfhd.Payload[8:16]))
+	binary.BigEndian.PutUint64(tfhd.Payload[8:16], uint64(offset+delta))
+	return nil
+}
+
+// SetSequenceNumber updates the sequence number of a mfhd box.
+func SetSequenceNumber(mfhd *Box, seq uint32) error {
+	if err := need(mfhd, "mfhd", 8); err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint32(mfhd.Payload[4:8], seq)
+	return nil
+}
+
+// BaseMediaDecodeTime returns the decode time of a tfdt box.
+func BaseMediaDecodeTime(tfdt *Box) (uint64, error) {
+	at, long, err := versioned(tfdt, "tfdt", 4, 4, 4, 8)
+	if err != nil {
+		return 0, err
+	}
+	return readTime(tfdt.Payload[at:], long), nil
+}
+
+// SetBaseMediaDecodeTime updates the decode time of a tfdt box.
+func SetBaseMediaDecodeTime(tfdt *Box, t uint64) error {
+	at, long, err := versioned(tfdt, "tfdt", 4, 4, 4, 8)
+	if err != nil {
+		return err
+	}
+	putTime(tfdt.Payload[at:], long, t)
+	return nil
+}
+
+// FragmentDuration returns the duration stored in a mehd box.
+func FragmentDuration(mehd *Box) (uint64, error) {
+	at, long, err := versioned(mehd, "mehd", 4, 4, 4, 8)
+	if err != nil {
+		return 0, err
+	}
+	return readTime(mehd.Payload[at:], long), nil
+}
+
+// SetFragmentDuration updates the duration stored in a mehd box.
+func SetFragmentDuration(mehd *Box, duration uint64) error {
+	at, long, err := versioned(mehd, "mehd", 4, 4, 4, 8)
+	if err != nil {
+		return err
+	}
+	putTime(mehd.Payload[at:], long, duration)
+	return nil
+}
+
+// ScaleEditList converts the segment durations of an elst box, which are
+// expressed in the movie timescale, from one timescale to another.
+func ScaleEditList(elst *Box, from, to uint32) error {
+	if err := need(elst, "elst", 8); err != nil {
+		return err
+	}
+	if from == to || from == 0 {
+		return nil
+	}
+
+	p := elst.Payload
+	count := int(binary.BigEndian.Uint32(p[4:8]))
+	entrySize := 12
+	if elst.Version() == 1 {
+		entrySize = 20
+	}
+	if (len(p)-8)/entrySize < count {
+		return fmt.Errorf("%w: elst with %d entries in %d bytes", ErrInvalidBox, count, len(p))
+	}
+
+	for i := 0; i < count; i++ {
+		entry := p[8+i*entrySize:]
+		if elst.Version() == 1 {
+			binary.BigEndian.PutUint64(entry[:8], scale(binary.BigEndian.Uint64(entry[:8]), from, to))
+			continue
+		}
+		duration := scale(uint64(binary.BigEndian.Uint32(entry[:4])), from, to)
+		binary.BigEndian.PutUint32(entry[:4], uint32(min(duration, 0xFFFFFFFF)))
+	}
+	return nil
+}
+
+// ChunkOffsets returns the entries of a stco or co64 box.
+func ChunkOffsets(box *Box) ([]uint64, error) {
+	if err := need(box, "stco", 8); err != nil {
+		return nil, err
+	}
+	p := box.Payload
+	count := int(binary.BigEndian.Uint32(p[4:8]))
+	entrySize := 4
+	if box.Type == "co64" {
+		entrySize = 8
+	}
+	if (len(p)-8)/entrySize < count {
+		return nil, fmt.Errorf("%w: %s with %d entries in %d bytes", ErrInvalidBox, box.Type, count, len(p))
+	}
+
+	offsets := make([]uint64, count)
+	for i := range offsets {
+		if entrySize == 8 {
+			offsets[i] = binary.BigEndian.Uint64(p[8+i*8:])
+		} else {
+			offsets[i] = uint64(binary.BigEndian.Uint32(p[8+i*4:]))
+		}
+	}
+	return offsets, nil
+}
+
+// NewChunkOffsetBox builds a co64 box with the given entries.
+func NewChunkOffsetBox(offsets []uint64) *Box {
+	p := make([]byte, 8, 8+len(offsets)*8)
+	binary.BigEndian.PutUint32(p[4:8], uint32(len(offsets)))
+	for _, offset := range offsets {
+		p = binary.BigEndian.AppendUint64(p, offset)
+	}
+	return &Box{Type: "co64", Payload: p}
+}
+
+// readTime reads a time field of 64 bits when long, of 32 otherwise.
+func readTime(p []byte, long bool) uint64 {
+	if long {
+		return binary.BigEndian.Uint64(p[:8])
+	}
+	return uint64(binary.BigEndian.Uint32(p[:4]))
+}
+
+// putTime writes a time field of 64 bits when long, of 32 otherwise,
+// saturating values that do not fit.
+func putTime(p []byte, long bool, t uint64) {
+	if long {
+		binary.BigEndian.PutUint64(p[:8], t)
+		return
+	}
+	binary.BigEndian.PutUint32(p[:4], uint32(min(t, 0xFFFFFFFF)))
+}
+
+func scale(value uint64, from, to uint32) uint64 {
+	if from == 0 || from == to {
+		return value
+	}
+	return value * uint64(to) / uint64(from)
+}
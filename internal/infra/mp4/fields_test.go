@@ -0,0 +1,113 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestFieldsRejectShortBoxes(t *testing.T) {
+	cases := []struct {
+		name string
+		call func(*Box) error
+	}{
+		{"MovieTimescale", func(b *Box) error { _, _, err := MovieTimescale(b); return err }},
+		{"SetMovieDuration", func(b *Box) error { return SetMovieDuration(b, 1) }},
+		{"SetNextTrackID", func(b *Box) error { return SetNextTrackID(b, 1) }},
+		{"TrackID", func(b *Box) error { _, err := TrackID(b); return err }},
+		{"SetTrackID", func(b *Box) error { return SetTrackID(b, 1) }},
+		{"TrackDuration", func(b *Box) error { _, err := TrackDuration(b); return err }},
+		{"SetTrackDuration", func(b *Box) error { return SetTrackDuration(b, 1) }},
+		{"MediaTimescale", func(b *Box) error { _, err := MediaTimescale(b); return err }},
+		{"SetTrexTrackID", func(b *Box) error { return SetTrexTrackID(b, 1) }},
+		{"FragmentTrackID", func(b *Box) error { _, err := FragmentTrackID(b); return err }},
+		{"SetFragmentTrackID", func(b *Box) error { return SetFragmentTrackID(b, 1) }},
+		{"ShiftBaseDataOffset", func(b *Box) error { return ShiftBaseDataOffset(b, 1) }},
+		{"SetSequenceNumber", func(b *Box) error { return SetSequenceNumber(b, 1) }},
+		{"BaseMediaDecodeTime", func(b *Box) error { _, err := BaseMediaDecodeTime(b); return err }},
+		{"SetBaseMediaDecodeTime", func(b *Box) error { return SetBaseMediaDecodeTime(b, 1) }},
+		{"FragmentDuration", func(b *Box) error { _, err := FragmentDuration(b); return err }},
+		{"SetFragmentDuration", func(b *Box) error { return SetFragmentDuration(b, 1) }},
+		{"ScaleEditList", func(b *Box) error { return ScaleEditList(b, 1, 2) }},
+		{"ChunkOffsets", func(b *Box) error { _, err := ChunkOffsets(b); return err }},
+	}
+
+	boxes := map[string]*Box{
+		"nil":                 nil,
+		"empty":               {Type: "free"},
+		"version and flags":   {Type: "free", Payload: []byte{0, 0, 0, 1}},
+		"version 1 truncated": {Type: "free", Payload: []byte{1, 0, 0, 1, 0, 0, 0}},
+	}
+
+	for _, c := range cases {
+		for name, box := range boxes {
+			t.Run(c.name+"/"+name, func(t *testing.T) {
+				if box != nil {
+					box = &Box{Type: box.Type, Payload: append([]byte(nil), box.Payload...)}
+				}
+				if err := c.call(box); !errors.Is(err, ErrInvalidBox) {
+					t.Fatalf("got %v, want ErrInvalidBox", err)
+				}
+			})
+		}
+	}
+}
+
+func TestFieldsReadBothVersions(t *testing.T) {
+	cases := []struct {
+		name    string
+		payload []byte
+		want    uint64
+	}{
+		{"version 0", append([]byte{0, 0, 0, 0}, 0, 0, 0x30, 0x39), 12345},
+		{"version 1", append([]byte{1, 0, 0, 0}, 0, 0, 0, 1, 0, 0, 0, 0), 1 << 32},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tfdt := &Box{Type: "tfdt", Payload: c.payload}
+			got, err := BaseMediaDecodeTime(tfdt)
+			if err != nil || got != c.want {
+				t.Fatalf("BaseMediaDecodeTime = %d, %v, want %d", got, err, c.want)
+			}
+			if err := SetBaseMediaDecodeTime(tfdt, c.want+1); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := BaseMediaDecodeTime(tfdt); got != c.want+1 {
+				t.Fatalf("after set got %d, want %d", got, c.want+1)
+			}
+		})
+	}
+}
+
+func TestChunkOffsetsChecksEntryCount(t *testing.T) {
+	cases := []struct {
+		name    string
+		typ     string
+		count   uint32
+		entries int
+		valid   bool
+	}{
+		{"stco complete", "stco", 2, 2, true},
+		{"stco truncated", "stco", 3, 2, false},
+		{"co64 complete", "co64", 1, 1, true},
+		{"co64 truncated", "co64", 2, 1, false},
+		{"huge count", "stco", 0xFFFFFFFF, 0, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			size := 4
+			if c.typ == "co64" {
+				size = 8
+			}
+			p := binary.BigEndian.AppendUint32(make([]byte, 4), c.count)
+			p = append(p, make([]byte, c.entries*size)...)
+			offsets, err := ChunkOffsets(&Box{Type: c.typ, Payload: p})
+			if c.valid && (err != nil || len(offsets) != int(c.count)) {
+				t.Fatalf("got %d offsets, %v, want %d", len(offsets), err, c.count)
+			}
+			if !c.valid && !errors.Is(err, ErrInvalidBox) {
+				t.Fatalf("got %v, want ErrInvalidBox", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,447 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var ErrUnsupportedLayout = errors.New("unsupported mp4 layout")
+
+const (
+	videoTrackID = 1
+	audioTrackID = 2
+)
+
+// source is an input file with a single track, either fragmented
+// (moov + moof/mdat pairs, as served by YouTube adaptive formats) or
+// progressive (moov + mdat).
+type source struct {
+	file      *os.File
+	ftyp      *Box
+	moov      *Box
+	mdats     []Header
+	fragments []fragment
+	timescale uint32
+}
+
+type fragment struct {
+	header Header
+	moof   *Box
+	data   []Header
+	time   float64
+}
+
+// Merge remuxes the video track of videoPath and the audio track of
+// audioPath into a single MP4 file at outputPath, without re-encoding.
+func Merge(videoPath, audioPath, outputPath string) error {
+	video, err := openSource(videoPath)
+	if err != nil {
+		return err
+	}
+	defer video.file.Close()
+
+	audio, err := openSource(audioPath)
+	if err != nil {
+		return err
+	}
+	defer audio.file.Close()
+
+	fragmented := video.moov.Child("mvex") != nil
+	if fragmented != (audio.moov.Child("mvex") != nil) {
+		return fmt.Errorf("%w: cannot mix fragmented and progressive inputs", ErrUnsupportedLayout)
+	}
+
+	moov, err := mergeMoov(video.moov, audio.moov)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer out.Close()
+
+	if fragmented {
+		err = writeFragmented(out, video, audio, moov)
+	} else {
+		err = writeProgressive(out, video, audio, moov)
+	}
+	if err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+func openSource(path string) (*source, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", path, err)
+	}
+
+	src, err := readSource(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return src, nil
+}
+
+func readSource(file *os.File) (*source, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	headers, err := ScanBoxes(file, 0, stat.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	src := &source{file: file}
+	for _, h := range headers {
+		switch h.Type {
+		case "ftyp":
+			src.ftyp, err = ReadBox(file, h)
+		case "moov":
+			src.moov, err = ReadBox(file, h)
+		case "moof":
+			var moof *Box
+			if moof, err = ReadBox(file, h); err == nil {
+				src.fragments = append(src.fragments, fragment{header: h, moof: moof})
+			}
+		default:
+			src.addData(h)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if src.ftyp == nil || src.moov == nil {
+		return nil, fmt.Errorf("%w: missing ftyp or moov", ErrUnsupportedLayout)
+	}
+	if len(src.moov.ChildrenOf("trak")) != 1 {
+		return nil, fmt.Errorf("%w: expected exactly one track", ErrUnsupportedLayout)
+	}
+
+	mdhd := src.moov.Find("trak", "mdia", "mdhd")
+	if mdhd == nil {
+		return nil, fmt.Errorf("%w: missing mdhd", ErrUnsupportedLayout)
+	}
+	if src.timescale, err = MediaTimescale(mdhd); err != nil {
+		return nil, err
+	}
+
+	for i := range src.fragments {
+		if src.fragments[i].time, err = src.fragmentTime(src.fragments[i].moof); err != nil {
+			return nil, err
+		}
+	}
+	return src, nil
+}
+
+// addData keeps the mdat boxes of the file. Inside a fragment every box up
+// to its last mdat is kept, so offsets relative to the moof stay valid.
+func (s *source) addData(h Header) {
+	if len(s.fragments) == 0 {
+		if h.Type == "mdat" {
+			s.mdats = append(s.mdats, h)
+		}
+		return
+	}
+
+	frag := &s.fragments[len(s.fragments)-1]
+	if h.Type == "mdat" {
+		frag.data = append(frag.data, h)
+		return
+	}
+	if len(frag.data) == 0 && h.Type != "sidx" && h.Type != "styp" && h.Type != "mfra" {
+		frag.data = append(frag.data, h)
+	}
+}
+
+func (s *source) fragmentTime(moof *Box) (float64, error) {
+	tfdt := moof.Find("traf", "tfdt")
+	if tfdt == nil || s.timescale == 0 {
+		return 0, nil
+	}
+	t, err := BaseMediaDecodeTime(tfdt)
+	if err != nil {
+		return 0, err
+	}
+	return float64(t) / float64(s.timescale), nil
+}
+
+func mergeMoov(videoMoov, audioMoov *Box) (*Box, error) {
+	moov := videoMoov.Clone()
+	mvhd := moov.Child("mvhd")
+	movieTimescale, movieDuration, err := MovieTimescale(mvhd)
+	if err != nil {
+		return nil, err
+	}
+	audioTimescale, _, err := MovieTimescale(audioMoov.Child("mvhd"))
+	if err != nil {
+		return nil, err
+	}
+
+	videoTrak, audioTrak := moov.Child("trak"), audioMoov.Child("trak")
+	if videoTrak == nil || audioTrak == nil {
+		return nil, fmt.Errorf("%w: missing trak", ErrUnsupportedLayout)
+	}
+	if err := SetTrackID(videoTrak.Child("tkhd"), videoTrackID); err != nil {
+		return nil, err
+	}
+
+	audioTrak = audioTrak.Clone()
+	audioTkhd := audioTrak.Child("tkhd")
+	if err := SetTrackID(audioTkhd, audioTrackID); err != nil {
+		return nil, err
+	}
+	audioTrackDuration, err := TrackDuration(audioTkhd)
+	if err != nil {
+		return nil, err
+	}
+	audioDuration := scale(audioTrackDuration, audioTimescale, movieTimescale)
+	if err := SetTrackDuration(audioTkhd, audioDuration); err != nil {
+		return nil, err
+	}
+	if elst := audioTrak.Find("edts", "elst"); elst != nil {
+		if err := ScaleEditList(elst, audioTimescale, movieTimescale); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := SetMovieDuration(mvhd, max(movieDuration, audioDuration)); err != nil {
+		return nil, err
+	}
+	if err := SetNextTrackID(mvhd, audioTrackID+1); err != nil {
+		return nil, err
+	}
+
+	children := make([]*Box, 0, len(moov.Children)+1)
+	for _, child := range moov.Children {
+		children = append(children, child)
+		if child == videoTrak {
+			children = append(children, audioTrak)
+		}
+	}
+	moov.Children = children
+
+	if mvex := moov.Child("mvex"); mvex != nil {
+		if err := mergeMvex(mvex, audioMoov, audioTimescale, movieTimescale); err != nil {
+			return nil, err
+		}
+	}
+
+	return moov, nil
+}
+
+// mergeMvex adds the trex of the audio track to mvex, the fragment defaults
+// of the video one, and extends its mehd to the longer of both.
+func mergeMvex(mvex, audioMoov *Box, audioTimescale, movieTimescale uint32) error {
+	if err := SetTrexTrackID(mvex.Child("trex"), videoTrackID); err != nil {
+		return err
+	}
+
+	audioTrex := audioMoov.Find("mvex", "trex")
+	if audioTrex == nil {
+		return fmt.Errorf("%w: missing trex", ErrUnsupportedLayout)
+	}
+	audioTrex = audioTrex.Clone()
+	if err := SetTrexTrackID(audioTrex, audioTrackID); err != nil {
+		return err
+	}
+	mvex.Children = append(mvex.Children, audioTrex)
+
+	mehd, audioMehd := mvex.Child("mehd"), audioMoov.Find("mvex", "mehd")
+	if mehd == nil || audioMehd == nil {
+		return nil
+	}
+	videoFragmentDuration, err := FragmentDuration(mehd)
+	if err != nil {
+		return err
+	}
+	audioFragmentDuration, err := FragmentDuration(audioMehd)
+	if err != nil {
+		return err
+	}
+	audioFragmentDuration = scale(audioFragmentDuration, audioTimescale, movieTimescale)
+	return SetFragmentDuration(mehd, max(videoFragmentDuration, audioFragmentDuration))
+}
+
+// writeFragmented writes both fragment sequences interleaved by decode
+// time, renumbering tracks and sequence numbers on the way.
+func writeFragmented(out io.Writer, video, audio *source, moov *Box) error {
+	w := &countingWriter{w: out}
+	if _, err := video.ftyp.WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := moov.WriteTo(w); err != nil {
+		return err
+	}
+
+	seq := uint32(1)
+	i, j := 0, 0
+	for i < len(video.fragments) || j < len(audio.fragments) {
+		var err error
+		if j >= len(audio.fragments) || (i < len(video.fragments) && video.fragments[i].time <= audio.fragments[j].time) {
+			err = writeFragment(w, video, video.fragments[i], videoTrackID, seq)
+			i++
+		} else {
+			err = writeFragment(w, audio, audio.fragments[j], audioTrackID, seq)
+			j++
+		}
+		if err != nil {
+			return err
+		}
+		seq++
+	}
+
+	return nil
+}
+
+func writeFragment(w *countingWriter, src *source, frag fragment, trackID, seq uint32) error {
+	moof := frag.moof
+	if err := SetSequenceNumber(moof.Child("mfhd"), seq); err != nil {
+		return err
+	}
+	for _, traf := range moof.ChildrenOf("traf") {
+		tfhd := traf.Child("tfhd")
+		if err := SetFragmentTrackID(tfhd, trackID); err != nil {
+			return err
+		}
+		if err := ShiftBaseDataOffset(tfhd, w.n-frag.header.Offset); err != nil {
+			return err
+		}
+	}
+
+	if moof.Size() != frag.header.Size {
+		return fmt.Errorf("%w: moof at %d changed size", ErrUnsupportedLayout, frag.header.Offset)
+	}
+	if _, err := moof.WriteTo(w); err != nil {
+		return err
+	}
+
+	for _, h := range frag.data {
+		if _, err := io.Copy(w, io.NewSectionReader(src.file, h.Offset, h.Size)); err != nil {
+			return fmt.Errorf("error copying fragment data: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeProgressive writes a single mdat with the video samples followed by
+// the audio samples and points the chunk offset tables at the new place.
+func writeProgressive(out io.Writer, video, audio *source, moov *Box) error {
+	traks := moov.ChildrenOf("trak")
+	sources := []*source{video, audio}
+
+	var payloadSize int64
+	for _, src := range sources {
+		for _, h := range src.mdats {
+			payloadSize += h.Size - h.HeaderSize
+		}
+	}
+
+	offsetTables := make([]*Box, len(traks))
+	for i, trak := range traks {
+		stbl := trak.Find("mdia", "minf", "stbl")
+		if stbl == nil {
+			return fmt.Errorf("%w: missing stbl", ErrUnsupportedLayout)
+		}
+
+		old := stbl.Child("stco")
+		if old == nil {
+			old = stbl.Child("co64")
+		}
+		if old == nil {
+			return fmt.Errorf("%w: missing chunk offsets", ErrUnsupportedLayout)
+		}
+
+		offsets, err := ChunkOffsets(old)
+		if err != nil {
+			return err
+		}
+		offsetTables[i] = NewChunkOffsetBox(offsets)
+		for k, child := range stbl.Children {
+			if child == old {
+				stbl.Children[k] = offsetTables[i]
+			}
+		}
+	}
+
+	mdatHeaderSize := int64(8)
+	if payloadSize+8 > 0xFFFFFFFF {
+		mdatHeaderSize = 16
+	}
+	dataStart := video.ftyp.Size() + moov.Size() + mdatHeaderSize
+
+	next := dataStart
+	for i, src := range sources {
+		original, err := ChunkOffsets(offsetTables[i])
+		if err != nil {
+			return err
+		}
+		offsets := make([]uint64, len(original))
+		for _, h := range src.mdats {
+			start, end := h.Offset+h.HeaderSize, h.End()
+			for k, offset := range original {
+				if int64(offset) >= start && int64(offset) < end {
+					offsets[k] = uint64(next + int64(offset) - start)
+				}
+			}
+			next += end - start
+		}
+		*offsetTables[i] = *NewChunkOffsetBox(offsets)
+	}
+
+	if _, err := video.ftyp.WriteTo(out); err != nil {
+		return err
+	}
+	if _, err := moov.WriteTo(out); err != nil {
+		return err
+	}
+	if err := writeMdatHeader(out, payloadSize, mdatHeaderSize); err != nil {
+		return err
+	}
+
+	for _, src := range sources {
+		for _, h := range src.mdats {
+			payload := io.NewSectionReader(src.file, h.Offset+h.HeaderSize, h.Size-h.HeaderSize)
+			if _, err := io.Copy(out, payload); err != nil {
+				return fmt.Errorf("error copying media data: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func writeMdatHeader(w io.Writer, payloadSize, headerSize int64) error {
+	buf := make([]byte, 0, headerSize)
+	if headerSize == 16 {
+		buf = binary.BigEndian.AppendUint32(buf, 1)
+		buf = append(buf, "mdat"...)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(payloadSize+16))
+	} else {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(payloadSize+8))
+		buf = append(buf, "mdat"...)
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
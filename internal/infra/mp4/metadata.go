@@ -0,0 +1,356 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var ErrUnsupportedImage = errors.New("unsupported cover image")
+
+// Well-known types of the data boxes of iTunes metadata items.
+const (
+	dataTypeUTF8 = 1
+	dataTypeJPEG = 13
+	dataTypePNG  = 14
+)
+
+// freeformMean is the namespace of the freeform items written by SetTags.
+const freeformMean = "com.apple.iTunes"
+
+// metadataHandler is the hdlr payload of an iTunes metadata box.
+var metadataHandler = []byte{
+	0, 0, 0, 0, // version and flags
+	0, 0, 0, 0, // pre_defined
+	'm', 'd', 'i', 'r',
+	'a', 'p', 'p', 'l',
+	0, 0, 0, 0, 0, 0, 0, 0,
+	0, // empty name
+}
+
+// Tags are the iTunes metadata items written by SetTags. Empty fields are
+// left as they are in the file.
+type Tags struct {
+	Title       string
+	Artist      string
+	Date        string
+	Description string
+	URL         string
+	VideoID     string
+	// Cover is a JPEG or PNG image of type CoverType.
+	Cover     []byte
+	CoverType string
+}
+
+// SetTags writes tags into the moov/udta/meta/ilst box of the MP4 file at
+// path, where players and media libraries read them.
+func SetTags(path string, tags Tags) error {
+	var coverType uint32
+	if tags.Cover != nil {
+		switch tags.CoverType {
+		case "image/jpeg":
+			coverType = dataTypeJPEG
+		case "image/png":
+			coverType = dataTypePNG
+		default:
+			return fmt.Errorf("%w: %s", ErrUnsupportedImage, tags.CoverType)
+		}
+	}
+
+	return updateMetadata(path, func(ilst *Box) {
+		for _, item := range []struct{ typ, value string }{
+			{"\xa9nam", tags.Title},
+			{"\xa9ART", tags.Artist},
+			{"\xa9day", tags.Date},
+			{"desc", tags.Description},
+		} {
+			if item.value != "" {
+				setItem(ilst, item.typ, dataTypeUTF8, []byte(item.value))
+			}
+		}
+		if tags.URL != "" {
+			setFreeformItem(ilst, "URL", tags.URL)
+		}
+		if tags.VideoID != "" {
+			setFreeformItem(ilst, "VIDEO_ID", tags.VideoID)
+		}
+		if tags.Cover != nil {
+			setItem(ilst, "covr", coverType, tags.Cover)
+		}
+	})
+}
+
+// setItem replaces the item of the given type with a single data box.
+func setItem(ilst *Box, typ string, dataType uint32, value []byte) {
+	item := &Box{Type: typ, Children: []*Box{dataBox(dataType, value)}}
+	replaceItem(ilst, item, func(child *Box) bool { return child.Type == typ })
+}
+
+// setFreeformItem replaces the "----" item with the given name, which
+// holds values that have no item type of their own.
+func setFreeformItem(ilst *Box, name, value string) {
+	item := &Box{Type: "----", Children: []*Box{
+		{Type: "mean", Payload: append(make([]byte, 4), freeformMean...)},
+		{Type: "name", Payload: append(make([]byte, 4), name...)},
+		dataBox(dataTypeUTF8, []byte(value)),
+	}}
+	replaceItem(ilst, item, func(child *Box) bool {
+		return child.Type == "----" && freeformName(child) == name
+	})
+}
+
+func freeformName(item *Box) string {
+	children, err := parseBoxes(item.Payload)
+	if err != nil {
+		return ""
+	}
+	for _, child := range children {
+		if child.Type == "name" && len(child.Payload) >= 4 {
+			return string(child.Payload[4:])
+		}
+	}
+	return ""
+}
+
+func dataBox(dataType uint32, value []byte) *Box {
+	payload := binary.BigEndian.AppendUint32(nil, dataType)
+	payload = binary.BigEndian.AppendUint32(payload, 0) // locale
+	payload = append(payload, value...)
+	return &Box{Type: "data", Payload: payload}
+}
+
+// replaceItem puts item in place of the first child that matches, dropping
+// the others, or appends it when none does.
+func replaceItem(ilst *Box, item *Box, matches func(*Box) bool) {
+	replaced := false
+	items := ilst.Children[:0]
+	for _, child := range ilst.Children {
+		switch {
+		case !matches(child):
+			items = append(items, child)
+		case !replaced:
+			items = append(items, item)
+			replaced = true
+		}
+	}
+	if !replaced {
+		items = append(items, item)
+	}
+	ilst.Children = items
+}
+
+// updateMetadata lets update change the iTunes metadata list of the file at
+// path, creating the boxes that hold it when missing, and rewrites the file.
+func updateMetadata(path string, update func(ilst *Box)) error {
+	return rewriteMoov(path, func(moov *Box) error {
+		udta := moov.Child("udta")
+		if udta == nil {
+			udta = &Box{Type: "udta", Children: []*Box{}}
+			moov.Children = append(moov.Children, udta)
+		}
+
+		var children []*Box
+		meta := udta.Child("meta")
+		if meta != nil {
+			var err error
+			if children, err = metaChildren(meta); err != nil {
+				return err
+			}
+		} else {
+			meta = &Box{Type: "meta"}
+			udta.Children = append(udta.Children, meta)
+		}
+
+		ilst := &Box{Type: "ilst", Children: []*Box{}}
+		hasHandler := false
+		for _, child := range children {
+			switch child.Type {
+			case "hdlr":
+				hasHandler = true
+			case "ilst":
+				items, err := parseBoxes(child.Payload)
+				if err != nil {
+					return err
+				}
+				ilst.Children = append(ilst.Children, items...)
+			}
+		}
+		update(ilst)
+
+		// meta is a full box: its children follow the version and flags.
+		payload := make([]byte, 4)
+		if !hasHandler {
+			payload = (&Box{Type: "hdlr", Payload: metadataHandler}).appendTo(payload)
+		}
+		for _, child := range children {
+			if child.Type != "ilst" {
+				payload = child.appendTo(payload)
+			}
+		}
+		meta.Payload = ilst.appendTo(payload)
+		return nil
+	})
+}
+
+// metaChildren parses the boxes of a meta box, which QuickTime files store
+// without the version and flags of a full box.
+func metaChildren(meta *Box) ([]*Box, error) {
+	if len(meta.Payload) >= 8 && string(meta.Payload[4:8]) == "hdlr" {
+		return parseBoxes(meta.Payload)
+	}
+	if len(meta.Payload) < 4 {
+		return nil, fmt.Errorf("%w: truncated meta", ErrInvalidBox)
+	}
+	return parseBoxes(meta.Payload[4:])
+}
+
+// rewriteMoov lets update change the moov box of the file at path and
+// writes the file again through a temporary one. When the media data
+// follows the moov, its offsets are moved by the change in size.
+func rewriteMoov(path string, update func(moov *Box) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", path, err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	headers, err := ScanBoxes(file, 0, stat.Size())
+	if err != nil {
+		return err
+	}
+
+	moovIndex := -1
+	for i, h := range headers {
+		if h.Type == "moov" {
+			moovIndex = i
+			break
+		}
+	}
+	if moovIndex < 0 {
+		return fmt.Errorf("%w: missing moov", ErrUnsupportedLayout)
+	}
+
+	moov, err := ReadBox(file, headers[moovIndex])
+	if err != nil {
+		return err
+	}
+	if err := update(moov); err != nil {
+		return err
+	}
+
+	dataAfter := false
+	for _, h := range headers[moovIndex+1:] {
+		if h.Type == "mdat" || h.Type == "moof" {
+			dataAfter = true
+		}
+	}
+	if dataAfter {
+		if err := useLargeOffsets(moov); err != nil {
+			return err
+		}
+	}
+	delta := moov.Size() - headers[moovIndex].Size
+	if dataAfter && delta != 0 {
+		if err := shiftChunkOffsets(moov, delta); err != nil {
+			return err
+		}
+	}
+
+	tmp := path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	if err := writeWithMoov(out, file, headers, moovIndex, moov, delta); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error moving %s: %w", tmp, err)
+	}
+	return nil
+}
+
+func writeWithMoov(out io.Writer, file io.ReaderAt, headers []Header, moovIndex int, moov *Box, delta int64) error {
+	for i, h := range headers {
+		switch {
+		case i == moovIndex:
+			if _, err := moov.WriteTo(out); err != nil {
+				return err
+			}
+		case i > moovIndex && delta != 0 && h.Type == "moof":
+			moof, err := ReadBox(file, h)
+			if err != nil {
+				return err
+			}
+			for _, traf := range moof.ChildrenOf("traf") {
+				if err := ShiftBaseDataOffset(traf.Child("tfhd"), delta); err != nil {
+					return err
+				}
+			}
+			if _, err := moof.WriteTo(out); err != nil {
+				return err
+			}
+		case i > moovIndex && delta != 0 && h.Type == "mfra":
+			// The random access index points at the old fragment offsets
+			// and is optional, so it is dropped.
+		default:
+			if _, err := io.Copy(out, io.NewSectionReader(file, h.Offset, h.Size)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// useLargeOffsets replaces every stco box with a co64 one, so the offsets
+// can grow without overflowing.
+func useLargeOffsets(moov *Box) error {
+	for _, trak := range moov.ChildrenOf("trak") {
+		stbl := trak.Find("mdia", "minf", "stbl")
+		if stbl == nil {
+			continue
+		}
+		for i, child := range stbl.Children {
+			if child.Type != "stco" {
+				continue
+			}
+			offsets, err := ChunkOffsets(child)
+			if err != nil {
+				return err
+			}
+			stbl.Children[i] = NewChunkOffsetBox(offsets)
+		}
+	}
+	return nil
+}
+
+func shiftChunkOffsets(moov *Box, delta int64) error {
+	for _, trak := range moov.ChildrenOf("trak") {
+		co64 := trak.Find("mdia", "minf", "stbl", "co64")
+		if co64 == nil {
+			continue
+		}
+		offsets, err := ChunkOffsets(co64)
+		if err != nil {
+			return err
+		}
+		for i := range offsets {
+			offsets[i] = uint64(int64(offsets[i]) + delta)
+		}
+		*co64 = *NewChunkOffsetBox(offsets)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// taggableFile builds a progressive file whose single chunk, at the start
+// of the mdat that follows the moov, holds sample.
+func taggableFile(t *testing.T, sample []byte) string {
+	t.Helper()
+	ftyp := (&Box{Type: "ftyp", Payload: []byte("M4A \x00\x00\x00\x00")}).Bytes()
+	moov := &Box{Type: "moov", Children: []*Box{
+		{Type: "mvhd", Payload: make([]byte, 100)},
+		{Type: "trak", Children: []*Box{
+			{Type: "mdia", Children: []*Box{
+				{Type: "minf", Children: []*Box{
+					{Type: "stbl", Children: []*Box{{Type: "stco", Payload: fullBox(1, 0)}}},
+				}},
+			}},
+		}},
+	}}
+	offset := len(ftyp) + int(moov.Size()) + 8
+	binary.BigEndian.PutUint32(moov.Find("trak", "mdia", "minf", "stbl", "stco").Payload[8:], uint32(offset))
+
+	data := append(ftyp, moov.Bytes()...)
+	data = append(data, (&Box{Type: "mdat", Payload: sample}).Bytes()...)
+	path := filepath.Join(t.TempDir(), "audio.m4a")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readTags returns the values of the items of the file at path, keyed by
+// item type or, for freeform items, by name, along with the file.
+func readTags(t *testing.T, path string) (map[string][]string, []byte) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	boxes, err := parseBoxes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags := map[string][]string{}
+	for _, box := range boxes {
+		meta := box.Find("udta", "meta")
+		if box.Type != "moov" || meta == nil {
+			continue
+		}
+		children, err := metaChildren(meta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, child := range children {
+			if child.Type != "ilst" {
+				continue
+			}
+			items, err := parseBoxes(child.Payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, item := range items {
+				key := item.Type
+				if key == "----" {
+					key = freeformName(item)
+				}
+				parts, err := parseBoxes(item.Payload)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, part := range parts {
+					if part.Type == "data" {
+						tags[key] = append(tags[key], string(part.Payload[8:]))
+					}
+				}
+			}
+		}
+	}
+	return tags, data
+}
+
+func TestSetTags(t *testing.T) {
+	sample := []byte("sample data")
+	path := taggableFile(t, sample)
+
+	if err := SetTags(path, Tags{Title: "Primeiro", Artist: "Canal", URL: "https://example.com/a"}); err != nil {
+		t.Fatal(err)
+	}
+	// A second write replaces the items it sets and keeps the others.
+	cover := []byte("\xff\xd8\xff")
+	if err := SetTags(path, Tags{Title: "Segundo", Date: "2026-05-10", URL: "https://example.com/b", VideoID: "abc", Cover: cover, CoverType: "image/jpeg"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tags, data := readTags(t, path)
+	want := map[string]string{
+		"\xa9nam":  "Segundo",
+		"\xa9ART":  "Canal",
+		"\xa9day":  "2026-05-10",
+		"URL":      "https://example.com/b",
+		"VIDEO_ID": "abc",
+		"covr":     string(cover),
+	}
+	for key, value := range want {
+		if len(tags[key]) != 1 || tags[key][0] != value {
+			t.Errorf("item %q = %q, want %q", key, tags[key], value)
+		}
+	}
+	if _, ok := tags["desc"]; ok {
+		t.Errorf("empty description written: %q", tags["desc"])
+	}
+
+	// The chunk offset follows the mdat moved by the bigger moov.
+	boxes, err := parseBoxes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var co64 *Box
+	for _, box := range boxes {
+		if box.Type == "moov" {
+			co64 = box.Find("trak", "mdia", "minf", "stbl", "co64")
+		}
+	}
+	offsets, err := ChunkOffsets(co64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offsets) != 1 || int(offsets[0]) > len(data) || !bytes.HasPrefix(data[offsets[0]:], sample) {
+		t.Fatalf("chunk offsets %v do not point at the sample", offsets)
+	}
+}
+
+func TestSetTagsRefusesUnknownCover(t *testing.T) {
+	path := taggableFile(t, []byte("x"))
+	err := SetTags(path, Tags{Cover: []byte("GIF89a"), CoverType: "image/gif"})
+	if !errors.Is(err, ErrUnsupportedImage) {
+		t.Fatalf("SetTags with a GIF cover = %v, want ErrUnsupportedImage", err)
+	}
+}
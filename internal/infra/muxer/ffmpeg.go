@@ -0,0 +1,55 @@
+package muxer
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// FFmpegMuxer delegates the remux to an external ffmpeg binary, which
+// accepts any pair of containers the native muxer cannot handle.
+type FFmpegMuxer struct {
+	path string
+}
+
+func NewFFmpegMuxer(path string) *FFmpegMuxer {
+	return &FFmpegMuxer{path: path}
+}
+
+func (m *FFmpegMuxer) Merge(videoPath string, audioPath string, outputPath string) error {
+	return m.run(
+		"-i", videoPath,
+		"-i", audioPath,
+		"-map", "0:v:0", "-map", "1:a:0",
+		"-c", "copy",
+		outputPath,
+	)
+}
+
+// Cut seeks the input to start, which ffmpeg moves back to the keyframe
+// before it when copying the streams.
+func (m *FFmpegMuxer) Cut(inputPath string, outputPath string, start, end time.Duration) error {
+	args := []string{"-ss", seconds(start), "-i", inputPath}
+	if end > 0 {
+		args = append(args, "-t", seconds(end-start))
+	}
+	return m.run(append(args, "-map", "0", "-c", "copy", outputPath)...)
+}
+
+func (m *FFmpegMuxer) run(args ...string) error {
+	cmd := exec.Command(m.path, append([]string{"-y", "-loglevel", "error"}, args...)...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error running ffmpeg: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return nil
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
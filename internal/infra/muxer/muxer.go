@@ -0,0 +1,12 @@
+package muxer
+
+import "downloader/internal/domain"
+
+// NewMuxer returns the external ffmpeg backend when a binary is configured
+// and the pure Go one otherwise.
+func NewMuxer(ffmpegPath string) domain.Muxer {
+	if ffmpegPath != "" {
+		return NewFFmpegMuxer(ffmpegPath)
+	}
+	return NewNativeMuxer()
+}
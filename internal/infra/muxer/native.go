@@ -0,0 +1,44 @@
+package muxer
+
+import (
+	"downloader/internal/infra/mkv"
+	"downloader/internal/infra/mp4"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// NativeMuxer remuxes the streams in pure Go. MP4 outputs take MP4/M4A
+// inputs and MKV/WebM outputs take WebM inputs. Only fragmented MP4 files
+// can be cut.
+type NativeMuxer struct {
+}
+
+func NewNativeMuxer() *NativeMuxer {
+	return &NativeMuxer{}
+}
+
+func (m *NativeMuxer) Merge(videoPath string, audioPath string, outputPath string) error {
+	switch ext := strings.ToLower(filepath.Ext(outputPath)); ext {
+	case ".mp4", ".m4v":
+		return mp4.Merge(videoPath, audioPath, outputPath)
+	case ".mkv":
+		return mkv.Merge(videoPath, audioPath, outputPath, "matroska")
+	case ".webm":
+		return mkv.Merge(videoPath, audioPath, outputPath, "webm")
+	default:
+		return fmt.Errorf("unsupported output container %q", ext)
+	}
+}
+
+func (m *NativeMuxer) Cut(inputPath string, outputPath string, start, end time.Duration) error {
+	switch ext := strings.ToLower(filepath.Ext(inputPath)); ext {
+	case ".mp4", ".m4v", ".m4a":
+		return mp4.Cut(inputPath, outputPath, start, end)
+	case ".mkv", ".webm":
+		return mkv.Cut(inputPath, outputPath, start, end)
+	default:
+		return fmt.Errorf("unsupported container %q", ext)
+	}
+}
@@ -16,8 +16,10 @@ type ServerNotifyer struct {
 }
 
 type VideoResponse struct {
-	URL string `json:"url"`
-	To  string `json:"to"`
+	URL     string `json:"url,omitempty"`
+	To      string `json:"to"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
 }
 
 func NewServerNotifyer(url string) *ServerNotifyer {
@@ -28,10 +30,15 @@ func (s *ServerNotifyer) Notify(notification domain.Notification) error {
 	log.Info(fmt.Sprintf("Sending notification: %s", notification.Title))
 	httpClient := &http.Client{}
 
-	obj, err := json.Marshal(VideoResponse{
-		URL: fmt.Sprintf("https://downloader.ajaxlima.dev.br/video/%s", notification.Message),
-		To:  notification.To,
-	})
+	response := VideoResponse{To: notification.To, Status: string(notification.Kind)}
+	if notification.Kind == domain.NotificationDone || notification.Kind == "" {
+		response.Status = string(domain.NotificationDone)
+		response.URL = fmt.Sprintf("https://downloader.ajaxlima.dev.br/video/%s", notification.Message)
+	} else {
+		response.Message = fmt.Sprintf("%s: %s", notification.Title, notification.Message)
+	}
+
+	obj, err := json.Marshal(response)
 	if err != nil {
 		msgError := fmt.Sprintf("Error creating json obj: %v", err)
 		log.Error(msgError)
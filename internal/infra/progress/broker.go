@@ -0,0 +1,138 @@
+package progress
+
+import "sync"
+
+const subscriberBuffer = 16
+
+const (
+	EventStart  = "start"
+	EventUpdate = "update"
+	EventFinish = "finish"
+	EventStage  = "stage"
+)
+
+type Event struct {
+	Type    string  `json:"type"`
+	State   string  `json:"state,omitempty"`
+	Bytes   int64   `json:"bytes"`
+	Total   int64   `json:"total"`
+	Percent float64 `json:"percent"`
+	Rate    float64 `json:"rate"`
+	Error   string  `json:"error,omitempty"`
+	// Segments and SegmentsTotal count the segments of segmented streams.
+	Segments      int `json:"segments,omitempty"`
+	SegmentsTotal int `json:"segments_total,omitempty"`
+}
+
+// Broker fans progress events of each job out to any number of
+// subscribers. The latest event of a job is kept, so subscribers joining
+// late start from the current state.
+type Broker struct {
+	mu     sync.Mutex
+	topics map[string]*topic
+}
+
+type topic struct {
+	latest      *Event
+	subscribers map[chan Event]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{topics: map[string]*topic{}}
+}
+
+// Open makes the events of a job that is about to run available to
+// subscribers, until the job is closed.
+func (b *Broker) Open(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.topic(id)
+}
+
+// Subscribe returns a channel with the events of the job, starting with
+// the latest one, and a function to stop receiving them. The channel is
+// closed when the job is closed. Jobs that were never opened, or already
+// closed, have no events and report false.
+func (b *Broker) Subscribe(id string) (<-chan Event, func(), bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[id]
+	if !ok {
+		return nil, nil, false
+	}
+	ch := make(chan Event, subscriberBuffer)
+	if t.latest != nil {
+		ch <- *t.latest
+	}
+	t.subscribers[ch] = struct{}{}
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := t.subscribers[ch]; ok {
+			delete(t.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe, true
+}
+
+// Publish sends the event to every subscriber of the job. Slow subscribers
+// lose their oldest pending event rather than blocking the download.
+func (b *Broker) Publish(id string, event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(id)
+	t.latest = &event
+	for ch := range t.subscribers {
+		send(ch, event)
+	}
+}
+
+// Close publishes the final event of the job, closes the channels of its
+// subscribers and forgets it. The final event keeps the byte counters of
+// the latest one when it carries none.
+func (b *Broker) Close(id string, event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[id]
+	if !ok {
+		return
+	}
+	if t.latest != nil && event.Total == 0 {
+		event.Bytes, event.Total, event.Percent = t.latest.Bytes, t.latest.Total, t.latest.Percent
+	}
+	for ch := range t.subscribers {
+		send(ch, event)
+		close(ch)
+		delete(t.subscribers, ch)
+	}
+	delete(b.topics, id)
+}
+
+func (b *Broker) topic(id string) *topic {
+	t, ok := b.topics[id]
+	if !ok {
+		t = &topic{subscribers: map[chan Event]struct{}{}}
+		b.topics[id] = t
+	}
+	return t
+}
+
+func send(ch chan Event, event Event) {
+	for {
+		select {
+		case ch <- event:
+			return
+		default:
+		}
+
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
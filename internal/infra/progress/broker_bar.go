@@ -0,0 +1,74 @@
+package progress
+
+import (
+	"downloader/internal/domain"
+	"sync"
+	"time"
+)
+
+// publishInterval limits how often update events reach the broker.
+const publishInterval = 250 * time.Millisecond
+
+// BrokerProgressBar publishes the progress of a job to a Broker.
+type BrokerProgressBar struct {
+	mu           sync.Mutex
+	broker       *Broker
+	id           string
+	total        int64
+	segments     int
+	segmentTotal int
+	started      time.Time
+	lastPublish  time.Time
+}
+
+func NewBrokerProgressBar(broker *Broker, id string) *BrokerProgressBar {
+	return &BrokerProgressBar{broker: broker, id: id}
+}
+
+func (bp *BrokerProgressBar) Start(total int64) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	bp.total = total
+	bp.started = time.Now()
+	bp.lastPublish = bp.started
+	bp.broker.Publish(bp.id, Event{Type: EventStart, Total: total})
+}
+
+func (bp *BrokerProgressBar) Update(current int64) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	now := time.Now()
+	if now.Sub(bp.lastPublish) < publishInterval {
+		return
+	}
+	bp.lastPublish = now
+	bp.broker.Publish(bp.id, bp.event(EventUpdate, current, now))
+}
+
+func (bp *BrokerProgressBar) Finish() {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	bp.broker.Publish(bp.id, bp.event(EventFinish, bp.total, time.Now()))
+}
+
+func (bp *BrokerProgressBar) Stage(state domain.JobState) {
+	bp.broker.Publish(bp.id, Event{Type: EventStage, State: string(state)})
+}
+
+// Segments is sent along with the next update.
+func (bp *BrokerProgressBar) Segments(done, total int) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	bp.segments, bp.segmentTotal = done, total
+}
+
+func (bp *BrokerProgressBar) event(typ string, current int64, now time.Time) Event {
+	event := Event{Type: typ, Bytes: current, Total: bp.total, Segments: bp.segments, SegmentsTotal: bp.segmentTotal}
+	if bp.total > 0 {
+		event.Percent = float64(current) * 100 / float64(bp.total)
+	}
+	if elapsed := now.Sub(bp.started).Seconds(); elapsed > 0 {
+		event.Rate = float64(current) / elapsed
+	}
+	return event
+}
@@ -0,0 +1,39 @@
+package progress
+
+import "testing"
+
+func TestBrokerTopics(t *testing.T) {
+	b := NewBroker()
+
+	if _, _, ok := b.Subscribe("unknown"); ok {
+		t.Fatal("subscribed to a job that was never opened")
+	}
+	if len(b.topics) != 0 {
+		t.Fatalf("Subscribe created %d topics", len(b.topics))
+	}
+
+	b.Open("job")
+	b.Publish("job", Event{Type: EventUpdate, Bytes: 10, Total: 100})
+	events, unsubscribe, ok := b.Subscribe("job")
+	if !ok {
+		t.Fatal("could not subscribe to an open job")
+	}
+	defer unsubscribe()
+	if e := <-events; e.Bytes != 10 {
+		t.Fatalf("first event = %+v, want the latest one", e)
+	}
+
+	b.Close("job", Event{Type: EventStage, State: "done"})
+	if e := <-events; e.State != "done" || e.Total != 100 {
+		t.Fatalf("final event = %+v, want done keeping the byte counters", e)
+	}
+	if _, ok := <-events; ok {
+		t.Fatal("channel still open after Close")
+	}
+	if len(b.topics) != 0 {
+		t.Fatalf("%d topics left after Close", len(b.topics))
+	}
+	if _, _, ok := b.Subscribe("job"); ok {
+		t.Fatal("subscribed to a closed job")
+	}
+}
@@ -0,0 +1,42 @@
+package progress
+
+import "downloader/internal/domain"
+
+// MultiProgressBar forwards every call to several progress bars.
+type MultiProgressBar struct {
+	bars []domain.ProgressBar
+}
+
+func NewMultiProgressBar(bars ...domain.ProgressBar) *MultiProgressBar {
+	return &MultiProgressBar{bars: bars}
+}
+
+func (mp *MultiProgressBar) Start(total int64) {
+	for _, bar := range mp.bars {
+		bar.Start(total)
+	}
+}
+
+func (mp *MultiProgressBar) Update(current int64) {
+	for _, bar := range mp.bars {
+		bar.Update(current)
+	}
+}
+
+func (mp *MultiProgressBar) Finish() {
+	for _, bar := range mp.bars {
+		bar.Finish()
+	}
+}
+
+func (mp *MultiProgressBar) Stage(state domain.JobState) {
+	for _, bar := range mp.bars {
+		domain.ReportStage(bar, state)
+	}
+}
+
+func (mp *MultiProgressBar) Segments(done, total int) {
+	for _, bar := range mp.bars {
+		domain.ReportSegments(bar, done, total)
+	}
+}
@@ -0,0 +1,496 @@
+package queue
+
+import (
+	"container/heap"
+	"context"
+	"downloader/internal/domain"
+	logger "downloader/pkg/log"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var log = logger.GetLogger("queue")
+
+var ErrQueueClosed = errors.New("queue is closed")
+
+var ErrJobNotFound = errors.New("job not found")
+
+var ErrJobFinished = errors.New("job already finished")
+
+var ErrJobElsewhere = errors.New("job held by another instance")
+
+// Handler runs a job taken from the queue, reporting to progress.
+type Handler func(ctx context.Context, job domain.Job, progress domain.ProgressBar) error
+
+// Queue hands persisted jobs to a bounded pool of workers, higher priority
+// first and in arrival order within the same priority.
+type Queue struct {
+	store     domain.JobStore
+	handler   Handler
+	workers   int
+	finished  func(job domain.Job)
+	queued    func(job domain.Job)
+	cancelled func(job domain.Job)
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending jobHeap
+	running map[string]*domain.Job
+	cancels map[string]context.CancelCauseFunc
+	seq     uint64
+	closed  bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewQueue(store domain.JobStore, workers int, handler Handler) *Queue {
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		store:   store,
+		handler: handler,
+		workers: workers,
+		running: map[string]*domain.Job{},
+		cancels: map[string]context.CancelCauseFunc{},
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// OnFinished registers fn to be called, outside of the queue lock, every
+// time a job reaches a final state. It must be called before Start.
+func (q *Queue) OnFinished(fn func(job domain.Job)) {
+	q.finished = fn
+}
+
+// OnQueued registers fn to be called every time a job joins this queue,
+// whether new or restored. It runs with the queue lock held, so it must not
+// call back into the queue. It must be called before Start.
+func (q *Queue) OnQueued(fn func(job domain.Job)) {
+	q.queued = fn
+}
+
+// OnCancelled registers fn to be called, outside of the queue lock and
+// before the finished callback, when a job is cancelled before it starts,
+// as no handler ran to report it. It must be called before Start.
+func (q *Queue) OnCancelled(fn func(job domain.Job)) {
+	q.cancelled = fn
+}
+
+// Start restores the unfinished jobs of the store and starts the workers.
+// Jobs that were running when the process stopped are queued again. With a
+// store shared between instances only the jobs this one claims are run,
+// and their leases are renewed until the queue stops.
+func (q *Queue) Start() error {
+	restored, err := q.restore()
+	if err != nil {
+		return fmt.Errorf("error loading jobs: %w", err)
+	}
+	if restored > 0 {
+		log.Info(fmt.Sprintf("%d jobs restaurados da fila", restored))
+	}
+
+	for i := 0; i < q.workers; i++ {
+		go q.work()
+	}
+	if claimer, ok := q.store.(domain.JobClaimer); ok {
+		go q.keepLeases(claimer)
+	}
+	return nil
+}
+
+// restore queues the unfinished jobs of the store that are neither queued
+// nor running here. The lock is held while reading the store, so a job
+// finishing meanwhile is not taken for unfinished.
+func (q *Queue) restore() (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return 0, nil
+	}
+
+	var jobs []domain.Job
+	var err error
+	if claimer, ok := q.store.(domain.JobClaimer); ok {
+		jobs, err = claimer.ClaimJobs()
+	} else {
+		jobs, err = q.store.List(domain.Query{})
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	known := make(map[string]bool, len(q.pending)+len(q.running))
+	for _, it := range q.pending {
+		known[it.job.ID] = true
+	}
+	for id := range q.running {
+		known[id] = true
+	}
+	restored := 0
+	for _, job := range jobs {
+		if job.Finished() || known[job.ID] {
+			continue
+		}
+		if job.State != domain.JobQueued {
+			job.State = domain.JobQueued
+			job.BytesDone, job.Speed, job.ETA = 0, 0, 0
+			q.save(&job)
+		}
+		q.push(job)
+		restored++
+	}
+	if restored > 0 {
+		q.cond.Broadcast()
+	}
+	return restored, nil
+}
+
+// keepLeases renews the jobs of this instance in the shared store and
+// takes over the ones that stopped instances left behind.
+func (q *Queue) keepLeases(claimer domain.JobClaimer) {
+	ticker := time.NewTicker(domain.JobLease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := claimer.RenewJobs(); err != nil {
+			log.Error(fmt.Sprintf("Erro ao renovar jobs: %v", err))
+		}
+		if claimed, err := q.restore(); err != nil {
+			log.Error(fmt.Sprintf("Erro ao assumir jobs abandonados: %v", err))
+		} else if claimed > 0 {
+			log.Info(fmt.Sprintf("%d jobs abandonados por outras instâncias assumidos", claimed))
+		}
+		q.cancelRequested(claimer)
+	}
+}
+
+// cancelRequested cancels the jobs of this instance whose cancellation
+// was requested through the store by another instance.
+func (q *Queue) cancelRequested(claimer domain.JobClaimer) {
+	ids, err := claimer.CancelRequests()
+	if err != nil {
+		log.Error(fmt.Sprintf("Erro ao ler pedidos de cancelamento: %v", err))
+		return
+	}
+	for _, id := range ids {
+		if _, ok := q.cancelLocal(id); ok {
+			log.Info(fmt.Sprintf("Job %s cancelado a pedido de outra instância", id))
+		}
+	}
+}
+
+// Stop stops taking new jobs. Jobs still running keep their state in the
+// store and are resumed by the next Start.
+func (q *Queue) Stop() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cond.Broadcast()
+	q.cancel()
+}
+
+// Enqueue persists a new job and schedules it.
+func (q *Queue) Enqueue(job domain.Job) (domain.Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return domain.Job{}, ErrQueueClosed
+	}
+
+	if job.ID == "" {
+		job.ID = uuid.NewString()
+	}
+	if job.Video.ID == "" {
+		job.Video.ID = uuid.NewString()
+	}
+	job.State = domain.JobQueued
+	job.CreatedAt = time.Now()
+	if err := q.save(&job); err != nil {
+		return domain.Job{}, err
+	}
+
+	q.push(job)
+	q.cond.Signal()
+	return job, nil
+}
+
+func (q *Queue) push(job domain.Job) {
+	q.seq++
+	heap.Push(&q.pending, &item{job: job, seq: q.seq})
+	if q.queued != nil {
+		q.queued(job)
+	}
+}
+
+// Cancel stops a job. A queued job is dropped from the queue, a running
+// one has its context cancelled with domain.ErrDownloadCancelled and is
+// marked as cancelled once its handler returns. A job another instance
+// holds in a shared store is cancelled by it once it sees the request.
+func (q *Queue) Cancel(id string) (domain.Job, error) {
+	if job, ok := q.cancelLocal(id); ok {
+		return job, nil
+	}
+
+	job, err := q.store.Get(id)
+	if err != nil {
+		return domain.Job{}, ErrJobNotFound
+	}
+	if job.Finished() {
+		return job, ErrJobFinished
+	}
+	claimer, ok := q.store.(domain.JobClaimer)
+	if !ok {
+		return job, ErrJobElsewhere
+	}
+	requested, err := claimer.RequestCancel(id)
+	if err != nil {
+		return job, fmt.Errorf("error requesting cancel: %w", err)
+	}
+	if !requested {
+		if finished, err := q.store.Get(id); err == nil {
+			job = finished
+		}
+		return job, ErrJobFinished
+	}
+	log.Info(fmt.Sprintf("Cancelamento do job %s pedido à instância que o executa", id))
+	return job, nil
+}
+
+// cancelLocal cancels a job queued or running here, reporting false when
+// this queue does not hold it.
+func (q *Queue) cancelLocal(id string) (domain.Job, bool) {
+	q.mu.Lock()
+
+	if job, ok := q.running[id]; ok {
+		q.cancels[id](domain.ErrDownloadCancelled)
+		defer q.mu.Unlock()
+		return *job, true
+	}
+
+	for i, it := range q.pending {
+		if it.job.ID != id {
+			continue
+		}
+		heap.Remove(&q.pending, i)
+		job := it.job
+		job.State = domain.JobCancelled
+		q.save(&job)
+		q.mu.Unlock()
+
+		log.Info(fmt.Sprintf("Job %s cancelado antes de iniciar", id))
+		if q.cancelled != nil {
+			q.cancelled(job)
+		}
+		q.notifyFinished(job)
+		return job, true
+	}
+	q.mu.Unlock()
+	return domain.Job{}, false
+}
+
+// next blocks until a job is available or the queue is stopped.
+func (q *Queue) next() (domain.Job, context.Context, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.pending) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return domain.Job{}, nil, false
+	}
+
+	job := heap.Pop(&q.pending).(*item).job
+	job.State = domain.JobFetchingMetadata
+	q.save(&job)
+	q.running[job.ID] = &job
+
+	ctx, cancel := context.WithCancelCause(q.ctx)
+	q.cancels[job.ID] = cancel
+	return job, ctx, true
+}
+
+func (q *Queue) work() {
+	for {
+		job, ctx, ok := q.next()
+		if !ok {
+			return
+		}
+		q.run(ctx, job)
+	}
+}
+
+func (q *Queue) run(ctx context.Context, job domain.Job) {
+	log.Info(fmt.Sprintf("Job %s iniciado", job.ID))
+	err := q.handle(ctx, job)
+
+	q.mu.Lock()
+	job = *q.running[job.ID]
+	delete(q.running, job.ID)
+	q.cancels[job.ID](nil)
+	delete(q.cancels, job.ID)
+
+	if q.ctx.Err() != nil {
+		q.mu.Unlock()
+		log.Info(fmt.Sprintf("Job %s interrompido, será retomado na próxima inicialização", job.ID))
+		return
+	}
+
+	job.Speed, job.ETA = 0, 0
+	if err != nil && errors.Is(context.Cause(ctx), domain.ErrDownloadCancelled) {
+		log.Info(fmt.Sprintf("Job %s cancelado", job.ID))
+		job.State = domain.JobCancelled
+	} else if err != nil {
+		log.Error(fmt.Sprintf("Job %s falhou: %v", job.ID, err))
+		job.State = domain.JobFailed
+		job.Error = err.Error()
+	} else {
+		log.Info(fmt.Sprintf("Job %s concluído", job.ID))
+		job.State = domain.JobDone
+		job.VideoID = job.Video.ID
+	}
+	q.save(&job)
+	q.mu.Unlock()
+
+	q.notifyFinished(job)
+}
+
+// handle calls the handler, turning a panic into an error so a job that
+// trips over malformed media fails instead of taking the process down.
+func (q *Queue) handle(ctx context.Context, job domain.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error(fmt.Sprintf("Job %s entrou em pânico: %v\n%s", job.ID, r, debug.Stack()))
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return q.handler(ctx, job, newTracker(q, job.ID))
+}
+
+func (q *Queue) notifyFinished(job domain.Job) {
+	if q.finished != nil {
+		q.finished(job)
+	}
+}
+
+// Get returns the job, with live progress when it is running.
+func (q *Queue) Get(id string) (domain.Job, error) {
+	q.mu.Lock()
+	if job, ok := q.running[id]; ok {
+		defer q.mu.Unlock()
+		return *job, nil
+	}
+	q.mu.Unlock()
+
+	job, err := q.store.Get(id)
+	if err != nil {
+		return domain.Job{}, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// List returns the jobs, newest first, optionally filtered by requester
+// and state. Empty filters match every job.
+func (q *Queue) List(requester string, state domain.JobState) ([]domain.Job, error) {
+	jobs, err := q.store.List(domain.Query{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing jobs: %w", err)
+	}
+
+	q.mu.Lock()
+	filtered := jobs[:0]
+	for _, job := range jobs {
+		if running, ok := q.running[job.ID]; ok {
+			job = *running
+		}
+		if requester != "" && job.Video.Requester != requester {
+			continue
+		}
+		if state != "" && job.State != state {
+			continue
+		}
+		filtered = append(filtered, job)
+	}
+	q.mu.Unlock()
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].CreatedAt.After(filtered[j].CreatedAt)
+	})
+	return filtered, nil
+}
+
+// update changes a running job in memory, persisting it when asked.
+func (q *Queue) update(id string, persist bool, change func(job *domain.Job)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.running[id]
+	if !ok {
+		return
+	}
+
+	change(job)
+	if persist {
+		q.save(job)
+	} else {
+		job.UpdatedAt = time.Now()
+	}
+}
+
+func (q *Queue) save(job *domain.Job) error {
+	job.UpdatedAt = time.Now()
+	if err := q.store.Save(job.ID, *job); err != nil {
+		log.Error(fmt.Sprintf("Erro ao salvar job %s: %v", job.ID, err))
+		return fmt.Errorf("error saving job: %w", err)
+	}
+	return nil
+}
+
+type item struct {
+	job domain.Job
+	seq uint64
+}
+
+// jobHeap orders items by priority, then by arrival.
+type jobHeap []*item
+
+func (h jobHeap) Len() int { return len(h) }
+
+func (h jobHeap) Less(i, j int) bool {
+	if h[i].job.Priority != h[j].job.Priority {
+		return h[i].job.Priority > h[j].job.Priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h jobHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *jobHeap) Push(x any) { *h = append(*h, x.(*item)) }
+
+func (h *jobHeap) Pop() any {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	*h = old[:n-1]
+	return it
+}
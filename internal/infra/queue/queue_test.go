@@ -0,0 +1,197 @@
+package queue
+
+import (
+	"context"
+	"downloader/internal/domain"
+	memoria "downloader/internal/infra/db/mem_db"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunMarksPanickingJobFailed(t *testing.T) {
+	store := memoria.NewMemoriaDatabase[domain.Job]()
+	q := NewQueue(store, 1, func(ctx context.Context, job domain.Job, progress domain.ProgressBar) error {
+		panic("slice bounds out of range")
+	})
+	finished := make(chan domain.Job, 1)
+	q.OnFinished(func(job domain.Job) { finished <- job })
+	if err := q.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer q.Stop()
+
+	job, err := q.Enqueue(domain.Job{Video: domain.Video{URL: "https://example.com/a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-finished:
+		if got.ID != job.ID || got.State != domain.JobFailed || !strings.Contains(got.Error, "panicked") {
+			t.Fatalf("finished job = %+v, want %s failed with a panic error", got, job.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not finish")
+	}
+
+	saved, err := q.Get(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.State != domain.JobFailed {
+		t.Fatalf("saved state = %s, want %s", saved.State, domain.JobFailed)
+	}
+}
+
+// claimingStore hands out only the jobs of one requester, as a shared store
+// does with the jobs other instances hold.
+type claimingStore struct {
+	*memoria.MemoriaDatabase[domain.Job]
+	requester string
+	cancels   map[string]bool
+}
+
+func (s claimingStore) ClaimJobs() ([]domain.Job, error) {
+	jobs, err := s.List(domain.Query{})
+	claimed := []domain.Job{}
+	for _, job := range jobs {
+		if job.Video.Requester == s.requester {
+			claimed = append(claimed, job)
+		}
+	}
+	return claimed, err
+}
+
+func (s claimingStore) RenewJobs() error { return nil }
+
+func (s claimingStore) RequestCancel(id string) (bool, error) {
+	job, err := s.Get(id)
+	if err != nil || job.Finished() {
+		return false, err
+	}
+	s.cancels[id] = true
+	return true, nil
+}
+
+func (s claimingStore) CancelRequests() ([]string, error) {
+	ids := []string{}
+	for id := range s.cancels {
+		if job, err := s.Get(id); err == nil && job.Video.Requester == s.requester && !job.Finished() {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func TestStartRunsOnlyClaimedJobs(t *testing.T) {
+	store := claimingStore{memoria.NewMemoriaDatabase[domain.Job](), "ana", map[string]bool{}}
+	for _, job := range []domain.Job{
+		{ID: "mine", Video: domain.Video{Requester: "ana"}, State: domain.JobDownloading},
+		{ID: "other", Video: domain.Video{Requester: "bia"}, State: domain.JobDownloading},
+		{ID: "done", Video: domain.Video{Requester: "ana"}, State: domain.JobDone},
+	} {
+		store.Save(job.ID, job)
+	}
+
+	var mu sync.Mutex
+	var ran []string
+	q := NewQueue(store, 1, func(ctx context.Context, job domain.Job, progress domain.ProgressBar) error {
+		mu.Lock()
+		ran = append(ran, job.ID)
+		mu.Unlock()
+		return nil
+	})
+	finished := make(chan domain.Job, 3)
+	q.OnFinished(func(job domain.Job) { finished <- job })
+	if err := q.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer q.Stop()
+
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("claimed job did not run")
+	}
+	select {
+	case job := <-finished:
+		t.Fatalf("job %s ran although it was not claimed", job.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ran) != 1 || ran[0] != "mine" {
+		t.Fatalf("ran %v, want [mine]", ran)
+	}
+	if other, _ := store.Get("other"); other.State != domain.JobDownloading {
+		t.Fatalf("unclaimed job state = %s, want it untouched", other.State)
+	}
+}
+
+func TestCancelJobHeldElsewhere(t *testing.T) {
+	shared := memoria.NewMemoriaDatabase[domain.Job]()
+	for _, job := range []domain.Job{
+		{ID: "queued", Video: domain.Video{Requester: "bia"}, State: domain.JobQueued},
+		{ID: "done", Video: domain.Video{Requester: "bia"}, State: domain.JobDone},
+	} {
+		shared.Save(job.ID, job)
+	}
+	block := func(ctx context.Context, job domain.Job, progress domain.ProgressBar) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	// Instance ana asks instance bia, which holds the jobs, to cancel one.
+	ana := NewQueue(claimingStore{shared, "ana", map[string]bool{}}, 1, block)
+	if err := ana.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer ana.Stop()
+	if _, err := ana.Cancel("done"); !errors.Is(err, ErrJobFinished) {
+		t.Fatalf("Cancel of a finished job = %v, want ErrJobFinished", err)
+	}
+	if _, err := ana.Cancel("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("Cancel of a missing job = %v, want ErrJobNotFound", err)
+	}
+	job, err := ana.Cancel("queued")
+	if err != nil || job.State != domain.JobQueued {
+		t.Fatalf("Cancel of a job held elsewhere = %+v, %v, want it still queued", job, err)
+	}
+
+	// The owner, restored but without workers so the job stays queued, only
+	// sees the request through the store it shares.
+	requests := ana.store.(claimingStore).cancels
+	bia := NewQueue(claimingStore{shared, "bia", requests}, 1, block)
+	cancelled := make(chan domain.Job, 1)
+	bia.OnCancelled(func(job domain.Job) { cancelled <- job })
+	if _, err := bia.restore(); err != nil {
+		t.Fatal(err)
+	}
+	bia.cancelRequested(bia.store.(claimingStore))
+
+	select {
+	case got := <-cancelled:
+		if got.ID != "queued" || got.State != domain.JobCancelled {
+			t.Fatalf("cancelled job = %+v, want queued cancelled", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("owner did not cancel the requested job")
+	}
+	if saved, _ := shared.Get("queued"); saved.State != domain.JobCancelled {
+		t.Fatalf("saved state = %s, want %s", saved.State, domain.JobCancelled)
+	}
+}
+
+func TestCancelJobHeldElsewhereWithoutSharedStore(t *testing.T) {
+	store := memoria.NewMemoriaDatabase[domain.Job]()
+	store.Save("queued", domain.Job{ID: "queued", State: domain.JobQueued})
+	q := NewQueue(store, 1, nil)
+
+	if _, err := q.Cancel("queued"); !errors.Is(err, ErrJobElsewhere) {
+		t.Fatalf("Cancel of a job not queued here = %v, want ErrJobElsewhere", err)
+	}
+}
@@ -0,0 +1,87 @@
+package queue
+
+import (
+	"downloader/internal/domain"
+	"sync"
+	"time"
+)
+
+// speedSampleInterval is the minimum time between two speed samples.
+const speedSampleInterval = 500 * time.Millisecond
+
+// tracker is the progress bar handed to each job. It keeps the live state
+// of the job in the queue; only stage changes are persisted, progress
+// updates stay in memory.
+type tracker struct {
+	mu         sync.Mutex
+	queue      *Queue
+	id         string
+	total      int64
+	lastBytes  int64
+	lastSample time.Time
+	speed      float64
+}
+
+func newTracker(q *Queue, id string) *tracker {
+	return &tracker{queue: q, id: id}
+}
+
+func (t *tracker) Start(total int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total = total
+	t.lastBytes = 0
+	t.lastSample = time.Now()
+	t.queue.update(t.id, true, func(job *domain.Job) {
+		job.State = domain.JobDownloading
+		job.BytesTotal = total
+		job.BytesDone = 0
+	})
+}
+
+func (t *tracker) Update(current int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	elapsed := now.Sub(t.lastSample)
+	if elapsed < speedSampleInterval {
+		t.queue.update(t.id, false, func(job *domain.Job) {
+			job.BytesDone = current
+		})
+		return
+	}
+
+	// Exponential moving average, so short stalls do not zero the speed.
+	sample := float64(current-t.lastBytes) / elapsed.Seconds()
+	if t.speed == 0 {
+		t.speed = sample
+	} else {
+		t.speed = 0.3*sample + 0.7*t.speed
+	}
+	t.lastBytes = current
+	t.lastSample = now
+
+	var eta time.Duration
+	if t.speed > 0 && t.total > current {
+		eta = time.Duration(float64(t.total-current) / t.speed * float64(time.Second))
+	}
+
+	t.queue.update(t.id, false, func(job *domain.Job) {
+		job.BytesDone = current
+		job.Speed = t.speed
+		job.ETA = eta
+	})
+}
+
+func (t *tracker) Finish() {
+	t.queue.update(t.id, false, func(job *domain.Job) {
+		job.BytesDone = job.BytesTotal
+		job.ETA = 0
+	})
+}
+
+func (t *tracker) Stage(state domain.JobState) {
+	t.queue.update(t.id, true, func(job *domain.Job) {
+		job.State = state
+	})
+}
@@ -0,0 +1,30 @@
+package registry
+
+import (
+	"downloader/internal/domain"
+	"downloader/internal/infra/direct"
+	"downloader/internal/infra/manifest"
+	"downloader/internal/infra/retry"
+	"downloader/internal/infra/youtube"
+)
+
+// NewDefault builds the registry of every source the application knows:
+// YouTube by host, HLS and DASH manifests by extension and any other
+// HTTP(S) URL that serves a file, rather than a web page, as a direct
+// download.
+// Each downloader retries its own transient errors.
+func NewDefault(notifyer domain.Notifyer, db domain.Database[domain.Video]) *Registry {
+	r := NewRegistry(notifyer)
+	yt := retry.NewRetryDownloader(youtube.NewKkdaiDownloader(notifyer, db), retry.NewPolicy(youtube.Retryable))
+	for _, host := range youtube.Hosts {
+		r.RegisterHost(host, yt)
+	}
+	m := retry.NewRetryDownloader(manifest.NewManifestDownloader(notifyer, db), retry.NewPolicy(manifest.Retryable))
+	for _, ext := range manifest.Extensions {
+		r.RegisterExtension(ext, m)
+	}
+	file := retry.NewRetryDownloader(direct.NewHTTPDownloader(notifyer, db), retry.NewPolicy(direct.Retryable))
+	r.RegisterScheme("http", file)
+	r.RegisterScheme("https", file)
+	return r
+}
@@ -0,0 +1,118 @@
+package registry
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"downloader/internal/infra/download"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// Registry is a domain.Downloader that hands every video to the downloader
+// registered for the host, the path extension or the scheme of its URL,
+// tried in that order. A host also matches its subdomains.
+type Registry struct {
+	hosts      map[string]domain.Downloader
+	extensions map[string]domain.Downloader
+	schemes    map[string]domain.Downloader
+	notifyer   domain.Notifyer
+}
+
+// NewRegistry builds an empty registry. notifyer is used for the
+// notifications that are not about a single video, and may be nil.
+func NewRegistry(notifyer domain.Notifyer) *Registry {
+	return &Registry{
+		hosts:      map[string]domain.Downloader{},
+		extensions: map[string]domain.Downloader{},
+		schemes:    map[string]domain.Downloader{},
+		notifyer:   notifyer,
+	}
+}
+
+// RegisterHost makes d download the URLs of host and of its subdomains.
+func (r *Registry) RegisterHost(host string, d domain.Downloader) {
+	r.hosts[strings.ToLower(host)] = d
+}
+
+// RegisterExtension makes d download the URLs whose path ends in ext, such
+// as ".m3u8", and whose host has no downloader of its own.
+func (r *Registry) RegisterExtension(ext string, d domain.Downloader) {
+	r.extensions[strings.ToLower(ext)] = d
+}
+
+// RegisterScheme makes d download the URLs of the given scheme whose host
+// and extension have no downloader of their own.
+func (r *Registry) RegisterScheme(scheme string, d domain.Downloader) {
+	r.schemes[strings.ToLower(scheme)] = d
+}
+
+// Resolve returns the downloader of rawURL.
+func (r *Registry) Resolve(rawURL string) (domain.Downloader, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q is not an absolute URL", domain.ErrUnsupportedSource, rawURL)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for {
+		if d, ok := r.hosts[host]; ok {
+			return d, nil
+		}
+		dot := strings.IndexByte(host, '.')
+		if dot < 0 {
+			break
+		}
+		host = host[dot+1:]
+	}
+	if d, ok := r.extensions[strings.ToLower(path.Ext(u.Path))]; ok {
+		return d, nil
+	}
+	if d, ok := r.schemes[strings.ToLower(u.Scheme)]; ok {
+		return d, nil
+	}
+	return nil, fmt.Errorf("%w: %s", domain.ErrUnsupportedSource, u.Redacted())
+}
+
+// Supports reports, as an ErrUnsupportedSource error, when no downloader
+// handles rawURL, asking the one that does when it can check URLs itself.
+func (r *Registry) Supports(ctx context.Context, rawURL string) error {
+	d, err := r.Resolve(rawURL)
+	if err != nil {
+		return err
+	}
+	if checker, ok := d.(domain.SourceChecker); ok {
+		return checker.Supports(ctx, rawURL)
+	}
+	return nil
+}
+
+// SourceID asks the downloader of rawURL for the ID of its video.
+func (r *Registry) SourceID(rawURL string) (string, error) {
+	d, err := r.Resolve(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if identifier, ok := d.(domain.SourceIdentifier); ok {
+		return identifier.SourceID(rawURL)
+	}
+	return "", nil
+}
+
+func (r *Registry) Download(ctx context.Context, video domain.Video, progress domain.ProgressBar) error {
+	d, err := r.Resolve(video.URL)
+	if err != nil {
+		return err
+	}
+	return d.Download(ctx, video, progress)
+}
+
+func (r *Registry) Finalize(notification domain.Notification) error {
+	return download.Finalize(r.notifyer, notification)
+}
+
+func (r *Registry) Cancel(file *os.File) error {
+	return download.Cancel(file)
+}
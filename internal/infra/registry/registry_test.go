@@ -0,0 +1,76 @@
+package registry
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"errors"
+	"os"
+	"testing"
+)
+
+type fakeDownloader struct {
+	name string
+	// refuse makes Supports fail, when set; nil means no Supports at all.
+	refuse error
+}
+
+func (d fakeDownloader) Download(ctx context.Context, video domain.Video, progress domain.ProgressBar) error {
+	return nil
+}
+
+func (d fakeDownloader) Finalize(notification domain.Notification) error { return nil }
+
+func (d fakeDownloader) Cancel(file *os.File) error { return nil }
+
+type checkingDownloader struct {
+	fakeDownloader
+}
+
+func (d checkingDownloader) Supports(ctx context.Context, url string) error { return d.refuse }
+
+func TestResolveAndSupports(t *testing.T) {
+	refused := errors.New("serves a web page")
+	r := NewRegistry(nil)
+	r.RegisterHost("youtube.com", fakeDownloader{name: "youtube"})
+	r.RegisterExtension(".m3u8", fakeDownloader{name: "manifest"})
+	r.RegisterScheme("https", checkingDownloader{fakeDownloader{name: "direct", refuse: refused}})
+
+	cases := []struct {
+		name     string
+		url      string
+		resolved string
+		supports error
+	}{
+		{"host", "https://youtube.com/watch?v=x", "youtube", nil},
+		{"subdomain", "https://m.youtube.com/watch?v=x", "youtube", nil},
+		{"extension", "https://cdn.example.com/live/index.M3U8", "manifest", nil},
+		{"scheme asks the downloader", "https://example.com/page", "direct", refused},
+		{"unknown scheme", "ftp://example.com/file.mp4", "", domain.ErrUnsupportedSource},
+		{"relative URL", "/video.mp4", "", domain.ErrUnsupportedSource},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, err := r.Resolve(c.url)
+			switch {
+			case c.resolved == "" && !errors.Is(err, domain.ErrUnsupportedSource):
+				t.Fatalf("Resolve = %v, want ErrUnsupportedSource", err)
+			case c.resolved != "" && err != nil:
+				t.Fatal(err)
+			case c.resolved != "":
+				name := ""
+				switch d := d.(type) {
+				case fakeDownloader:
+					name = d.name
+				case checkingDownloader:
+					name = d.name
+				}
+				if name != c.resolved {
+					t.Fatalf("resolved to %q, want %q", name, c.resolved)
+				}
+			}
+			if err := r.Supports(context.Background(), c.url); !errors.Is(err, c.supports) {
+				t.Fatalf("Supports = %v, want %v", err, c.supports)
+			}
+		})
+	}
+}
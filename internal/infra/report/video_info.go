@@ -0,0 +1,201 @@
+// Package report renders results for people reading a terminal.
+package report
+
+import (
+	"downloader/internal/domain"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// VideoInfo writes the video info as aligned text, with one line per
+// format.
+func VideoInfo(w io.Writer, info domain.VideoInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	metadata := info.Metadata
+
+	fmt.Fprintf(tw, "Title:\t%s\n", info.Title)
+	fmt.Fprintf(tw, "URL:\t%s\n", info.URL)
+	fmt.Fprintf(tw, "Channel:\t%s (%s)\n", metadata.Author, metadata.ChannelID)
+	fmt.Fprintf(tw, "Duration:\t%s\n", Timestamp(metadata.Duration))
+	if !metadata.PublishDate.IsZero() {
+		fmt.Fprintf(tw, "Published:\t%s\n", metadata.PublishDate.Format(time.DateOnly))
+	}
+	fmt.Fprintf(tw, "Views:\t%d\n", metadata.Views)
+	if len(metadata.Thumbnails) > 0 {
+		best := metadata.Thumbnails[len(metadata.Thumbnails)-1]
+		fmt.Fprintf(tw, "Thumbnail:\t%s (%dx%d)\n", best.URL, best.Width, best.Height)
+	}
+
+	if len(metadata.Chapters) > 0 {
+		fmt.Fprintln(tw, "\nChapters:")
+		for _, chapter := range metadata.Chapters {
+			fmt.Fprintf(tw, "  %s\t%s\n", Timestamp(chapter.Start), chapter.Title)
+		}
+	}
+
+	if len(info.Captions) > 0 {
+		fmt.Fprintln(tw, "\nCaptions:")
+		for _, caption := range info.Captions {
+			name := caption.Name
+			if caption.AutoGenerated {
+				name += " (auto)"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\n", caption.LanguageCode, name)
+		}
+	}
+
+	fmt.Fprintln(tw, "\nITAG\tCONTAINER\tCODECS\tRESOLUTION\tFPS\tBITRATE\tSIZE\tAUDIO")
+	for _, f := range info.Formats {
+		resolution := "audio only"
+		if f.Width > 0 {
+			resolution = fmt.Sprintf("%dx%d", f.Width, f.Height)
+			if f.Resolution != "" {
+				resolution += " (" + f.Resolution + ")"
+			}
+		}
+		audio := "-"
+		if f.AudioChannels > 0 {
+			audio = fmt.Sprintf("%dch %dHz", f.AudioChannels, f.AudioSampleRate)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%dk\t%s\t%s\n", f.Itag, f.Container, strings.Join(f.Codecs, ", "),
+			resolution, fps(f.FPS), f.Bitrate/1000, Size(f.Size), audio)
+	}
+	return tw.Flush()
+}
+
+type videoInfoDocument struct {
+	ID          string              `json:"id"`
+	URL         string              `json:"url"`
+	Title       string              `json:"title"`
+	Author      string              `json:"author"`
+	ChannelID   string              `json:"channel_id"`
+	Duration    float64             `json:"duration_seconds"`
+	Description string              `json:"description"`
+	PublishDate *time.Time          `json:"publish_date,omitempty"`
+	Views       int                 `json:"views"`
+	Thumbnails  []thumbnailDocument `json:"thumbnails"`
+	Chapters    []chapterDocument   `json:"chapters"`
+	Captions    []captionDocument   `json:"captions"`
+	Formats     []formatDocument    `json:"formats"`
+}
+
+type thumbnailDocument struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type chapterDocument struct {
+	Title string  `json:"title"`
+	Start float64 `json:"start_seconds"`
+	End   float64 `json:"end_seconds"`
+}
+
+type captionDocument struct {
+	LanguageCode  string `json:"language_code"`
+	Name          string `json:"name"`
+	AutoGenerated bool   `json:"auto_generated"`
+}
+
+type formatDocument struct {
+	Itag            int      `json:"itag"`
+	Container       string   `json:"container"`
+	MimeType        string   `json:"mime_type"`
+	Codecs          []string `json:"codecs"`
+	Resolution      string   `json:"resolution,omitempty"`
+	Width           int      `json:"width,omitempty"`
+	Height          int      `json:"height,omitempty"`
+	FPS             int      `json:"fps,omitempty"`
+	Bitrate         int      `json:"bitrate"`
+	Size            int64    `json:"size,omitempty"`
+	AudioChannels   int      `json:"audio_channels,omitempty"`
+	AudioSampleRate int      `json:"audio_sample_rate,omitempty"`
+}
+
+// VideoInfoJSON writes the video info as a JSON document.
+func VideoInfoJSON(w io.Writer, info domain.VideoInfo) error {
+	metadata := info.Metadata
+	doc := videoInfoDocument{
+		ID:          info.ID,
+		URL:         info.URL,
+		Title:       info.Title,
+		Author:      metadata.Author,
+		ChannelID:   metadata.ChannelID,
+		Duration:    metadata.Duration.Seconds(),
+		Description: metadata.Description,
+		Views:       metadata.Views,
+		Thumbnails:  []thumbnailDocument{},
+		Chapters:    []chapterDocument{},
+		Captions:    []captionDocument{},
+		Formats:     []formatDocument{},
+	}
+	if !metadata.PublishDate.IsZero() {
+		doc.PublishDate = &metadata.PublishDate
+	}
+	for _, t := range metadata.Thumbnails {
+		doc.Thumbnails = append(doc.Thumbnails, thumbnailDocument{URL: t.URL, Width: t.Width, Height: t.Height})
+	}
+	for _, c := range metadata.Chapters {
+		doc.Chapters = append(doc.Chapters, chapterDocument{Title: c.Title, Start: c.Start.Seconds(), End: c.End.Seconds()})
+	}
+	for _, c := range info.Captions {
+		doc.Captions = append(doc.Captions, captionDocument{LanguageCode: c.LanguageCode, Name: c.Name, AutoGenerated: c.AutoGenerated})
+	}
+	for _, f := range info.Formats {
+		doc.Formats = append(doc.Formats, formatDocument{
+			Itag:            f.Itag,
+			Container:       f.Container,
+			MimeType:        f.MimeType,
+			Codecs:          f.Codecs,
+			Resolution:      f.Resolution,
+			Width:           f.Width,
+			Height:          f.Height,
+			FPS:             f.FPS,
+			Bitrate:         f.Bitrate,
+			Size:            f.Size,
+			AudioChannels:   f.AudioChannels,
+			AudioSampleRate: f.AudioSampleRate,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// Timestamp formats d as "M:SS" or "H:MM:SS".
+func Timestamp(d time.Duration) string {
+	seconds := int(d.Seconds())
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// Size formats a byte count with a binary unit, or "-" when unknown.
+func Size(bytes int64) string {
+	if bytes <= 0 {
+		return "-"
+	}
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+func fps(n int) string {
+	if n == 0 {
+		return "-"
+	}
+	return fmt.Sprint(n)
+}
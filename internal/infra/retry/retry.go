@@ -0,0 +1,141 @@
+package retry
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"downloader/internal/infra/download"
+	"downloader/pkg/config"
+	logger "downloader/pkg/log"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"time"
+)
+
+var log = logger.GetLogger("retry")
+
+// Policy tells how many times a download is attempted and how long to wait
+// between attempts. Retryable decides which errors are worth another try;
+// a nil Retryable retries every error.
+type Policy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Retryable   func(err error) bool
+}
+
+// NewPolicy builds a policy from the retry section of the config.
+func NewPolicy(retryable func(err error) bool) Policy {
+	cfg := config.GetConfig().Retry
+	return Policy{
+		MaxAttempts: cfg.MaxAttempts,
+		BaseDelay:   time.Duration(cfg.BaseDelayMs) * time.Millisecond,
+		MaxDelay:    time.Duration(cfg.MaxDelayMs) * time.Millisecond,
+		Retryable:   retryable,
+	}
+}
+
+// Delay returns the wait before the attempt following the given one: an
+// exponential backoff capped at MaxDelay, with half of it randomized so
+// concurrent downloads do not retry in lockstep.
+func (p Policy) Delay(attempt int) time.Duration {
+	shift := min(max(attempt-1, 0), 30)
+	delay := p.BaseDelay << shift
+	if delay>>shift != p.BaseDelay {
+		// Too long to represent: past any cap.
+		delay = time.Duration(math.MaxInt64)
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+func (p Policy) retryable(err error) bool {
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// Downloader retries the downloads of another domain.Downloader on
+// transient errors, and tells the requester when it finally gives up,
+// unless the video belongs to a playlist.
+type Downloader struct {
+	inner  domain.Downloader
+	policy Policy
+}
+
+func NewRetryDownloader(inner domain.Downloader, policy Policy) *Downloader {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return &Downloader{inner: inner, policy: policy}
+}
+
+func (d *Downloader) Download(ctx context.Context, video domain.Video, progress domain.ProgressBar) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = d.inner.Download(ctx, video, progress)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+
+		if !d.policy.retryable(err) {
+			log.Error(fmt.Sprintf("Download de %s falhou sem nova tentativa: %v", video.URL, err))
+			break
+		}
+		if attempt >= d.policy.MaxAttempts {
+			log.Error(fmt.Sprintf("Download de %s falhou após %d tentativas: %v", video.URL, attempt, err))
+			break
+		}
+
+		delay := d.policy.Delay(attempt)
+		log.Info(fmt.Sprintf("Tentativa %d de %d para %s falhou: %v. Nova tentativa em %s", attempt, d.policy.MaxAttempts, video.URL, err, delay.Round(time.Millisecond)))
+
+		select {
+		case <-ctx.Done():
+			return download.Stopped(ctx, d.inner, video, video.URL)
+		case <-time.After(delay):
+		}
+	}
+
+	if video.InPlaylist() {
+		return err
+	}
+	if notifyErr := d.inner.Finalize(domain.Notification{
+		Title:   video.URL,
+		Message: err.Error(),
+		To:      video.Requester,
+		Kind:    domain.NotificationFailed,
+	}); notifyErr != nil {
+		log.Error(fmt.Sprintf("Erro ao notificar falha de %s: %v", video.URL, notifyErr))
+	}
+	return err
+}
+
+// Supports asks the inner downloader, when it can check URLs.
+func (d *Downloader) Supports(ctx context.Context, url string) error {
+	if checker, ok := d.inner.(domain.SourceChecker); ok {
+		return checker.Supports(ctx, url)
+	}
+	return nil
+}
+
+// SourceID asks the inner downloader, when it knows source IDs.
+func (d *Downloader) SourceID(url string) (string, error) {
+	if identifier, ok := d.inner.(domain.SourceIdentifier); ok {
+		return identifier.SourceID(url)
+	}
+	return "", nil
+}
+
+func (d *Downloader) Finalize(notification domain.Notification) error {
+	return d.inner.Finalize(notification)
+}
+
+func (d *Downloader) Cancel(file *os.File) error {
+	return d.inner.Cancel(file)
+}
@@ -0,0 +1,46 @@
+package retry
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		attempt  int
+		min, max time.Duration
+	}{
+		{"first attempt", Policy{BaseDelay: time.Second, MaxDelay: time.Minute}, 1, 500 * time.Millisecond, time.Second},
+		{"doubles each attempt", Policy{BaseDelay: time.Second, MaxDelay: time.Minute}, 3, 2 * time.Second, 4 * time.Second},
+		{"capped", Policy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}, 4, 2500 * time.Millisecond, 5 * time.Second},
+		{"uncapped", Policy{BaseDelay: time.Second}, 5, 8 * time.Second, 16 * time.Second},
+		{"overflowing shift takes the cap", Policy{BaseDelay: time.Hour, MaxDelay: time.Minute}, 100, 30 * time.Second, time.Minute},
+		{"overflowing shift without a cap", Policy{BaseDelay: time.Hour}, 100, math.MaxInt64 / 2, math.MaxInt64},
+		{"attempt zero", Policy{BaseDelay: time.Second, MaxDelay: time.Minute}, 0, 500 * time.Millisecond, time.Second},
+		{"no delays", Policy{}, 3, 0, 0},
+		{"no base delay", Policy{MaxDelay: time.Second}, 1, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 100 {
+				if got := tt.policy.Delay(tt.attempt); got < tt.min || got > tt.max {
+					t.Fatalf("Delay(%d) = %v, want between %v and %v", tt.attempt, got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestPolicyRetryable(t *testing.T) {
+	err := fmt.Errorf("boom")
+	if !(Policy{}).retryable(err) {
+		t.Error("a policy without Retryable does not retry")
+	}
+	if (Policy{Retryable: func(error) bool { return false }}).retryable(err) {
+		t.Error("a policy retries an error its Retryable refuses")
+	}
+}
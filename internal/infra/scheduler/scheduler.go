@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"context"
+	"downloader/internal/usecase"
+	logger "downloader/pkg/log"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var log = logger.GetLogger("scheduler")
+
+// checkTimeout bounds a single subscription check, so a stuck request does
+// not hold back the others.
+const checkTimeout = 5 * time.Minute
+
+// Scheduler checks the subscriptions that are due every tick.
+type Scheduler struct {
+	subscriptions *usecase.SubscriptionUseCase
+	tick          time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewScheduler(subscriptions *usecase.SubscriptionUseCase, tick time.Duration) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{subscriptions: subscriptions, tick: tick, ctx: ctx, cancel: cancel}
+}
+
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.tick)
+		defer ticker.Stop()
+
+		for {
+			s.checkDue()
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop interrupts the running check and waits for the scheduler to end.
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) checkDue() {
+	due, err := s.subscriptions.Due(time.Now())
+	if err != nil {
+		log.Error(fmt.Sprintf("Erro ao buscar inscrições: %v", err))
+		return
+	}
+
+	for _, sub := range due {
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(s.ctx, checkTimeout)
+		if _, err := s.subscriptions.Check(ctx, sub.ID); err != nil {
+			log.Error(fmt.Sprintf("Erro ao verificar inscrição %s: %v", sub.ID, err))
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,50 @@
+package tagger
+
+import (
+	"downloader/internal/domain"
+	"downloader/internal/infra/id3"
+	"downloader/internal/infra/mp4"
+	"errors"
+	"fmt"
+)
+
+var ErrUnsupportedFormat = errors.New("tags not supported for this format")
+
+const dateLayout = "2006-01-02"
+
+// Write stores the title, channel, upload date, description, source URL and
+// ID of the video as tags of the file at video.Path. cover, when given, is
+// embedded as artwork.
+func Write(video domain.Video, cover []byte, coverType string) error {
+	metadata := video.Metadata
+	var date string
+	if !metadata.PublishDate.IsZero() {
+		date = metadata.PublishDate.Format(dateLayout)
+	}
+
+	switch video.Extension {
+	case "mp4", "m4a":
+		return mp4.SetTags(video.Path, mp4.Tags{
+			Title:       video.Title,
+			Artist:      metadata.Author,
+			Date:        date,
+			Description: metadata.Description,
+			URL:         video.URL,
+			VideoID:     video.SourceID,
+			Cover:       cover,
+			CoverType:   coverType,
+		})
+	case "mp3":
+		return id3.Write(video.Path, id3.Tags{
+			Title:       video.Title,
+			Artist:      metadata.Author,
+			Date:        date,
+			Description: metadata.Description,
+			URL:         video.URL,
+			VideoID:     video.SourceID,
+			Cover:       cover,
+			CoverType:   coverType,
+		})
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedFormat, video.Extension)
+}
@@ -0,0 +1,170 @@
+package webserver
+
+import (
+	"archive/zip"
+	"downloader/internal/domain"
+	"downloader/pkg/config"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type chapterResponse struct {
+	Index       int     `json:"index"`
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	Start       float64 `json:"start_seconds"`
+	End         float64 `json:"end_seconds,omitempty"`
+	Size        int64   `json:"size"`
+	DownloadURL string  `json:"download_url"`
+}
+
+type chapterList struct {
+	VideoID   string            `json:"video_id"`
+	Chapters  []chapterResponse `json:"chapters"`
+	BundleURL string            `json:"bundle_url,omitempty"`
+}
+
+// chapterFiles returns the videos split from the one with the given ID, in
+// chapter order.
+func (ws *WebServer) chapterFiles(id string) ([]domain.Video, error) {
+	return ws.db.List(domain.Query{
+		Filters: []domain.Filter{{Field: "ParentID", Op: domain.OpEqual, Value: id}},
+		OrderBy: "ChapterIndex",
+	})
+}
+
+// listChapters lists the chapters split from a downloaded video.
+func (ws *WebServer) listChapters(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	video, err := ws.db.Get(mux.Vars(r)["id"])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	chapters, err := ws.chapterFiles(video.ID)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	response := chapterList{VideoID: video.ID, Chapters: make([]chapterResponse, 0, len(chapters))}
+	if len(chapters) > 0 {
+		response.BundleURL = "/video/" + video.ID + "/chapters.zip"
+	}
+	for _, chapter := range chapters {
+		response.Chapters = append(response.Chapters, chapterResponse{
+			Index:       chapter.ChapterIndex,
+			ID:          chapter.ID,
+			Title:       chapter.Title,
+			Start:       chapter.ClipStart.Seconds(),
+			End:         chapter.ClipEnd.Seconds(),
+			Size:        chapter.Size,
+			DownloadURL: "/video/" + video.ID + "/chapters/" + strconv.Itoa(chapter.ChapterIndex),
+		})
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// chapter serves the file of a single chapter, by its number.
+func (ws *WebServer) chapter(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	index, err := strconv.Atoi(vars["n"])
+	if err != nil {
+		http.Error(w, "chapter must be a number", http.StatusBadRequest)
+		return
+	}
+	chapters, err := ws.db.List(domain.Query{Filters: []domain.Filter{
+		{Field: "ParentID", Op: domain.OpEqual, Value: vars["id"]},
+		{Field: "ChapterIndex", Op: domain.OpEqual, Value: index},
+	}})
+	if err != nil || len(chapters) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	ws.serveVideo(w, r, chapters[0])
+}
+
+// chapterBundle sends every chapter of a video in a single zip file. Like
+// single downloads, the files are removed once the bundle was sent.
+func (ws *WebServer) chapterBundle(w http.ResponseWriter, r *http.Request) {
+	video, err := ws.db.Get(mux.Vars(r)["id"])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	chapters, err := ws.chapterFiles(video.ID)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	videoRoot := config.GetConfig().VideoDir
+	var files []*os.File
+	var names []string
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	for _, chapter := range chapters {
+		// Chapter files are named after their ID, which is not trusted as
+		// a path.
+		f, err := os.Open(filepath.Join(videoRoot, filepath.Base(chapter.ID+"."+chapter.Extension)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		files = append(files, f)
+		names = append(names, chapter.Filename+"."+chapter.Extension)
+	}
+	if len(files) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, video.Filename))
+	w.Header().Set("Content-Type", "application/zip")
+	log.Info(fmt.Sprintf("Download dos capítulos de %s iniciado", video.Filename))
+
+	if err := writeBundle(w, files, names); err != nil {
+		log.Error(fmt.Sprintf("Erro ao enviar capítulos de %s: %v", video.Filename, err))
+		return
+	}
+	for _, f := range files {
+		f.Close()
+		if err := os.Remove(f.Name()); err != nil {
+			log.Error(fmt.Sprintf("Erro ao remover arquivo: %s", err))
+		}
+	}
+	log.Info(fmt.Sprintf("Capítulos de %s removidos após download", video.Filename))
+}
+
+// writeBundle writes files to a zip under names. Media is already
+// compressed, so the files are only stored.
+func writeBundle(w io.Writer, files []*os.File, names []string) error {
+	archive := zip.NewWriter(w)
+	for i, f := range files {
+		stat, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		entry, err := archive.CreateHeader(&zip.FileHeader{Name: names[i], Method: zip.Store, Modified: stat.ModTime()})
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(entry, f); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
@@ -0,0 +1,94 @@
+package webserver
+
+import (
+	"downloader/internal/domain"
+	"downloader/internal/infra/progress"
+	"downloader/internal/infra/queue"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const keepAliveInterval = 15 * time.Second
+
+// jobEvents streams the progress of a job as Server-Sent Events until the
+// job finishes or the client goes away. Only the unfinished jobs of this
+// instance have events: others are answered with 404, and their state is
+// available from the job itself.
+func (ws *WebServer) jobEvents(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	job, err := ws.queue.Get(id)
+	if errors.Is(err, queue.ErrJobNotFound) || (err == nil && job.Finished()) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// The job may have ended since Get, or run on another instance.
+	events, unsubscribe, ok := ws.broker.Subscribe(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			writeEvent(w, event)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event progress.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Error(fmt.Sprintf("Erro ao serializar evento: %v", err))
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+}
+
+func finalEvent(job domain.Job) progress.Event {
+	event := progress.Event{
+		Type:  progress.EventStage,
+		State: string(job.State),
+		Bytes: job.BytesDone,
+		Total: job.BytesTotal,
+		Error: job.Error,
+	}
+	if job.BytesTotal > 0 {
+		event.Percent = float64(job.BytesDone) * 100 / float64(job.BytesTotal)
+	}
+	return event
+}
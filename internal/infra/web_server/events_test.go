@@ -0,0 +1,72 @@
+package webserver
+
+import (
+	"downloader/internal/domain"
+	memoria "downloader/internal/infra/db/mem_db"
+	"downloader/internal/infra/progress"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestJobEvents(t *testing.T) {
+	jobs := memoria.NewMemoriaDatabase[domain.Job]()
+	jobs.Save("done", domain.Job{ID: "done", State: domain.JobDone})
+	ws := NewWebServer(&fakeDownloader{}, memoria.NewMemoriaDatabase[domain.Video](), jobs,
+		fakePlaylists{}, nil, nil, memoria.NewMemoriaDatabase[domain.Subscription]())
+	// Without workers the enqueued job stays queued.
+	queued, err := ws.queue.Enqueue(domain.Job{Video: domain.Video{URL: "https://example.com/a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(id string) *httptest.ResponseRecorder {
+		r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/jobs/"+id+"/events", nil), map[string]string{"id": id})
+		w := httptest.NewRecorder()
+		ws.jobEvents(w, r)
+		return w
+	}
+
+	cases := []struct {
+		name string
+		id   string
+	}{
+		{"unknown job", "missing"},
+		{"finished job", "done"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if w := get(c.id); w.Code != http.StatusNotFound {
+				t.Fatalf("status = %d, want 404", w.Code)
+			}
+		})
+	}
+
+	t.Run("queued job", func(t *testing.T) {
+		done := make(chan *httptest.ResponseRecorder)
+		go func() { done <- get(queued.ID) }()
+
+		// Give the handler time to subscribe before the job is closed; a
+		// handler that subscribes later answers 404, which fails below.
+		time.Sleep(50 * time.Millisecond)
+		ws.broker.Close(queued.ID, progress.Event{Type: progress.EventStage, State: string(domain.JobCancelled)})
+
+		select {
+		case w := <-done:
+			if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"state":"cancelled"`) {
+				t.Fatalf("status = %d, body %q, want the final event", w.Code, w.Body)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("stream did not end when the job was closed")
+		}
+
+		// Once closed, the job has no events left to subscribe to.
+		if _, _, ok := ws.broker.Subscribe(queued.ID); ok {
+			t.Fatal("closed job still has a topic")
+		}
+	})
+}
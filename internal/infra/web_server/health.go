@@ -0,0 +1,35 @@
+package webserver
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const healthTimeout = 3 * time.Second
+
+type healthResponse struct {
+	Status   string `json:"status"`
+	Database string `json:"database,omitempty"`
+}
+
+// health answers 503 when the database is unreachable, so a load balancer
+// can stop sending requests to this instance.
+func (ws *WebServer) health(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	response := healthResponse{Status: "ok"}
+
+	if checker, ok := ws.db.(domain.HealthChecker); ok {
+		ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+		defer cancel()
+		if err := checker.Health(ctx); err != nil {
+			log.Error(fmt.Sprintf("Banco de dados indisponível: %v", err))
+			response = healthResponse{Status: "unavailable", Database: err.Error()}
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,130 @@
+package webserver
+
+import (
+	"downloader/internal/domain"
+	"downloader/internal/infra/queue"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+var validJobStates = map[domain.JobState]bool{
+	domain.JobQueued:           true,
+	domain.JobFetchingMetadata: true,
+	domain.JobDownloading:      true,
+	domain.JobPostProcessing:   true,
+	domain.JobDone:             true,
+	domain.JobFailed:           true,
+	domain.JobCancelled:        true,
+}
+
+type jobResponse struct {
+	ID         string    `json:"id"`
+	State      string    `json:"state"`
+	URL        string    `json:"url"`
+	Requester  string    `json:"requester"`
+	Priority   int       `json:"priority"`
+	BytesDone  int64     `json:"bytes_done"`
+	BytesTotal int64     `json:"bytes_total"`
+	Speed      float64   `json:"speed"`
+	ETA        float64   `json:"eta_seconds"`
+	Error      string    `json:"error,omitempty"`
+	VideoID    string    `json:"video_id,omitempty"`
+	PlaylistID string    `json:"playlist_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func newJobResponse(job domain.Job) jobResponse {
+	return jobResponse{
+		ID:         job.ID,
+		State:      string(job.State),
+		URL:        job.Video.URL,
+		Requester:  job.Video.Requester,
+		Priority:   job.Priority,
+		BytesDone:  job.BytesDone,
+		BytesTotal: job.BytesTotal,
+		Speed:      job.Speed,
+		ETA:        job.ETA.Seconds(),
+		Error:      job.Error,
+		VideoID:    job.VideoID,
+		PlaylistID: job.Video.PlaylistID,
+		CreatedAt:  job.CreatedAt,
+		UpdatedAt:  job.UpdatedAt,
+	}
+}
+
+func (ws *WebServer) getJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	job, err := ws.queue.Get(mux.Vars(r)["id"])
+	if errors.Is(err, queue.ErrJobNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(newJobResponse(job))
+}
+
+func (ws *WebServer) listJobs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	requester := r.URL.Query().Get("requester")
+	state := domain.JobState(r.URL.Query().Get("state"))
+	if state != "" && !validJobStates[state] {
+		http.Error(w, "unknown state", http.StatusBadRequest)
+		return
+	}
+
+	playlist := r.URL.Query().Get("playlist")
+
+	jobs, err := ws.queue.List(requester, state)
+	if err != nil {
+		log.Error(fmt.Sprintf("Erro ao listar jobs: %v", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]jobResponse, 0, len(jobs))
+	for _, job := range jobs {
+		if playlist != "" && job.Video.PlaylistID != playlist {
+			continue
+		}
+		response = append(response, newJobResponse(job))
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// cancelJob stops a queued or running job. Running jobs stop
+// asynchronously, so they are answered with 202 and their current state.
+// A live recording stops by keeping what it recorded, and ends as done.
+// Jobs another instance holds are cancelled by it, also answered with 202.
+func (ws *WebServer) cancelJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	job, err := ws.queue.Cancel(mux.Vars(r)["id"])
+	switch {
+	case errors.Is(err, queue.ErrJobNotFound):
+		http.NotFound(w, r)
+		return
+	case errors.Is(err, queue.ErrJobFinished):
+		http.Error(w, "job already finished", http.StatusConflict)
+		return
+	case errors.Is(err, queue.ErrJobElsewhere):
+		http.Error(w, "job held by another instance", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if job.State != domain.JobCancelled {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	json.NewEncoder(w).Encode(newJobResponse(job))
+}
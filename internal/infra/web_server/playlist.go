@@ -0,0 +1,211 @@
+package webserver
+
+import (
+	"downloader/internal/domain"
+	"downloader/internal/usecase"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// playlistTracker follows the playlist requests through the jobs of their
+// entries, so each request gets its own summary once all of them finish.
+// Requests queued before a restart are rebuilt from the job store.
+type playlistTracker struct {
+	mu   sync.Mutex
+	jobs map[string]*playlistRequest
+}
+
+// playlistRequest counts how the entries queued by a playlist request
+// ended. expected is only final once every entry was queued.
+type playlistRequest struct {
+	id, title, requester string
+	expected, finished   int
+	result               usecase.PlaylistResult
+}
+
+func newPlaylistTracker() *playlistTracker {
+	return &playlistTracker{jobs: map[string]*playlistRequest{}}
+}
+
+func (ws *WebServer) addPlaylistNaFilaDeDownload(w http.ResponseWriter, r *http.Request, sol usecase.Solicitation, priority int) {
+	query := r.URL.Query()
+	reverse, err := parseBoolParam(query.Get("reverse"))
+	if err != nil {
+		http.Error(w, "reverse parameter must be a boolean", http.StatusBadRequest)
+		return
+	}
+	skipDownloaded, err := parseBoolParam(query.Get("skip_downloaded"))
+	if err != nil {
+		http.Error(w, "skip_downloaded parameter must be a boolean", http.StatusBadRequest)
+		return
+	}
+
+	download, err := ws.playlistUC.Expand(r.Context(), usecase.PlaylistSolicitation{
+		Solicitation:   sol,
+		Items:          query.Get("items"),
+		Reverse:        reverse,
+		SkipDownloaded: skipDownloaded,
+	})
+	if errors.Is(err, usecase.ErrInvalidItems) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("Erro ao carregar playlist %s: %v", sol.URL, err))
+		http.Error(w, "could not load playlist", http.StatusBadGateway)
+		return
+	}
+
+	if len(download.Videos) == 0 {
+		json.NewEncoder(w).Encode(returnHttp{Message: "Nenhum vídeo da playlist para baixar", PlaylistID: download.ID})
+		return
+	}
+
+	// Entries that finish while the rest are being queued wait for the
+	// lock, so the summary never covers part of the playlist.
+	request := &playlistRequest{
+		id:        download.ID,
+		title:     download.Videos[0].PlaylistTitle,
+		requester: sol.Requester,
+		result:    usecase.PlaylistResult{Skipped: download.Skipped},
+	}
+	ws.playlists.mu.Lock()
+	response := returnHttp{Message: "Download da playlist iniciado", PlaylistID: download.ID}
+	var enqueueErr error
+	for _, video := range download.Videos {
+		job, err := ws.downloadUC.Enqueue(r.Context(), video, priority)
+		if err != nil {
+			enqueueErr = err
+			break
+		}
+		ws.playlists.jobs[job.ID] = request
+		request.expected++
+		response.JobIDs = append(response.JobIDs, job.ID)
+	}
+	ws.playlists.mu.Unlock()
+
+	if enqueueErr != nil {
+		log.Error(fmt.Sprintf("Erro ao enfileirar playlist %s: %d de %d vídeos enfileirados: %v", download.ID, request.expected, len(download.Videos), enqueueErr))
+		if request.expected == 0 {
+			http.Error(w, "could not queue download", http.StatusServiceUnavailable)
+			return
+		}
+		response.Message = fmt.Sprintf("Download da playlist iniciado parcialmente: %d de %d vídeos enfileirados", request.expected, len(download.Videos))
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// restorePlaylists tracks again the playlist requests left unfinished by
+// a previous run. The entries of a request are the jobs that carry its
+// playlist ID, and the finished ones already count in its summary.
+func (ws *WebServer) restorePlaylists() error {
+	jobs, err := ws.jobs.List(domain.Query{})
+	if err != nil {
+		return fmt.Errorf("error listing jobs: %w", err)
+	}
+
+	ws.playlists.mu.Lock()
+	defer ws.playlists.mu.Unlock()
+	requests := map[string]*playlistRequest{}
+	for _, job := range jobs {
+		video := job.Video
+		if !video.InPlaylist() {
+			continue
+		}
+		request, ok := requests[video.PlaylistID]
+		if !ok {
+			request = &playlistRequest{
+				id:        video.PlaylistID,
+				title:     video.PlaylistTitle,
+				requester: video.Requester,
+				result:    usecase.PlaylistResult{Skipped: video.PlaylistSkipped},
+			}
+			requests[video.PlaylistID] = request
+		}
+		request.expected++
+		if job.Finished() {
+			request.count(job)
+		} else {
+			ws.playlists.jobs[job.ID] = request
+		}
+	}
+
+	restored := 0
+	for _, request := range requests {
+		if request.finished < request.expected {
+			restored++
+		}
+	}
+	if restored > 0 {
+		log.Info(fmt.Sprintf("%d playlists em andamento restauradas", restored))
+	}
+	return nil
+}
+
+// count records how an entry of the request ended.
+func (r *playlistRequest) count(job domain.Job) {
+	switch job.State {
+	case domain.JobDone:
+		r.result.Done++
+	case domain.JobFailed:
+		r.result.Failed++
+	case domain.JobCancelled:
+		r.result.Cancelled++
+	}
+	r.finished++
+}
+
+// jobFinished counts the entry in its playlist request and sends the
+// summary once the last one reaches a final state. Entries of a request
+// no tracker holds, such as one queued by another instance, are notified
+// on their own, as their downloader left that to the summary.
+func (ws *WebServer) jobFinished(job domain.Job) {
+	ws.playlists.mu.Lock()
+	request, ok := ws.playlists.jobs[job.ID]
+	if !ok {
+		ws.playlists.mu.Unlock()
+		if job.Video.InPlaylist() {
+			ws.notifyEntry(job)
+		}
+		return
+	}
+	delete(ws.playlists.jobs, job.ID)
+	request.count(job)
+	if request.finished < request.expected {
+		ws.playlists.mu.Unlock()
+		return
+	}
+	result := request.result
+	ws.playlists.mu.Unlock()
+
+	log.Info(fmt.Sprintf("Playlist %s concluída: %d baixados, %d falharam, %d cancelados", request.title, result.Done, result.Failed, result.Cancelled))
+	if err := ws.playlistUC.NotifySummary(request.title, request.requester, result); err != nil {
+		log.Error(fmt.Sprintf("Erro ao notificar resumo da playlist %s: %v", request.id, err))
+	}
+}
+
+// notifyEntry tells the requester how a playlist entry ended, as its
+// downloader would have for a video requested alone.
+func (ws *WebServer) notifyEntry(job domain.Job) {
+	title := job.Video.Title
+	if title == "" {
+		title = job.Video.URL
+	}
+	notification := domain.Notification{Title: title, To: job.Video.Requester}
+	switch job.State {
+	case domain.JobDone:
+		notification.Kind, notification.Message = domain.NotificationDone, job.VideoID
+	case domain.JobFailed:
+		notification.Kind, notification.Message = domain.NotificationFailed, job.Error
+	case domain.JobCancelled:
+		notification.Kind, notification.Message = domain.NotificationCancelled, "Download cancelado"
+	default:
+		return
+	}
+	if err := ws.downloadUC.Downloader.Finalize(notification); err != nil {
+		log.Error(fmt.Sprintf("Erro ao notificar %s da playlist %s: %v", job.ID, job.Video.PlaylistID, err))
+	}
+}
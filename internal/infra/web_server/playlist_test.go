@@ -0,0 +1,188 @@
+package webserver
+
+import (
+	"context"
+	"downloader/internal/domain"
+	memoria "downloader/internal/infra/db/mem_db"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDownloader fails the URLs ending in "fail" and reports every
+// notification on sent.
+type fakeDownloader struct {
+	sent chan domain.Notification
+}
+
+func (d *fakeDownloader) Download(ctx context.Context, video domain.Video, progress domain.ProgressBar) error {
+	if strings.HasSuffix(video.URL, "fail") {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func (d *fakeDownloader) Finalize(notification domain.Notification) error {
+	d.sent <- notification
+	return nil
+}
+
+func (d *fakeDownloader) Cancel(file *os.File) error { return nil }
+
+type fakePlaylists struct{}
+
+func (fakePlaylists) IsPlaylist(url string) bool { return strings.Contains(url, "list=") }
+
+func (fakePlaylists) Fetch(ctx context.Context, url string) (domain.Playlist, error) {
+	return domain.Playlist{ID: "PL", Title: "Playlist", Entries: []domain.PlaylistEntry{
+		{Index: 1, ID: "a", URL: "https://example.com/a"},
+		{Index: 2, ID: "b", URL: "https://example.com/b-fail"},
+		{Index: 3, ID: "c", URL: "https://example.com/c"},
+	}}, nil
+}
+
+func (fakePlaylists) PublishDate(ctx context.Context, entryID string) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func TestPlaylistSummaryPerRequest(t *testing.T) {
+	downloader := &fakeDownloader{sent: make(chan domain.Notification, 10)}
+	ws := NewWebServer(downloader, memoria.NewMemoriaDatabase[domain.Video](), memoria.NewMemoriaDatabase[domain.Job](),
+		fakePlaylists{}, nil, nil, memoria.NewMemoriaDatabase[domain.Subscription]())
+	if err := ws.queue.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer ws.queue.Stop()
+
+	// The same playlist, requested twice and by two requesters, gets one
+	// summary per request even though its entries finish while queuing.
+	for _, requester := range []string{"ana", "ana", "bia"} {
+		r := httptest.NewRequest(http.MethodGet, "/video/download?url=https://example.com/p?list=PL&requester="+requester, nil)
+		w := httptest.NewRecorder()
+		ws.addVideoNaFilaDeDownload(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+	}
+
+	got := map[string]int{}
+	for range 3 {
+		select {
+		case n := <-downloader.sent:
+			if n.Kind != domain.NotificationPlaylist {
+				t.Fatalf("notification = %+v, want a playlist summary", n)
+			}
+			if want := "2 de 3 vídeos baixados, 1 falharam"; n.Message != want {
+				t.Errorf("summary = %q, want %q", n.Message, want)
+			}
+			got[n.To]++
+		case <-time.After(5 * time.Second):
+			t.Fatalf("summaries = %v, want 3", got)
+		}
+	}
+	if fmt.Sprint(got) != "map[ana:2 bia:1]" {
+		t.Errorf("summaries = %v, want 2 for ana and 1 for bia", got)
+	}
+	select {
+	case n := <-downloader.sent:
+		t.Errorf("extra notification %+v", n)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if len(ws.playlists.jobs) != 0 {
+		t.Errorf("%d jobs still tracked", len(ws.playlists.jobs))
+	}
+}
+
+func TestPlaylistSummaryAfterRestart(t *testing.T) {
+	downloader := &fakeDownloader{sent: make(chan domain.Notification, 10)}
+	jobs := memoria.NewMemoriaDatabase[domain.Job]()
+	entry := func(id string, state domain.JobState) domain.Job {
+		return domain.Job{ID: id, State: state, CreatedAt: time.Now(), Video: domain.Video{
+			URL:             "https://example.com/" + id,
+			Requester:       "ana",
+			PlaylistID:      "request",
+			PlaylistTitle:   "Playlist",
+			PlaylistSkipped: 1,
+		}}
+	}
+	// The previous run finished two entries of the request before stopping.
+	for _, job := range []domain.Job{
+		entry("a", domain.JobDone),
+		entry("b-fail", domain.JobFailed),
+		entry("c", domain.JobQueued),
+		entry("d", domain.JobDownloading),
+	} {
+		if err := jobs.Save(job.ID, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ws := NewWebServer(downloader, memoria.NewMemoriaDatabase[domain.Video](), jobs,
+		fakePlaylists{}, nil, nil, memoria.NewMemoriaDatabase[domain.Subscription]())
+	if err := ws.restorePlaylists(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.queue.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer ws.queue.Stop()
+
+	select {
+	case n := <-downloader.sent:
+		want := domain.Notification{Title: "Playlist", Message: "3 de 4 vídeos baixados, 1 falharam, 1 já baixados", To: "ana", Kind: domain.NotificationPlaylist}
+		if n != want {
+			t.Fatalf("notification = %+v, want %+v", n, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no summary for the restored playlist")
+	}
+	select {
+	case n := <-downloader.sent:
+		t.Errorf("extra notification %+v", n)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestUntrackedPlaylistEntryNotified(t *testing.T) {
+	downloader := &fakeDownloader{sent: make(chan domain.Notification, 10)}
+	ws := NewWebServer(downloader, memoria.NewMemoriaDatabase[domain.Video](), memoria.NewMemoriaDatabase[domain.Job](),
+		fakePlaylists{}, nil, nil, memoria.NewMemoriaDatabase[domain.Subscription]())
+
+	tests := []struct {
+		job  domain.Job
+		want domain.Notification
+	}{
+		{
+			job:  domain.Job{ID: "1", State: domain.JobDone, VideoID: "v1", Video: domain.Video{Title: "Vídeo", Requester: "ana", PlaylistID: "other"}},
+			want: domain.Notification{Title: "Vídeo", Message: "v1", To: "ana", Kind: domain.NotificationDone},
+		},
+		{
+			job:  domain.Job{ID: "2", State: domain.JobFailed, Error: "failed", Video: domain.Video{URL: "https://example.com/b", Requester: "bia", PlaylistID: "other"}},
+			want: domain.Notification{Title: "https://example.com/b", Message: "failed", To: "bia", Kind: domain.NotificationFailed},
+		},
+	}
+	for _, tt := range tests {
+		ws.jobFinished(tt.job)
+		select {
+		case n := <-downloader.sent:
+			if n != tt.want {
+				t.Errorf("notification = %+v, want %+v", n, tt.want)
+			}
+		default:
+			t.Errorf("job %s of an untracked playlist not notified", tt.job.ID)
+		}
+	}
+
+	// Videos requested alone were notified by their downloader.
+	ws.jobFinished(domain.Job{ID: "3", State: domain.JobDone, Video: domain.Video{Requester: "ana"}})
+	select {
+	case n := <-downloader.sent:
+		t.Errorf("extra notification %+v", n)
+	default:
+	}
+}
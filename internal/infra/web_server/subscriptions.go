@@ -0,0 +1,198 @@
+package webserver
+
+import (
+	"downloader/internal/domain"
+	"downloader/internal/usecase"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type subscriptionRequest struct {
+	URL            string `json:"url"`
+	Requester      string `json:"requester"`
+	Format         string `json:"format"`
+	AudioOnly      bool   `json:"audio_only"`
+	Interval       int64  `json:"interval_seconds"`
+	TitlePattern   string `json:"title_regex"`
+	MinDuration    int64  `json:"min_duration_seconds"`
+	MaxDuration    int64  `json:"max_duration_seconds"`
+	PublishedAfter string `json:"published_after"`
+}
+
+type subscriptionResponse struct {
+	ID             string     `json:"id"`
+	URL            string     `json:"url"`
+	Requester      string     `json:"requester"`
+	Format         string     `json:"format,omitempty"`
+	AudioOnly      bool       `json:"audio_only"`
+	Interval       int64      `json:"interval_seconds"`
+	TitlePattern   string     `json:"title_regex,omitempty"`
+	MinDuration    int64      `json:"min_duration_seconds,omitempty"`
+	MaxDuration    int64      `json:"max_duration_seconds,omitempty"`
+	PublishedAfter *time.Time `json:"published_after,omitempty"`
+	Seen           int        `json:"seen"`
+	LastChecked    *time.Time `json:"last_checked,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// subscription converts the request body, accepting published_after as
+// RFC 3339 or as a plain date.
+func (req subscriptionRequest) subscription() (domain.Subscription, error) {
+	sub := domain.Subscription{
+		URL:          req.URL,
+		Requester:    req.Requester,
+		Format:       req.Format,
+		AudioOnly:    req.AudioOnly,
+		Interval:     time.Duration(req.Interval) * time.Second,
+		TitlePattern: req.TitlePattern,
+		MinDuration:  time.Duration(req.MinDuration) * time.Second,
+		MaxDuration:  time.Duration(req.MaxDuration) * time.Second,
+	}
+
+	if req.PublishedAfter != "" {
+		date, err := time.Parse(time.RFC3339, req.PublishedAfter)
+		if err != nil {
+			date, err = time.Parse(time.DateOnly, req.PublishedAfter)
+		}
+		if err != nil {
+			return sub, fmt.Errorf("%w: published_after must be a date", usecase.ErrInvalidSubscription)
+		}
+		sub.PublishedAfter = date
+	}
+	return sub, nil
+}
+
+func newSubscriptionResponse(sub domain.Subscription) subscriptionResponse {
+	response := subscriptionResponse{
+		ID:           sub.ID,
+		URL:          sub.URL,
+		Requester:    sub.Requester,
+		Format:       sub.Format,
+		AudioOnly:    sub.AudioOnly,
+		Interval:     int64(sub.Interval.Seconds()),
+		TitlePattern: sub.TitlePattern,
+		MinDuration:  int64(sub.MinDuration.Seconds()),
+		MaxDuration:  int64(sub.MaxDuration.Seconds()),
+		Seen:         len(sub.Seen),
+		LastError:    sub.LastError,
+		CreatedAt:    sub.CreatedAt,
+		UpdatedAt:    sub.UpdatedAt,
+	}
+	if !sub.PublishedAfter.IsZero() {
+		response.PublishedAfter = &sub.PublishedAfter
+	}
+	if !sub.LastChecked.IsZero() {
+		response.LastChecked = &sub.LastChecked
+	}
+	return response
+}
+
+func (ws *WebServer) createSubscription(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	sub, ok := decodeSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := ws.subscriptionUC.Create(sub)
+	if err != nil {
+		writeSubscriptionError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newSubscriptionResponse(sub))
+}
+
+func (ws *WebServer) listSubscriptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	subs, err := ws.subscriptionUC.List(r.URL.Query().Get("requester"))
+	if err != nil {
+		writeSubscriptionError(w, r, err)
+		return
+	}
+
+	response := make([]subscriptionResponse, 0, len(subs))
+	for _, sub := range subs {
+		response = append(response, newSubscriptionResponse(sub))
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (ws *WebServer) getSubscription(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	sub, err := ws.subscriptionUC.Get(mux.Vars(r)["id"])
+	if err != nil {
+		writeSubscriptionError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(newSubscriptionResponse(sub))
+}
+
+func (ws *WebServer) updateSubscription(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	sub, ok := decodeSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := ws.subscriptionUC.Update(mux.Vars(r)["id"], sub)
+	if err != nil {
+		writeSubscriptionError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(newSubscriptionResponse(sub))
+}
+
+func (ws *WebServer) deleteSubscription(w http.ResponseWriter, r *http.Request) {
+	if err := ws.subscriptionUC.Delete(mux.Vars(r)["id"]); err != nil {
+		writeSubscriptionError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkSubscription checks a subscription right away instead of waiting
+// for its interval.
+func (ws *WebServer) checkSubscription(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enqueued, err := ws.subscriptionUC.Check(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeSubscriptionError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]int{"enqueued": enqueued})
+}
+
+func decodeSubscription(w http.ResponseWriter, r *http.Request) (domain.Subscription, bool) {
+	var req subscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return domain.Subscription{}, false
+	}
+
+	sub, err := req.subscription()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return domain.Subscription{}, false
+	}
+	return sub, true
+}
+
+func writeSubscriptionError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrSubscriptionNotFound):
+		http.NotFound(w, r)
+	case errors.Is(err, usecase.ErrInvalidSubscription):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Error(fmt.Sprintf("Erro nas inscrições: %v", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
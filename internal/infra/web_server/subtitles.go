@@ -0,0 +1,73 @@
+package webserver
+
+import (
+	"downloader/internal/domain"
+	"downloader/pkg/config"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gorilla/mux"
+)
+
+var subtitleContentTypes = map[string]string{
+	"srt": "application/x-subrip; charset=utf-8",
+	"vtt": "text/vtt; charset=utf-8",
+}
+
+// subtitle serves a caption track of a downloaded video, as WebVTT or, with
+// ?format=srt, as SRT.
+func (ws *WebServer) subtitle(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	video, err := ws.db.Get(vars["id"])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "vtt"
+	}
+	contentType, ok := subtitleContentTypes[format]
+	if !ok {
+		http.Error(w, "format must be srt or vtt", http.StatusBadRequest)
+		return
+	}
+
+	// Only registered languages are served, which also keeps the path
+	// inside the video directory.
+	var subtitle *domain.Subtitle
+	for i := range video.Subtitles {
+		if video.Subtitles[i].Language == vars["lang"] {
+			subtitle = &video.Subtitles[i]
+		}
+	}
+	if subtitle == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	filename := domain.SubtitleFilename(video.ID, subtitle.Language, format)
+	f, err := os.Open(filepath.Join(config.GetConfig().VideoDir, filename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s.%s"`, video.Filename, subtitle.Language, format))
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, filename, stat.ModTime().UTC(), f)
+}
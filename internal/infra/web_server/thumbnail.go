@@ -0,0 +1,43 @@
+package webserver
+
+import (
+	"downloader/pkg/config"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gorilla/mux"
+)
+
+// thumbnail serves the thumbnail saved with a downloaded video.
+func (ws *WebServer) thumbnail(w http.ResponseWriter, r *http.Request) {
+	video, err := ws.db.Get(mux.Vars(r)["id"])
+	if err != nil || video.Thumbnail == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	// The stored name is only trusted as a name inside the video directory.
+	filename := filepath.Base(video.Thumbnail)
+	f, err := os.Open(filepath.Join(config.GetConfig().VideoDir, filename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if video.ThumbnailType != "" {
+		w.Header().Set("Content-Type", video.ThumbnailType)
+	}
+	http.ServeContent(w, r, filename, stat.ModTime().UTC(), f)
+}
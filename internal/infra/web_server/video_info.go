@@ -0,0 +1,38 @@
+package webserver
+
+import (
+	"downloader/internal/infra/report"
+	"downloader/internal/usecase"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// videoMetadata answers with what is available for the video at ?url=
+// without downloading it, as JSON or, with ?output=table, as text.
+func (ws *WebServer) videoMetadata(w http.ResponseWriter, r *http.Request) {
+	output := r.URL.Query().Get("output")
+	if output != "" && output != "json" && output != "table" {
+		http.Error(w, "output must be json or table", http.StatusBadRequest)
+		return
+	}
+
+	info, err := ws.infoUC.Execute(r.Context(), r.URL.Query().Get("url"))
+	if errors.Is(err, usecase.ErrMissingURL) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("Erro ao buscar informações do vídeo: %v", err))
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if output == "table" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		report.VideoInfo(w, info)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	report.VideoInfoJSON(w, info)
+}
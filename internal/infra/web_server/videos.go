@@ -0,0 +1,255 @@
+package webserver
+
+import (
+	"downloader/internal/domain"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+type videoResponse struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Title       string    `json:"title,omitempty"`
+	Requester   string    `json:"requester"`
+	Format      string    `json:"format,omitempty"`
+	AudioOnly   bool      `json:"audio_only"`
+	Extension   string    `json:"extension,omitempty"`
+	MimeType    string    `json:"mime_type,omitempty"`
+	SourceID    string    `json:"source_id,omitempty"`
+	PlaylistID  string    `json:"playlist_id,omitempty"`
+	ParentID    string    `json:"parent_id,omitempty"`
+	Chapter     int       `json:"chapter_index,omitempty"`
+	Live        bool      `json:"live,omitempty"`
+	DownloadURL string    `json:"download_url"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type videoPage struct {
+	Videos  []videoResponse `json:"videos"`
+	Total   int             `json:"total"`
+	Page    int             `json:"page"`
+	PerPage int             `json:"per_page"`
+}
+
+func newVideoResponse(video domain.Video) videoResponse {
+	return videoResponse{
+		ID:          video.ID,
+		URL:         video.URL,
+		Title:       video.Title,
+		Requester:   video.Requester,
+		Format:      video.Format,
+		AudioOnly:   video.AudioOnly,
+		Extension:   video.Extension,
+		MimeType:    video.MimeType,
+		SourceID:    video.SourceID,
+		PlaylistID:  video.PlaylistID,
+		ParentID:    video.ParentID,
+		Chapter:     video.ChapterIndex,
+		Live:        video.Live,
+		DownloadURL: "/video/" + video.ID,
+		CreatedAt:   video.CreatedAt,
+	}
+}
+
+// listVideos pages through the downloaded videos, newest first. requester
+// matches exactly, since takes a date or RFC 3339 time and q searches the
+// title.
+func (ws *WebServer) listVideos(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	params := r.URL.Query()
+
+	page, err := positiveParam(params.Get("page"), 1)
+	if err != nil {
+		http.Error(w, "invalid page", http.StatusBadRequest)
+		return
+	}
+	perPage, err := positiveParam(params.Get("per_page"), defaultPerPage)
+	if err != nil || perPage > maxPerPage {
+		http.Error(w, fmt.Sprintf("per_page must be between 1 and %d", maxPerPage), http.StatusBadRequest)
+		return
+	}
+
+	query := domain.Query{OrderBy: "CreatedAt", Descending: true, Offset: (page - 1) * perPage, Limit: perPage}
+	if requester := params.Get("requester"); requester != "" {
+		query.Filters = append(query.Filters, domain.Filter{Field: "Requester", Op: domain.OpEqual, Value: requester})
+	}
+	if since := params.Get("since"); since != "" {
+		date, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			date, err = time.Parse(time.DateOnly, since)
+		}
+		if err != nil {
+			http.Error(w, "since must be a date", http.StatusBadRequest)
+			return
+		}
+		query.Filters = append(query.Filters, domain.Filter{Field: "CreatedAt", Op: domain.OpGreaterOrEqual, Value: date})
+	}
+	if q := params.Get("q"); q != "" {
+		query.Filters = append(query.Filters, domain.Filter{Field: "Title", Op: domain.OpContains, Value: q})
+	}
+
+	videos, err := ws.db.List(query)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	total, err := ws.db.Count(query)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	response := videoPage{Videos: make([]videoResponse, 0, len(videos)), Total: total, Page: page, PerPage: perPage}
+	for _, video := range videos {
+		response.Videos = append(response.Videos, newVideoResponse(video))
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func writeQueryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, domain.ErrInvalidQuery) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Error(fmt.Sprintf("Erro ao listar vídeos: %v", err))
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
+
+func positiveParam(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, errors.New("not a positive number")
+	}
+	return n, nil
+}
+
+type videoInfoResponse struct {
+	videoResponse
+	Filename    string              `json:"filename"`
+	Author      string              `json:"author,omitempty"`
+	ChannelID   string              `json:"channel_id,omitempty"`
+	Duration    float64             `json:"duration_seconds"`
+	Description string              `json:"description,omitempty"`
+	PublishDate *time.Time          `json:"publish_date,omitempty"`
+	Views       int                 `json:"views"`
+	Thumbnails  []thumbnailResponse `json:"thumbnails"`
+	Thumbnail   string              `json:"thumbnail_url,omitempty"`
+	Subtitles   []subtitleResponse  `json:"subtitles"`
+	Stream      streamResponse      `json:"stream"`
+	ClipStart   float64             `json:"clip_start_seconds,omitempty"`
+	ClipEnd     float64             `json:"clip_end_seconds,omitempty"`
+	Path        string              `json:"path"`
+	Size        int64               `json:"size"`
+	Checksum    string              `json:"sha256,omitempty"`
+	RequestedAt *time.Time          `json:"requested_at,omitempty"`
+	StartedAt   *time.Time          `json:"started_at,omitempty"`
+	FinishedAt  *time.Time          `json:"finished_at,omitempty"`
+}
+
+type thumbnailResponse struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type subtitleResponse struct {
+	Language      string `json:"language"`
+	Name          string `json:"name,omitempty"`
+	AutoGenerated bool   `json:"auto_generated"`
+	Translated    bool   `json:"translated"`
+	URL           string `json:"url"`
+}
+
+type streamResponse struct {
+	Itag       int    `json:"itag"`
+	AudioItag  int    `json:"audio_itag,omitempty"`
+	Resolution string `json:"resolution,omitempty"`
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
+	FPS        int    `json:"fps,omitempty"`
+	Bitrate    int    `json:"bitrate"`
+	VideoCodec string `json:"video_codec,omitempty"`
+	AudioCodec string `json:"audio_codec,omitempty"`
+}
+
+func newVideoInfoResponse(video domain.Video) videoInfoResponse {
+	metadata, stream := video.Metadata, video.Stream
+	response := videoInfoResponse{
+		videoResponse: newVideoResponse(video),
+		Filename:      video.Filename,
+		Author:        metadata.Author,
+		ChannelID:     metadata.ChannelID,
+		Duration:      metadata.Duration.Seconds(),
+		Description:   metadata.Description,
+		PublishDate:   optionalTime(metadata.PublishDate),
+		Views:         metadata.Views,
+		Thumbnails:    make([]thumbnailResponse, 0, len(metadata.Thumbnails)),
+		Subtitles:     make([]subtitleResponse, 0, len(video.Subtitles)),
+		Stream: streamResponse{
+			Itag:       stream.Itag,
+			AudioItag:  stream.AudioItag,
+			Resolution: stream.Resolution,
+			Width:      stream.Width,
+			Height:     stream.Height,
+			FPS:        stream.FPS,
+			Bitrate:    stream.Bitrate,
+			VideoCodec: stream.VideoCodec,
+			AudioCodec: stream.AudioCodec,
+		},
+		ClipStart:   video.ClipStart.Seconds(),
+		ClipEnd:     video.ClipEnd.Seconds(),
+		Path:        video.Path,
+		Size:        video.Size,
+		Checksum:    video.Checksum,
+		RequestedAt: optionalTime(video.RequestedAt),
+		StartedAt:   optionalTime(video.StartedAt),
+		FinishedAt:  optionalTime(video.FinishedAt),
+	}
+	if video.Thumbnail != "" {
+		response.Thumbnail = "/video/" + video.ID + "/thumbnail"
+	}
+	for _, thumb := range metadata.Thumbnails {
+		response.Thumbnails = append(response.Thumbnails, thumbnailResponse{URL: thumb.URL, Width: thumb.Width, Height: thumb.Height})
+	}
+	for _, sub := range video.Subtitles {
+		response.Subtitles = append(response.Subtitles, subtitleResponse{
+			Language:      sub.Language,
+			Name:          sub.Name,
+			AutoGenerated: sub.AutoGenerated,
+			Translated:    sub.Translated,
+			URL:           "/video/" + video.ID + "/subtitles/" + sub.Language,
+		})
+	}
+	return response
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func (ws *WebServer) videoInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	video, err := ws.db.Get(mux.Vars(r)["id"])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(newVideoInfoResponse(video))
+}
@@ -4,16 +4,20 @@ import (
 	"context"
 	"downloader/internal/domain"
 	"downloader/internal/infra/progress"
+	"downloader/internal/infra/queue"
+	"downloader/internal/infra/scheduler"
 	"downloader/internal/usecase"
 	"downloader/pkg/config"
 	logger "downloader/pkg/log"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"sync"
 	"time"
 
@@ -23,23 +27,90 @@ import (
 var log = logger.GetLogger("web_server")
 
 type WebServer struct {
-	server     *http.Server
-	downloadUC usecase.DownloadVideoUseCase
-	db         domain.Database[domain.Video]
+	server         *http.Server
+	downloadUC     usecase.DownloadVideoUseCase
+	playlistUC     usecase.PlaylistUseCase
+	infoUC         usecase.VideoInfoUseCase
+	subscriptionUC *usecase.SubscriptionUseCase
+	db             domain.Database[domain.Video]
+	jobs           domain.JobStore
+	queue          *queue.Queue
+	broker         *progress.Broker
+	playlists      *playlistTracker
+	scheduler      *scheduler.Scheduler
 }
 
+// subscriptionTick is how often the scheduler looks for due subscriptions.
+const subscriptionTick = time.Minute
+
 type returnHttp struct {
-	Message string `json:"message"`
+	Message    string   `json:"message"`
+	JobID      string   `json:"job_id,omitempty"`
+	PlaylistID string   `json:"playlist_id,omitempty"`
+	JobIDs     []string `json:"job_ids,omitempty"`
 }
 
-func NewWebServer(downloader domain.Downloader, db domain.Database[domain.Video]) *WebServer {
-	return &WebServer{downloadUC: usecase.DownloadVideoUseCase{Downloader: downloader}, db: db}
+func NewWebServer(downloader domain.Downloader, db domain.Database[domain.Video], jobs domain.JobStore, playlists domain.PlaylistFetcher, info domain.VideoInfoFetcher, archive domain.Archive, subscriptions domain.SubscriptionStore) *WebServer {
+	ws := &WebServer{
+		downloadUC: usecase.DownloadVideoUseCase{Downloader: downloader, Archive: archive},
+		playlistUC: usecase.PlaylistUseCase{Fetcher: playlists, Downloader: downloader, Archive: archive},
+		infoUC:     usecase.VideoInfoUseCase{Fetcher: info},
+		db:         db,
+		jobs:       jobs,
+		broker:     progress.NewBroker(),
+		playlists:  newPlaylistTracker(),
+	}
+	ws.queue = queue.NewQueue(jobs, config.GetConfig().Workers, ws.runJob)
+	// Every job that joins the queue has events until it finishes, however
+	// it does: runJob closes them with the outcome of the download, this
+	// with the state of jobs that never ran or whose handler panicked.
+	ws.queue.OnQueued(func(job domain.Job) { ws.broker.Open(job.ID) })
+	ws.queue.OnCancelled(func(job domain.Job) {
+		if err := ws.downloadUC.NotifyCancelled(job.Video); err != nil {
+			log.Error(fmt.Sprintf("Erro ao notificar cancelamento do job %s: %v", job.ID, err))
+		}
+	})
+	ws.queue.OnFinished(func(job domain.Job) {
+		ws.broker.Close(job.ID, finalEvent(job))
+		ws.jobFinished(job)
+	})
+	ws.downloadUC.Queue = ws.queue
+	ws.subscriptionUC = &usecase.SubscriptionUseCase{Store: subscriptions, Fetcher: playlists, Videos: &ws.downloadUC}
+	ws.scheduler = scheduler.NewScheduler(ws.subscriptionUC, subscriptionTick)
+	return ws
 }
 
 func (w *WebServer) Start(port int) {
+	if err := w.restorePlaylists(); err != nil {
+		log.Error(fmt.Sprintf("Erro ao restaurar playlists em andamento: %v", err))
+	}
+	if err := w.queue.Start(); err != nil {
+		log.Error(fmt.Sprintf("Erro ao iniciar fila de downloads: %v", err))
+	}
+	w.scheduler.Start()
+
 	mux := mux.NewRouter()
+	mux.HandleFunc("/health", w.health).Methods("GET")
 	mux.HandleFunc("/video/download", w.addVideoNaFilaDeDownload).Methods("GET")
+	mux.HandleFunc("/video/info", w.videoMetadata).Methods("GET")
 	mux.HandleFunc("/video/{id}", w.download).Methods("GET")
+	mux.HandleFunc("/video/{id}/subtitles/{lang}", w.subtitle).Methods("GET")
+	mux.HandleFunc("/video/{id}/thumbnail", w.thumbnail).Methods("GET")
+	mux.HandleFunc("/video/{id}/chapters", w.listChapters).Methods("GET")
+	mux.HandleFunc("/video/{id}/chapters.zip", w.chapterBundle).Methods("GET")
+	mux.HandleFunc("/video/{id}/chapters/{n}", w.chapter).Methods("GET")
+	mux.HandleFunc("/videos", w.listVideos).Methods("GET")
+	mux.HandleFunc("/videos/{id}/info", w.videoInfo).Methods("GET")
+	mux.HandleFunc("/jobs", w.listJobs).Methods("GET")
+	mux.HandleFunc("/jobs/{id}", w.getJob).Methods("GET")
+	mux.HandleFunc("/jobs/{id}", w.cancelJob).Methods("DELETE")
+	mux.HandleFunc("/jobs/{id}/events", w.jobEvents).Methods("GET")
+	mux.HandleFunc("/subscriptions", w.createSubscription).Methods("POST")
+	mux.HandleFunc("/subscriptions", w.listSubscriptions).Methods("GET")
+	mux.HandleFunc("/subscriptions/{id}", w.getSubscription).Methods("GET")
+	mux.HandleFunc("/subscriptions/{id}", w.updateSubscription).Methods("PUT")
+	mux.HandleFunc("/subscriptions/{id}", w.deleteSubscription).Methods("DELETE")
+	mux.HandleFunc("/subscriptions/{id}/check", w.checkSubscription).Methods("POST")
 
 	w.server = &http.Server{
 		Addr:    fmt.Sprintf(":%d", port),
@@ -63,6 +134,8 @@ func (w *WebServer) Stop() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	_ = w.server.Shutdown(ctx)
+	w.scheduler.Stop()
+	w.queue.Stop()
 	log.Info("server stopped")
 }
 
@@ -79,8 +152,104 @@ func (ws *WebServer) addVideoNaFilaDeDownload(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	go ws.downloadUC.Execute(usecase.Solicitation{URL: url, Requester: requester}, progress.NewTerminalProgressBar())
-	json.NewEncoder(w).Encode(returnHttp{Message: "Download iniciado"})
+	format := r.URL.Query().Get("format")
+	audioOnly, err := parseBoolParam(r.URL.Query().Get("audio"))
+	if err != nil {
+		http.Error(w, "audio parameter must be a boolean", http.StatusBadRequest)
+		return
+	}
+
+	priority, err := parseIntParam(r.URL.Query().Get("priority"))
+	if err != nil {
+		http.Error(w, "priority parameter must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	subtitles, err := usecase.ParseLanguages(r.URL.Query().Get("subtitles"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	autoSubtitles, err := parseBoolParam(r.URL.Query().Get("auto_subtitles"))
+	if err != nil {
+		http.Error(w, "auto_subtitles parameter must be a boolean", http.StatusBadRequest)
+		return
+	}
+
+	embedThumbnail, err := parseBoolParam(r.URL.Query().Get("embed_thumbnail"))
+	if err != nil {
+		http.Error(w, "embed_thumbnail parameter must be a boolean", http.StatusBadRequest)
+		return
+	}
+
+	splitChapters, err := parseBoolParam(r.URL.Query().Get("split_chapters"))
+	if err != nil {
+		http.Error(w, "split_chapters parameter must be a boolean", http.StatusBadRequest)
+		return
+	}
+
+	start, end, err := usecase.ParseClip(r.URL.Query().Get("start"), r.URL.Query().Get("end"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	liveFromStart, err := parseBoolParam(r.URL.Query().Get("live_from_start"))
+	if err != nil {
+		http.Error(w, "live_from_start parameter must be a boolean", http.StatusBadRequest)
+		return
+	}
+	liveMaxDuration, err := usecase.ParseDuration(r.URL.Query().Get("live_max_duration"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sol := usecase.Solicitation{
+		URL:             url,
+		Requester:       requester,
+		Format:          format,
+		AudioOnly:       audioOnly,
+		Subtitles:       subtitles,
+		AutoSubtitles:   autoSubtitles,
+		EmbedThumbnail:  embedThumbnail,
+		Start:           start,
+		End:             end,
+		SplitChapters:   splitChapters,
+		LiveFromStart:   liveFromStart,
+		LiveMaxDuration: liveMaxDuration,
+	}
+	if ws.playlistUC.IsPlaylist(url) {
+		ws.addPlaylistNaFilaDeDownload(w, r, sol, priority)
+		return
+	}
+
+	job, err := ws.downloadUC.Enqueue(r.Context(), sol.Video(), priority)
+	if errors.Is(err, domain.ErrUnsupportedSource) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "could not queue download", http.StatusServiceUnavailable)
+		return
+	}
+	json.NewEncoder(w).Encode(returnHttp{Message: "Download iniciado", JobID: job.ID})
+}
+
+func (ws *WebServer) runJob(ctx context.Context, job domain.Job, tracker domain.ProgressBar) error {
+	bar := progress.NewMultiProgressBar(tracker, progress.NewBrokerProgressBar(ws.broker, job.ID))
+	err := ws.downloadUC.Download(ctx, job.Video, bar)
+
+	final := progress.Event{Type: progress.EventStage, State: string(domain.JobDone)}
+	switch {
+	case err != nil && errors.Is(context.Cause(ctx), domain.ErrDownloadCancelled):
+		final.State = string(domain.JobCancelled)
+	case err != nil:
+		final.State = string(domain.JobFailed)
+		final.Error = err.Error()
+	}
+	ws.broker.Close(job.ID, final)
+	return err
 }
 
 func (ws *WebServer) download(w http.ResponseWriter, r *http.Request) {
@@ -91,9 +260,20 @@ func (ws *WebServer) download(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	ws.serveVideo(w, r, video)
+}
+
+// serveVideo sends the file of a downloaded video, which is removed once it
+// was read to the end.
+func (ws *WebServer) serveVideo(w http.ResponseWriter, r *http.Request, video domain.Video) {
 	videoRoot := config.GetConfig().VideoDir
 
-	filename := id + ".mp4"
+	extension, mimeType := video.Extension, video.MimeType
+	if extension == "" {
+		extension, mimeType = "mp4", "video/mp4"
+	}
+
+	filename := video.ID + "." + extension
 	fullPath := filepath.Join(videoRoot, filename)
 
 	cleanRoot, _ := filepath.Abs(videoRoot)
@@ -120,8 +300,8 @@ func (ws *WebServer) download(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.mp4"`, video.Filename))
-	w.Header().Set("Content-Type", "video/mp4")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, video.Filename, extension))
+	w.Header().Set("Content-Type", mimeType)
 	w.Header().Set("Cache-Control", "private, max-age=86400")
 
 	log.Info(fmt.Sprintf("Download de %s iniciado", video.Filename))
@@ -146,6 +326,20 @@ func (ws *WebServer) download(w http.ResponseWriter, r *http.Request) {
 	http.ServeContent(w, r, filename, stat.ModTime().UTC(), seeker)
 }
 
+func parseBoolParam(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func parseIntParam(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
 type cleanupReadSeeker struct {
 	file    *os.File
 	reader  io.Reader
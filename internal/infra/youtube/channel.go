@@ -0,0 +1,90 @@
+package youtube
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	yt "github.com/kkdai/youtube/v2"
+)
+
+var (
+	channelIDPattern = regexp.MustCompile(`^UC[\w-]{22}$`)
+	canonicalPattern = regexp.MustCompile(`<link rel="canonical" href="https://www\.youtube\.com/channel/(UC[\w-]{22})"`)
+)
+
+// isChannelURL reports whether rawURL points to a channel page, in any of
+// the /channel/ID, /@handle, /c/name or /user/name forms.
+func isChannelURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.HasSuffix(u.Hostname(), "youtube.com") {
+		return false
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch {
+	case strings.HasPrefix(segments[0], "@"):
+		return len(segments[0]) > 1
+	case segments[0] == "channel", segments[0] == "c", segments[0] == "user":
+		return len(segments) > 1 && segments[1] != ""
+	}
+	return false
+}
+
+// uploadsPlaylistURL returns the playlist holding every upload of the
+// channel. Its ID is the channel ID with the "UC" prefix replaced by "UU".
+func uploadsPlaylistURL(ctx context.Context, rawURL string) (string, error) {
+	channelID, err := channelID(ctx, rawURL)
+	if err != nil {
+		return "", err
+	}
+	return "https://www.youtube.com/playlist?list=UU" + channelID[2:], nil
+}
+
+func channelID(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid channel url: %w", err)
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if segments[0] == "channel" && channelIDPattern.MatchString(segments[1]) {
+		return segments[1], nil
+	}
+
+	// Handles and custom names only resolve through the channel page.
+	page := "https://www.youtube.com/" + strings.Join(segments[:min(len(segments), 2)], "/")
+	if strings.HasPrefix(segments[0], "@") {
+		page = "https://www.youtube.com/" + segments[0]
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, page, nil)
+	if err != nil {
+		return "", err
+	}
+	req.AddCookie(&http.Cookie{Name: "CONSENT", Value: "YES+"})
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error fetching channel page: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error fetching channel page: %w", yt.ErrUnexpectedStatusCode(resp.StatusCode))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return "", fmt.Errorf("error reading channel page: %w", err)
+	}
+
+	match := canonicalPattern.FindSubmatch(body)
+	if match == nil {
+		return "", fmt.Errorf("channel id not found in %s", page)
+	}
+	return string(match[1]), nil
+}
@@ -0,0 +1,79 @@
+package youtube
+
+import (
+	"downloader/internal/domain"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A chapter line has its timestamp either before or after the title, e.g.
+// "0:00 Intro", "1:02:03 - Outro" or "Intro (0:00)".
+var (
+	leadingTimestamp  = regexp.MustCompile(`^[-*•]?\s*\(?((?:\d{1,2}:)?\d{1,2}:\d{2})\)?\s*[-–—:|]?\s*(.+)$`)
+	trailingTimestamp = regexp.MustCompile(`^[-*•]?\s*(.+?)\s*[-–—:|]?\s*\(?((?:\d{1,2}:)?\d{1,2}:\d{2})\)?$`)
+)
+
+// minChapters is the fewest chapters YouTube itself accepts.
+const minChapters = 3
+
+// parseChapters reads the chapters listed in a video description. Like
+// YouTube, it only accepts lists that start at 0:00 and go forward, with
+// at least three entries; anything else is taken as loose timestamps.
+func parseChapters(description string, duration time.Duration) []domain.Chapter {
+	var chapters []domain.Chapter
+	for _, line := range strings.Split(description, "\n") {
+		start, title, ok := chapterLine(strings.TrimSpace(line))
+		if !ok {
+			continue
+		}
+		if len(chapters) == 0 && start != 0 {
+			continue
+		}
+		if len(chapters) > 0 && start <= chapters[len(chapters)-1].Start {
+			break
+		}
+		chapters = append(chapters, domain.Chapter{Title: title, Start: start})
+	}
+	if len(chapters) < minChapters {
+		return nil
+	}
+
+	for i := range chapters {
+		if i+1 < len(chapters) {
+			chapters[i].End = chapters[i+1].Start
+		} else if duration > chapters[i].Start {
+			chapters[i].End = duration
+		}
+	}
+	return chapters
+}
+
+func chapterLine(line string) (time.Duration, string, bool) {
+	if m := leadingTimestamp.FindStringSubmatch(line); m != nil {
+		if start, ok := parseTimestamp(m[1]); ok {
+			return start, strings.TrimSpace(m[2]), true
+		}
+	}
+	if m := trailingTimestamp.FindStringSubmatch(line); m != nil {
+		if start, ok := parseTimestamp(m[2]); ok {
+			return start, strings.TrimSpace(m[1]), true
+		}
+	}
+	return 0, "", false
+}
+
+// parseTimestamp parses "M:SS" or "H:MM:SS".
+func parseTimestamp(s string) (time.Duration, bool) {
+	var total time.Duration
+	parts := strings.Split(s, ":")
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || (i > 0 && n >= 60) {
+			return 0, false
+		}
+		total = total*60 + time.Duration(n)
+	}
+	return total * time.Second, true
+}
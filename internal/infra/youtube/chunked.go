@@ -0,0 +1,79 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	yt "github.com/kkdai/youtube/v2"
+)
+
+const (
+	// minChunkSize keeps small streams from being split into many
+	// requests that cost more than they save.
+	minChunkSize = 4 * 1024 * 1024
+
+	chunkAttempts = 3
+	chunkBackoff  = time.Second
+)
+
+// saveChunks fetches the pending chunks of part concurrently, each one
+// writing at its own offset of the preallocated file. A failed chunk is
+// retried on its own, from the last byte it wrote.
+func (d *KkdaiDownloader) saveChunks(ctx context.Context, client *yt.Client, url string, part *partFile, counter io.Writer) error {
+	chunkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pending := part.Pending()
+	errs := make([]error, len(pending))
+	var wg sync.WaitGroup
+	for i, index := range pending {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if errs[i] = fetchChunk(chunkCtx, client, url, part, index, counter); errs[i] != nil {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	var err error
+	for _, chunkErr := range errs {
+		if chunkErr != nil && (err == nil || errors.Is(err, context.Canceled)) {
+			err = chunkErr
+		}
+	}
+	return d.closePart(ctx, part, err)
+}
+
+func fetchChunk(ctx context.Context, client *yt.Client, url string, part *partFile, index int, counter io.Writer) error {
+	var err error
+	for attempt := 1; attempt <= chunkAttempts; attempt++ {
+		offset, end := part.Chunk(index)
+		if offset >= end {
+			return nil
+		}
+
+		stream := newRangeReader(ctx, client, url, offset, end)
+		_, err = io.Copy(part.ChunkWriter(index), io.TeeReader(&contextReader{ctx: ctx, r: stream}, counter))
+		stream.Close()
+		if err == nil || ctx.Err() != nil || errors.Is(err, ErrRangeNotSupported) {
+			return err
+		}
+
+		log.Error(fmt.Sprintf("Erro no trecho %d (tentativa %d de %d): %v", index, attempt, chunkAttempts, err))
+		if attempt == chunkAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(chunkBackoff * time.Duration(attempt)):
+		}
+	}
+	return fmt.Errorf("error fetching chunk %d: %w", index, err)
+}
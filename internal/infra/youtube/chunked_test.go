@@ -0,0 +1,226 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	yt "github.com/kkdai/youtube/v2"
+)
+
+// chunkedStream is a stream big enough to be split in two chunks.
+func chunkedStream() []byte {
+	data := make([]byte, 2*minChunkSize+3)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+// splitPart opens a part of data split in chunks, in a new directory.
+func splitPart(t *testing.T, size int64) (*partFile, string) {
+	t.Helper()
+	dir := t.TempDir()
+	part, err := openPart(filepath.Join(dir, "v.f18.mp4.part"), "v", 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := part.Split(size, 4); err != nil {
+		t.Fatal(err)
+	}
+	return part, dir
+}
+
+func TestSaveChunks(t *testing.T) {
+	data := chunkedStream()
+	server, ranges := streamServer(t, data)
+	part, dir := splitPart(t, int64(len(data)))
+	if n := len(part.manifest.Chunks); n != 2 {
+		t.Fatalf("split in %d chunks, want 2 of at least minChunkSize", n)
+	}
+
+	var counted countingWriter
+	d := &KkdaiDownloader{}
+	if err := d.saveChunks(context.Background(), &yt.Client{}, server.URL, part, &counted); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "v.mp4")
+	if err := part.Finish(target); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(target); !bytes.Equal(got, data) {
+		t.Fatal("chunks merged into a different stream")
+	}
+	if counted.n != int64(len(data)) {
+		t.Fatalf("progress counted %d bytes, want %d", counted.n, len(data))
+	}
+	if got := ranges.all(); len(got) != 2 {
+		t.Fatalf("requested ranges %q, want one per chunk", got)
+	}
+}
+
+func TestPartKeepsChunks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "v.f18.mp4.part")
+	chunks := []partChunk{{0, 10, 4}, {10, 20, 10}}
+	writePart(t, path, make([]byte, 20), partManifest{VideoID: "v", Itag: 18, Size: 20, Chunks: chunks})
+
+	part, err := openPart(path, "v", 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer part.Remove()
+	if err := part.Split(20, 2); err != nil {
+		t.Fatal(err)
+	}
+	if pending := part.Pending(); len(pending) != 1 || pending[0] != 0 {
+		t.Fatalf("pending chunks = %v, want [0]", pending)
+	}
+	if offset, end := part.Chunk(0); offset != 4 || end != 10 {
+		t.Fatalf("chunk 0 continues at %d up to %d, want 4 up to 10", offset, end)
+	}
+}
+
+func TestSaveChunksServerIgnoresRange(t *testing.T) {
+	data := chunkedStream()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer server.Close()
+	part, _ := splitPart(t, int64(len(data)))
+
+	d := &KkdaiDownloader{}
+	err := d.saveChunks(context.Background(), &yt.Client{}, server.URL, part, io.Discard)
+	if !errors.Is(err, ErrRangeNotSupported) {
+		t.Fatalf("saveChunks = %v, want ErrRangeNotSupported", err)
+	}
+	for _, leftover := range []string{part.path, part.manifestPath()} {
+		if _, err := os.Stat(leftover); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s kept although it cannot be resumed: %v", leftover, err)
+		}
+	}
+}
+
+func TestSaveChunksResumesFailedChunk(t *testing.T) {
+	data := chunkedStream()
+	half := int64(len(data) / 2)
+	var mu sync.Mutex
+	var ranges []string
+	failed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		fail := !failed && !strings.HasPrefix(r.Header.Get("Range"), "bytes=0-")
+		if fail {
+			failed = true
+		}
+		mu.Unlock()
+
+		start, end := parseRange(t, r.Header.Get("Range"))
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+		w.WriteHeader(http.StatusPartialContent)
+		if fail {
+			// Cut the connection a thousand bytes into the chunk.
+			w.Write(data[start : start+1000])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		w.Write(data[start : end+1])
+	}))
+	defer server.Close()
+	part, dir := splitPart(t, int64(len(data)))
+
+	d := &KkdaiDownloader{}
+	if err := d.saveChunks(context.Background(), &yt.Client{}, server.URL, part, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "v.mp4")
+	if err := part.Finish(target); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(target); !bytes.Equal(got, data) {
+		t.Fatal("resumed chunk merged into a different stream")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	retry := fmt.Sprintf("bytes=%d-%d", half+1000, len(data)-1)
+	if len(ranges) != 3 || ranges[2] != retry {
+		t.Fatalf("requested ranges %q, want the failed chunk retried with %s", ranges, retry)
+	}
+}
+
+func TestSaveChunksGivesUp(t *testing.T) {
+	data := chunkedStream()
+	var mu sync.Mutex
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Header.Get("Range"), "bytes=0-") {
+			http.ServeContent(w, r, "stream", time.Time{}, bytes.NewReader(data))
+			return
+		}
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	part, _ := splitPart(t, int64(len(data)))
+	defer part.Remove()
+
+	d := &KkdaiDownloader{}
+	err := d.saveChunks(context.Background(), &yt.Client{}, server.URL, part, io.Discard)
+	var status yt.ErrUnexpectedStatusCode
+	if !errors.As(err, &status) || status != http.StatusServiceUnavailable || !Retryable(err) {
+		t.Fatalf("saveChunks = %v, want the retryable status of the last attempt", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != chunkAttempts {
+		t.Fatalf("failed chunk attempted %d times, want %d", attempts, chunkAttempts)
+	}
+
+	// The chunk that succeeded is kept for the next download.
+	m, err := readManifest(part.manifestPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := m.Chunks[0]; c.Written != c.End-c.Start {
+		t.Fatalf("first chunk recorded %d of %d bytes", c.Written, c.End-c.Start)
+	}
+	if pending := part.Pending(); len(pending) != 1 || pending[0] != 1 {
+		t.Fatalf("pending chunks = %v, want [1]", pending)
+	}
+}
+
+// parseRange reads the bounds of a "bytes=start-end" header.
+func parseRange(t *testing.T, header string) (int64, int64) {
+	var start, end int64
+	if _, err := fmt.Sscanf(header, "bytes=%d-%d", &start, &end); err != nil {
+		t.Errorf("unexpected Range %q: %v", header, err)
+	}
+	return start, end
+}
+
+type countingWriter struct {
+	mu sync.Mutex
+	n  int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.n += int64(len(p))
+	return len(p), nil
+}
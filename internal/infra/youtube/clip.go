@@ -0,0 +1,197 @@
+package youtube
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"downloader/internal/infra/download"
+	"downloader/internal/infra/mkv"
+	"downloader/internal/infra/mp4"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	yt "github.com/kkdai/youtube/v2"
+)
+
+var ErrClipUnsupported = errors.New("format cannot be clipped")
+
+// clipSegment is a part of a stream that starts at a keyframe.
+type clipSegment struct {
+	start, end   time.Duration
+	offset, size int64
+}
+
+// clipSource is a stream read through its index, so only the segments
+// covering a clip are fetched.
+type clipSource struct {
+	target   streamTarget
+	url      string
+	segments []clipSegment
+	// write turns the size bytes of the stream fetched from offset into a
+	// file starting at base.
+	write func(w io.Writer, data io.ReaderAt, offset, size int64, base, duration time.Duration) error
+}
+
+// fetchClips downloads the segments of every stream that cover the part of
+// the video between start and end, widened to the keyframes around it, and
+// writes each stream as a file of its own. Clips are small, so unlike
+// fetchStreams they are not resumed.
+func (d *KkdaiDownloader) fetchClips(ctx context.Context, client *yt.Client, ytVideo *yt.Video, streams []streamTarget, start, end time.Duration, progress domain.ProgressBar) error {
+	sources := make([]*clipSource, len(streams))
+	var total int64
+	base := time.Duration(-1)
+	for i, target := range streams {
+		src, err := openClip(ctx, client, ytVideo, target)
+		if err != nil {
+			return err
+		}
+		if src.segments = selectSegments(src.segments, start, end); src.segments == nil {
+			return fmt.Errorf("%w: %s is outside the video", ErrClipUnsupported, clipRange(start, end))
+		}
+		if base < 0 || src.segments[0].start < base {
+			base = src.segments[0].start
+		}
+		for _, s := range src.segments {
+			total += s.size
+		}
+		sources[i] = src
+	}
+
+	log.Info(fmt.Sprintf("Baixando o trecho %s de %s", clipRange(start, end), ytVideo.Title))
+	progress.Start(total)
+	counter := download.NewProgressWriter(progress, 0)
+
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	for i, src := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = d.saveClip(ctx, client, src, base, counter)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			removeStreams(streams)
+			if ctx.Err() != nil {
+				return context.Cause(ctx)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// openClip reads the header and the index of a stream.
+func openClip(ctx context.Context, client *yt.Client, ytVideo *yt.Video, target streamTarget) (*clipSource, error) {
+	format := target.format
+	if format.IndexRange == nil {
+		return nil, fmt.Errorf("%w: itag %d has no index", ErrClipUnsupported, format.ItagNo)
+	}
+	indexEnd, err := strconv.ParseInt(format.IndexRange.End, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: itag %d has an invalid index range", ErrClipUnsupported, format.ItagNo)
+	}
+
+	url, err := client.GetStreamURLContext(ctx, ytVideo, format)
+	if err != nil {
+		return nil, fmt.Errorf("error getting stream url: %w", err)
+	}
+	reader := newRangeReader(ctx, client, url, 0, indexEnd+1)
+	head, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching stream index: %w", err)
+	}
+
+	src := &clipSource{target: target, url: url}
+	switch formatContainer(*format) {
+	case "mp4":
+		idx, err := mp4.ReadIndex(head)
+		if err != nil {
+			return nil, fmt.Errorf("error reading stream index: %w", err)
+		}
+		for _, s := range idx.Segments {
+			src.segments = append(src.segments, clipSegment{start: s.Start, end: s.Start + s.Duration, offset: s.Offset, size: s.Size})
+		}
+		src.write = idx.WriteClip
+	case "webm":
+		if format.ContentLength <= 0 {
+			return nil, fmt.Errorf("%w: itag %d has no size", ErrClipUnsupported, format.ItagNo)
+		}
+		idx, err := mkv.ReadIndex(head, format.ContentLength)
+		if err != nil {
+			return nil, fmt.Errorf("error reading stream index: %w", err)
+		}
+		for _, s := range idx.Segments {
+			src.segments = append(src.segments, clipSegment{start: s.Start, end: s.Start + s.Duration, offset: s.Offset, size: s.Size})
+		}
+		src.write = func(w io.Writer, data io.ReaderAt, _, size int64, base, duration time.Duration) error {
+			return idx.WriteClip(w, data, size, base, duration)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrClipUnsupported, format.MimeType)
+	}
+	return src, nil
+}
+
+// selectSegments keeps the segments that overlap the clip. A zero end runs
+// to the end of the stream. The last segment may have an unknown duration,
+// in which case it is taken to cover any later start.
+func selectSegments(segments []clipSegment, start, end time.Duration) []clipSegment {
+	var selected []clipSegment
+	for i, s := range segments {
+		open := i == len(segments)-1 && s.end == s.start
+		if (s.end > start || (open && s.start <= start)) && (end == 0 || s.start < end) {
+			selected = append(selected, s)
+		}
+	}
+	return selected
+}
+
+// saveClip fetches the selected segments, which are contiguous, and writes
+// them as a file starting at base.
+func (d *KkdaiDownloader) saveClip(ctx context.Context, client *yt.Client, src *clipSource, base time.Duration, counter io.Writer) error {
+	first, last := src.segments[0], src.segments[len(src.segments)-1]
+	from, to := first.offset, last.offset+last.size
+
+	dataPath := src.target.path + ".clip"
+	data, err := os.Create(dataPath)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer os.Remove(dataPath)
+	defer data.Close()
+
+	reader := newRangeReader(ctx, client, src.url, from, to)
+	defer reader.Close()
+	if _, err := io.Copy(data, io.TeeReader(&contextReader{ctx: ctx, r: reader}, counter)); err != nil {
+		return fmt.Errorf("error saving video: %w", err)
+	}
+
+	out, err := os.Create(src.target.path)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	err = src.write(out, data, from, to-from, base, last.end-base)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing clip: %w", err)
+	}
+	return nil
+}
+
+func clipRange(start, end time.Duration) string {
+	if end == 0 {
+		return start.String() + "-"
+	}
+	return start.String() + "-" + end.String()
+}
@@ -1,17 +1,19 @@
 package youtube
 
 import (
+	"context"
 	"downloader/internal/domain"
+	"downloader/internal/infra/download"
+	"downloader/internal/infra/manifest"
+	"downloader/internal/infra/muxer"
 	"downloader/pkg/config"
 	logger "downloader/pkg/log"
 	"downloader/pkg/utils"
+	"errors"
 	"fmt"
-	"io"
 	"os"
-	"os/signal"
 	"path/filepath"
-	"strings"
-	"syscall"
+	"time"
 
 	"github.com/google/uuid"
 	yt "github.com/kkdai/youtube/v2"
@@ -19,104 +21,161 @@ import (
 
 var log = logger.GetLogger("youtube")
 
+var ErrMergeFailed = errors.New("error merging streams")
+
+// Hosts are the hosts of the video URLs KkdaiDownloader handles, with
+// their subdomains.
+var Hosts = []string{"youtube.com", "youtu.be", "youtube-nocookie.com"}
+
 type KkdaiDownloader struct {
 	notifyer domain.Notifyer
 	db       domain.Database[domain.Video]
+	muxer    domain.Muxer
+	live     *manifest.ManifestDownloader
 }
 
 func NewKkdaiDownloader(notifyer domain.Notifyer, db domain.Database[domain.Video]) *KkdaiDownloader {
-	return &KkdaiDownloader{notifyer: notifyer, db: db}
+	return &KkdaiDownloader{
+		notifyer: notifyer,
+		db:       db,
+		muxer:    muxer.NewMuxer(config.GetConfig().FFmpegPath),
+		live:     manifest.NewManifestDownloader(notifyer, db),
+	}
 }
 
-func (d *KkdaiDownloader) Download(video domain.Video, progress domain.ProgressBar) error {
+func (d *KkdaiDownloader) Download(ctx context.Context, video domain.Video, progress domain.ProgressBar) error {
 	client := yt.Client{}
 	cfg := config.GetConfig()
 
-	ytVideo, err := client.GetVideo(video.URL)
+	domain.ReportStage(progress, domain.JobFetchingMetadata)
+	ytVideo, err := client.GetVideoContext(ctx, video.URL)
 	if err != nil {
+		if ctx.Err() != nil {
+			return download.Stopped(ctx, d, video, video.URL)
+		}
 		return fmt.Errorf("error fetching video info: %w", err)
 	}
 
-	format := &ytVideo.Formats[0]
+	video.Title = ytVideo.Title
+	video.SourceID = ytVideo.ID
+	video.Metadata = videoMetadata(ytVideo)
+	if isLive(ytVideo) {
+		return d.recordLive(ctx, ytVideo, video, progress)
+	}
 
-	stream, size, err := client.GetStream(ytVideo, format)
+	selector, err := parseFormatSelector(video.Format)
 	if err != nil {
-		return fmt.Errorf("error getting video stream: %w", err)
+		return err
 	}
 
-	id := uuid.NewString()
-	fileName := utils.SanitizeFilename(id + "." + strings.Split(format.MimeType, ";")[0][6:])
-
-	outFile, err := os.OpenFile(filepath.Join(cfg.VideoDir, fileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o777)
+	var format, audioFormat *yt.Format
+	if video.AudioOnly {
+		format, err = selector.SelectAudio(ytVideo.Formats)
+	} else {
+		format, audioFormat, err = selector.SelectStreams(ytVideo.Formats)
+	}
 	if err != nil {
-		return fmt.Errorf("error creating file: %w", err)
+		return err
 	}
-	d.configCancelSignal(*outFile)
-	defer outFile.Close()
 
-	log.Info(fmt.Sprintf("Download do vídeo %s iniciado!", ytVideo.Title))
-	progress.Start(size)
+	id := video.ID
+	if id == "" {
+		id = uuid.NewString()
+	}
+	video.ID = id
+	video.Extension = formatExtension(*format)
+	video.MimeType = formatMimeType(*format)
+	video.Stream = streamInfo(format, audioFormat)
+	streams := []streamTarget{{format: format}}
+	if audioFormat != nil {
+		video.Extension, video.MimeType = mergedContainer(*format)
+		streams = []streamTarget{
+			{format: format, path: filepath.Join(cfg.VideoDir, tempStreamName(id, *format))},
+			{format: audioFormat, path: filepath.Join(cfg.VideoDir, tempStreamName(id, *audioFormat))},
+		}
+		log.Info(fmt.Sprintf("Formatos selecionados: itag %d (%s) + itag %d (%s)", format.ItagNo, format.MimeType, audioFormat.ItagNo, audioFormat.MimeType))
+	} else {
+		log.Info(fmt.Sprintf("Formato selecionado: itag %d (%s)", format.ItagNo, format.MimeType))
+	}
 
-	proxyReader := io.TeeReader(stream, &progressWriter{progress: progress})
+	fileName := utils.SanitizeFilename(id + "." + video.Extension)
+	outputPath := filepath.Join(cfg.VideoDir, fileName)
+	if audioFormat == nil {
+		streams[0].path = outputPath
+	}
 
-	_, err = io.Copy(outFile, proxyReader)
+	log.Info(fmt.Sprintf("Download do vídeo %s iniciado!", ytVideo.Title))
+	domain.ReportStage(progress, domain.JobDownloading)
+	if video.Clipped() {
+		err = d.fetchClips(ctx, &client, ytVideo, streams, video.ClipStart, video.ClipEnd, progress)
+	} else {
+		err = d.fetchStreams(ctx, &client, ytVideo, streams, progress)
+	}
 	if err != nil {
-		return fmt.Errorf("error saving video: %w", err)
+		if ctx.Err() != nil {
+			return download.Stopped(ctx, d, video, ytVideo.Title)
+		}
+		return err
+	}
+
+	if audioFormat != nil {
+		domain.ReportStage(progress, domain.JobPostProcessing)
+		log.Info(fmt.Sprintf("Unindo áudio e vídeo de %s", ytVideo.Title))
+		err := d.muxer.Merge(streams[0].path, streams[1].path, outputPath)
+		removeStreams(streams)
+		if err != nil {
+			os.Remove(outputPath)
+			return fmt.Errorf("%w: %w", ErrMergeFailed, err)
+		}
+		if ctx.Err() != nil {
+			os.Remove(outputPath)
+			return download.Stopped(ctx, d, video, ytVideo.Title)
+		}
 	}
 
 	progress.Finish()
+	if len(video.SubtitleLanguages) > 0 {
+		video.Subtitles = downloadSubtitles(ctx, ytVideo, video, cfg.VideoDir)
+	}
 	video.Filename = utils.SanitizeFilename(ytVideo.Title)
-	if d.db != nil {
-		d.db.Save(id, video)
+	video.Path = outputPath
+	thumbnail := saveThumbnail(ctx, ytVideo, &video, cfg.VideoDir)
+	writeTags(video, thumbnail)
+	if video.Size, video.Checksum, err = download.FileChecksum(outputPath); err != nil {
+		log.Error(fmt.Sprintf("Erro ao calcular checksum de %s: %v", outputPath, err))
+	}
+	video.FinishedAt = time.Now()
+	video.CreatedAt = video.FinishedAt
+	if err := download.Save(d.db, video); err != nil {
+		return err
 	}
-	if d.notifyer != nil {
+	if video.SplitChapters {
+		d.splitChapters(ctx, video, thumbnail)
+	}
+	if d.notifyer != nil && !video.InPlaylist() {
 		d.Finalize(domain.Notification{
 			Title:   ytVideo.Title,
 			Message: id,
 			To:      video.Requester,
+			Kind:    domain.NotificationDone,
 		})
 	}
 	return nil
 }
 
-func (d *KkdaiDownloader) Finalize(notification domain.Notification) error {
-	if err := d.notifyer.Notify(notification); err != nil {
-		return fmt.Errorf("erro to notify user: %w", err)
-	}
-
-	return nil
-}
-
-func (d *KkdaiDownloader) Cancel(file os.File) error {
-	if err := file.Close(); err != nil {
-		return fmt.Errorf("error closing file: %w", err)
-	}
-	if err := os.Remove(file.Name()); err != nil {
-		return fmt.Errorf("error deleting file: %w", err)
+// SourceID returns the YouTube ID of the video at url.
+func (d *KkdaiDownloader) SourceID(url string) (string, error) {
+	id, err := yt.ExtractVideoID(url)
+	if err != nil {
+		return "", fmt.Errorf("error extracting video ID: %w", err)
 	}
-
-	return nil
+	return id, nil
 }
 
-func (d *KkdaiDownloader) configCancelSignal(file os.File) {
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-
-	go func() {
-		<-sigChan
-		d.Cancel(file)
-	}()
-}
-
-type progressWriter struct {
-	total    int64
-	current  int64
-	progress domain.ProgressBar
+func (d *KkdaiDownloader) Finalize(notification domain.Notification) error {
+	return download.Finalize(d.notifyer, notification)
 }
 
-func (pw *progressWriter) Write(p []byte) (int, error) {
-	n := len(p)
-	pw.current += int64(n)
-	pw.progress.Update(pw.current)
-	return n, nil
+func (d *KkdaiDownloader) Cancel(file *os.File) error {
+	return download.Cancel(file)
 }
@@ -0,0 +1,67 @@
+package youtube
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"downloader/internal/infra/download"
+	"downloader/internal/infra/hls"
+	"downloader/internal/infra/manifest"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+
+	yt "github.com/kkdai/youtube/v2"
+)
+
+// Retryable reports whether a download that failed with err may succeed
+// when attempted again. Network failures, server errors and the 403 and
+// 429 answers YouTube uses for throttling are transient. Videos that are
+// private, removed or age-gated, and requests that can never be served,
+// are not, and neither are failures to merge the downloaded streams or
+// to fetch a segment of a live recording.
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var statusCode yt.ErrUnexpectedStatusCode
+	var playability *yt.ErrPlayabiltyStatus
+	var netErr net.Error
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, domain.ErrDownloadCancelled),
+		errors.Is(err, download.ErrSaveFailed):
+		return false
+	case errors.Is(err, yt.ErrVideoPrivate),
+		errors.Is(err, yt.ErrLoginRequired),
+		errors.Is(err, yt.ErrNotPlayableInEmbed),
+		errors.Is(err, yt.ErrInvalidCharactersInVideoID),
+		errors.Is(err, yt.ErrVideoIDMinLength),
+		errors.As(err, &playability):
+		return false
+	case errors.Is(err, ErrNoMatchingFormat),
+		errors.Is(err, ErrInvalidFormatSelector),
+		errors.Is(err, ErrMergeFailed):
+		return false
+	case errors.Is(err, manifest.ErrSegmentFailed),
+		errors.Is(err, manifest.ErrMergeFailed),
+		errors.Is(err, hls.ErrInvalidPlaylist):
+		// Live recordings already retry every segment.
+		return false
+	case errors.As(err, &statusCode):
+		code := int(statusCode)
+		return code >= 500 || code == 403 || code == 408 || code == 429
+	case errors.As(err, &netErr),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, ErrSizeMismatch),
+		errors.Is(err, ErrRangeNotSupported):
+		return true
+	case errors.As(err, &pathErr):
+		// Local file system errors do not go away by downloading again.
+		return false
+	}
+	return true
+}
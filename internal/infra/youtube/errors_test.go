@@ -0,0 +1,64 @@
+package youtube
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"downloader/internal/infra/download"
+	"downloader/internal/infra/manifest"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	yt "github.com/kkdai/youtube/v2"
+)
+
+func TestRetryable(t *testing.T) {
+	status := func(code int) error {
+		return fmt.Errorf("error downloading stream: %w", yt.ErrUnexpectedStatusCode(code))
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"no error", nil, false},
+		{"unknown error", errors.New("boom"), true},
+		{"server error", status(500), true},
+		{"bad gateway", status(502), true},
+		{"throttled with 403", status(403), true},
+		{"request timeout", status(408), true},
+		{"too many requests", status(429), true},
+		{"not found", status(404), false},
+		{"gone", status(410), false},
+		{"bad request", status(400), false},
+		{"context canceled", fmt.Errorf("error fetching: %w", context.Canceled), false},
+		{"deadline exceeded", context.DeadlineExceeded, false},
+		{"download cancelled", domain.ErrDownloadCancelled, false},
+		{"not saved", fmt.Errorf("%w v: disk full", download.ErrSaveFailed), false},
+		{"private video", yt.ErrVideoPrivate, false},
+		{"login required", yt.ErrLoginRequired, false},
+		{"unplayable", fmt.Errorf("error getting video: %w", &yt.ErrPlayabiltyStatus{Status: "UNPLAYABLE"}), false},
+		{"no matching format", ErrNoMatchingFormat, false},
+		{"merge failed", ErrMergeFailed, false},
+		{"live segment failed", manifest.ErrSegmentFailed, false},
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{"dns failure", &net.DNSError{Err: "no such host", Name: "rr1.googlevideo.com"}, true},
+		{"connection reset", fmt.Errorf("error reading: %w", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}), true},
+		{"cut short", io.ErrUnexpectedEOF, true},
+		{"size mismatch", ErrSizeMismatch, true},
+		{"range ignored", ErrRangeNotSupported, true},
+		{"file system", &fs.PathError{Op: "open", Path: "/videos/v.mp4", Err: os.ErrPermission}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Retryable(tt.err); got != tt.want {
+				t.Fatalf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
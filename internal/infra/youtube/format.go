@@ -0,0 +1,339 @@
+package youtube
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	yt "github.com/kkdai/youtube/v2"
+)
+
+var (
+	ErrNoMatchingFormat      = errors.New("no format matches the selector")
+	ErrInvalidFormatSelector = errors.New("invalid format selector")
+)
+
+// codecAliases maps the names accepted by the selector to the
+// identifiers YouTube uses inside the format mime type.
+var codecAliases = map[string][]string{
+	"av1":  {"av01"},
+	"vp9":  {"vp9", "vp09"},
+	"h264": {"avc1"},
+	"avc":  {"avc1"},
+	"opus": {"opus"},
+	"aac":  {"mp4a"},
+}
+
+// formatSelector is the parsed form of selectors such as
+// "best,height<=720,mp4,prefer=av1/vp9/h264" or "itag=22".
+type formatSelector struct {
+	raw     string
+	worst   bool
+	muxed   bool
+	itag    int
+	filters []func(yt.Format) bool
+	prefer  [][]string
+}
+
+func parseFormatSelector(selector string) (*formatSelector, error) {
+	sel := &formatSelector{raw: selector}
+
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.ToLower(strings.TrimSpace(term))
+		if term == "" {
+			continue
+		}
+
+		if err := sel.parseTerm(term); err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidFormatSelector, selector, err)
+		}
+	}
+
+	return sel, nil
+}
+
+func (s *formatSelector) parseTerm(term string) error {
+	switch term {
+	case "best":
+		s.worst = false
+		return nil
+	case "worst":
+		s.worst = true
+		return nil
+	case "muxed":
+		s.muxed = true
+		return nil
+	case "mp4", "webm":
+		s.filters = append(s.filters, func(f yt.Format) bool {
+			return formatContainer(f) == term
+		})
+		return nil
+	}
+
+	if strings.HasPrefix(term, "height") {
+		return s.parseHeight(strings.TrimPrefix(term, "height"))
+	}
+
+	key, value, ok := strings.Cut(term, "=")
+	if !ok {
+		return fmt.Errorf("unknown term %q", term)
+	}
+
+	switch key {
+	case "itag":
+		itag, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid itag %q", value)
+		}
+		s.itag = itag
+	case "container", "ext":
+		s.filters = append(s.filters, func(f yt.Format) bool {
+			return formatContainer(f) == value
+		})
+	case "codec":
+		ids, ok := codecAliases[value]
+		if !ok {
+			return fmt.Errorf("unknown codec %q", value)
+		}
+		s.filters = append(s.filters, func(f yt.Format) bool {
+			return formatHasCodec(f, ids)
+		})
+	case "prefer":
+		for _, name := range strings.Split(value, "/") {
+			ids, ok := codecAliases[name]
+			if !ok {
+				return fmt.Errorf("unknown codec %q", name)
+			}
+			s.prefer = append(s.prefer, ids)
+		}
+	default:
+		return fmt.Errorf("unknown term %q", term)
+	}
+
+	return nil
+}
+
+func (s *formatSelector) parseHeight(expr string) error {
+	var op string
+	for _, candidate := range []string{"<=", ">=", "<", ">", "="} {
+		if strings.HasPrefix(expr, candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return fmt.Errorf("invalid height expression %q", "height"+expr)
+	}
+
+	height, err := strconv.Atoi(strings.TrimPrefix(expr, op))
+	if err != nil {
+		return fmt.Errorf("invalid height %q", strings.TrimPrefix(expr, op))
+	}
+
+	compare := map[string]func(int) bool{
+		"<=": func(h int) bool { return h <= height },
+		">=": func(h int) bool { return h >= height },
+		"<":  func(h int) bool { return h < height },
+		">":  func(h int) bool { return h > height },
+		"=":  func(h int) bool { return h == height },
+	}[op]
+
+	s.filters = append(s.filters, func(f yt.Format) bool {
+		return compare(f.Height)
+	})
+	return nil
+}
+
+// Select returns the muxed format of the list that best satisfies the
+// selector. Without an explicit itag only formats carrying both video and
+// audio are considered, so the result is always playable on its own.
+func (s *formatSelector) Select(formats yt.FormatList) (*yt.Format, error) {
+	candidates := formats.Select(func(f yt.Format) bool {
+		if s.itag != 0 {
+			return f.ItagNo == s.itag
+		}
+		return f.Width > 0 && f.AudioChannels > 0
+	})
+	candidates = s.filter(candidates)
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w %q (available: %s)", ErrNoMatchingFormat, s.raw, describeFormats(formats))
+	}
+
+	s.sort(candidates)
+	return &candidates[0], nil
+}
+
+// SelectStreams returns the video format to download and, when the best
+// match only exists as a video-only adaptive stream, the audio-only format
+// in the same container to merge with it. audio is nil when a muxed format
+// is at least as good, or when the selector pins an itag or asks for
+// "muxed".
+func (s *formatSelector) SelectStreams(formats yt.FormatList) (video *yt.Format, audio *yt.Format, err error) {
+	if s.itag != 0 || s.muxed {
+		video, err = s.Select(formats)
+		return video, nil, err
+	}
+
+	muxed, err := s.Select(formats)
+	adaptive, adaptiveAudio := s.selectAdaptive(formats)
+	if adaptive == nil {
+		return muxed, nil, err
+	}
+
+	if muxed != nil {
+		if s.worst && muxed.Height <= adaptive.Height || !s.worst && muxed.Height >= adaptive.Height {
+			return muxed, nil, nil
+		}
+	}
+	return adaptive, adaptiveAudio, nil
+}
+
+// selectAdaptive picks the best video-only format that has an audio-only
+// companion in the same container.
+func (s *formatSelector) selectAdaptive(formats yt.FormatList) (*yt.Format, *yt.Format) {
+	videos := s.filter(formats.Select(func(f yt.Format) bool {
+		return f.Width > 0 && f.AudioChannels == 0
+	}))
+	s.sort(videos)
+
+	audios := formats.Select(func(f yt.Format) bool {
+		return f.Width == 0 && f.AudioChannels > 0
+	})
+	s.sort(audios)
+
+	for i := range videos {
+		for j := range audios {
+			if formatContainer(audios[j]) == formatContainer(videos[i]) {
+				return &videos[i], &audios[j]
+			}
+		}
+	}
+	return nil, nil
+}
+
+// SelectAudio returns the audio-only adaptive format of the list that best
+// satisfies the selector.
+func (s *formatSelector) SelectAudio(formats yt.FormatList) (*yt.Format, error) {
+	candidates := formats.Select(func(f yt.Format) bool {
+		if s.itag != 0 {
+			return f.ItagNo == s.itag
+		}
+		return f.Width == 0 && f.AudioChannels > 0
+	})
+	candidates = s.filter(candidates)
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w %q in audio-only mode (available: %s)", ErrNoMatchingFormat, s.raw, describeFormats(formats))
+	}
+
+	s.sort(candidates)
+	return &candidates[0], nil
+}
+
+func (s *formatSelector) filter(formats yt.FormatList) yt.FormatList {
+	return formats.Select(func(f yt.Format) bool {
+		for _, match := range s.filters {
+			if !match(f) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (s *formatSelector) sort(formats yt.FormatList) {
+	slices.SortStableFunc(formats, func(a, b yt.Format) int {
+		if diff := s.codecRank(a) - s.codecRank(b); diff != 0 {
+			return diff
+		}
+
+		diff := compareQuality(b, a)
+		if s.worst {
+			diff = -diff
+		}
+		return diff
+	})
+}
+
+// codecRank gives the position of the format codec in the preference
+// list; formats without a preferred codec go last.
+func (s *formatSelector) codecRank(f yt.Format) int {
+	for i, ids := range s.prefer {
+		if formatHasCodec(f, ids) {
+			return i
+		}
+	}
+	return len(s.prefer)
+}
+
+func compareQuality(a, b yt.Format) int {
+	if a.Height != b.Height {
+		return a.Height - b.Height
+	}
+	if a.FPS != b.FPS {
+		return a.FPS - b.FPS
+	}
+	if a.AudioChannels != b.AudioChannels {
+		return a.AudioChannels - b.AudioChannels
+	}
+	return a.Bitrate - b.Bitrate
+}
+
+// formatContainer returns the subtype of the mime type, e.g. "mp4" for
+// `video/mp4; codecs="avc1.42001E, mp4a.40.2"`.
+func formatContainer(f yt.Format) string {
+	_, subtype, _ := strings.Cut(formatMimeType(f), "/")
+	return subtype
+}
+
+// formatMimeType returns the mime type without the codecs parameter.
+func formatMimeType(f yt.Format) string {
+	mime, _, _ := strings.Cut(f.MimeType, ";")
+	return strings.TrimSpace(mime)
+}
+
+// mergedContainer returns the extension and mime type of the file produced
+// by merging a video stream in the given format with its audio stream.
+func mergedContainer(video yt.Format) (string, string) {
+	if formatContainer(video) == "webm" {
+		return "mkv", "video/x-matroska"
+	}
+	return "mp4", "video/mp4"
+}
+
+// formatExtension returns the file extension used to store the format.
+func formatExtension(f yt.Format) string {
+	switch formatMimeType(f) {
+	case "audio/mp4":
+		return "m4a"
+	case "video/3gpp":
+		return "3gp"
+	default:
+		return formatContainer(f)
+	}
+}
+
+func formatHasCodec(f yt.Format, ids []string) bool {
+	_, codecs, _ := strings.Cut(f.MimeType, ";")
+	for _, id := range ids {
+		if strings.Contains(codecs, id) {
+			return true
+		}
+	}
+	return false
+}
+
+func describeFormats(formats yt.FormatList) string {
+	descriptions := make([]string, 0, len(formats))
+	for _, f := range formats {
+		label := f.QualityLabel
+		if label == "" {
+			label = f.AudioQuality
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%d/%s/%s", f.ItagNo, formatContainer(f), label))
+	}
+	return strings.Join(descriptions, ", ")
+}
@@ -0,0 +1,143 @@
+package youtube
+
+import (
+	"errors"
+	"testing"
+
+	yt "github.com/kkdai/youtube/v2"
+)
+
+var testFormats = yt.FormatList{
+	{ItagNo: 18, MimeType: `video/mp4; codecs="avc1.42001E, mp4a.40.2"`, Width: 640, Height: 360, AudioChannels: 2},
+	{ItagNo: 22, MimeType: `video/mp4; codecs="avc1.64001F, mp4a.40.2"`, Width: 1280, Height: 720, AudioChannels: 2},
+	{ItagNo: 43, MimeType: `video/webm; codecs="vp8.0, vorbis"`, Width: 640, Height: 360, AudioChannels: 2},
+	{ItagNo: 137, MimeType: `video/mp4; codecs="avc1.640028"`, Width: 1920, Height: 1080},
+	{ItagNo: 248, MimeType: `video/webm; codecs="vp9"`, Width: 1920, Height: 1080},
+	{ItagNo: 399, MimeType: `video/mp4; codecs="av01.0.08M.08"`, Width: 1920, Height: 1080},
+	{ItagNo: 140, MimeType: `audio/mp4; codecs="mp4a.40.2"`, AudioChannels: 2, Bitrate: 128000},
+	{ItagNo: 251, MimeType: `audio/webm; codecs="opus"`, AudioChannels: 2, Bitrate: 160000},
+}
+
+func TestParseFormatSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		valid    bool
+	}{
+		{"", true},
+		{"best", true},
+		{" Worst , MP4 ", true},
+		{"best,height<=720,mp4,prefer=av1/vp9/h264", true},
+		{"height>=480,height<1080,height>360,height=720", true},
+		{"itag=22", true},
+		{"container=webm,codec=opus", true},
+		{"ext=mp4,muxed", true},
+		{"height", false},
+		{"height<=hd", false},
+		{"height~720", false},
+		{"itag=x", false},
+		{"codec=mpeg2", false},
+		{"prefer=av1/theora", false},
+		{"fast", false},
+		{"size=10", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			_, err := parseFormatSelector(tt.selector)
+			if tt.valid && err != nil {
+				t.Fatalf("parseFormatSelector(%q) = %v", tt.selector, err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidFormatSelector) {
+				t.Fatalf("parseFormatSelector(%q) = %v, want ErrInvalidFormatSelector", tt.selector, err)
+			}
+		})
+	}
+}
+
+func TestFormatSelectorSelect(t *testing.T) {
+	tests := []struct {
+		selector string
+		video    int
+		audio    int
+		err      error
+	}{
+		{selector: "best", video: 137, audio: 140},
+		{selector: "prefer=av1", video: 399, audio: 140},
+		{selector: "best,muxed", video: 22},
+		{selector: "worst", video: 18},
+		{selector: "height<=720", video: 22},
+		{selector: "height=360,webm", video: 43},
+		{selector: "webm", video: 248, audio: 251},
+		{selector: "prefer=vp9/h264", video: 248, audio: 251},
+		{selector: "codec=h264", video: 137, audio: 140},
+		{selector: "itag=137", video: 137},
+		{selector: "height>1080", err: ErrNoMatchingFormat},
+		{selector: "itag=5", err: ErrNoMatchingFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			sel, err := parseFormatSelector(tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			video, audio, err := sel.SelectStreams(testFormats)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("SelectStreams = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if video.ItagNo != tt.video {
+				t.Errorf("video itag = %d, want %d", video.ItagNo, tt.video)
+			}
+			if got := itagOf(audio); got != tt.audio {
+				t.Errorf("audio itag = %d, want %d", got, tt.audio)
+			}
+		})
+	}
+}
+
+func TestFormatSelectorSelectAudio(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     int
+		err      error
+	}{
+		{selector: "best", want: 251},
+		{selector: "worst", want: 140},
+		{selector: "mp4", want: 140},
+		{selector: "codec=opus", want: 251},
+		{selector: "itag=140", want: 140},
+		{selector: "codec=av1", err: ErrNoMatchingFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			sel, err := parseFormatSelector(tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			audio, err := sel.SelectAudio(testFormats)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("SelectAudio = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if audio.ItagNo != tt.want {
+				t.Errorf("audio itag = %d, want %d", audio.ItagNo, tt.want)
+			}
+		})
+	}
+}
+
+func itagOf(f *yt.Format) int {
+	if f == nil {
+		return 0
+	}
+	return f.ItagNo
+}
@@ -0,0 +1,63 @@
+package youtube
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"fmt"
+	"strconv"
+
+	yt "github.com/kkdai/youtube/v2"
+)
+
+type KkdaiInfoFetcher struct{}
+
+func NewKkdaiInfoFetcher() *KkdaiInfoFetcher {
+	return &KkdaiInfoFetcher{}
+}
+
+// Info fetches the metadata and the format list of a video without
+// downloading any of its streams.
+func (f *KkdaiInfoFetcher) Info(ctx context.Context, url string) (domain.VideoInfo, error) {
+	client := yt.Client{}
+	ytVideo, err := client.GetVideoContext(ctx, url)
+	if err != nil {
+		return domain.VideoInfo{}, fmt.Errorf("error fetching video info: %w", err)
+	}
+
+	info := domain.VideoInfo{
+		ID:       ytVideo.ID,
+		URL:      watchURL + ytVideo.ID,
+		Title:    ytVideo.Title,
+		Metadata: videoMetadata(ytVideo),
+		Formats:  make([]domain.FormatInfo, 0, len(ytVideo.Formats)),
+	}
+	for _, track := range ytVideo.CaptionTracks {
+		info.Captions = append(info.Captions, domain.Caption{
+			LanguageCode:  track.LanguageCode,
+			Name:          track.Name.SimpleText,
+			AutoGenerated: track.Kind == "asr",
+		})
+	}
+	for _, format := range ytVideo.Formats {
+		info.Formats = append(info.Formats, formatInfo(format))
+	}
+	return info, nil
+}
+
+func formatInfo(f yt.Format) domain.FormatInfo {
+	sampleRate, _ := strconv.Atoi(f.AudioSampleRate)
+	return domain.FormatInfo{
+		Itag:            f.ItagNo,
+		Container:       formatExtension(f),
+		MimeType:        formatMimeType(f),
+		Codecs:          formatCodecs(f),
+		Resolution:      f.QualityLabel,
+		Width:           f.Width,
+		Height:          f.Height,
+		FPS:             f.FPS,
+		Bitrate:         f.Bitrate,
+		Size:            f.ContentLength,
+		AudioChannels:   f.AudioChannels,
+		AudioSampleRate: sampleRate,
+	}
+}
@@ -0,0 +1,40 @@
+package youtube
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"downloader/pkg/config"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	yt "github.com/kkdai/youtube/v2"
+)
+
+// isLive reports whether ytVideo is a livestream still going on, which has
+// no length yet and is served through its HLS playlist.
+func isLive(ytVideo *yt.Video) bool {
+	return ytVideo.HLSManifestURL != "" && ytVideo.Duration == 0
+}
+
+// recordLive records the livestream from its HLS playlist until it ends,
+// video.LiveMaxDuration is reached or the requester cancels, keeping what
+// was recorded. The thumbnail is saved first, since the recording is
+// stored as soon as it stops.
+func (d *KkdaiDownloader) recordLive(ctx context.Context, ytVideo *yt.Video, video domain.Video, progress domain.ProgressBar) error {
+	if video.ID == "" {
+		video.ID = uuid.NewString()
+	}
+	if video.Clipped() || video.SplitChapters || video.EmbedThumbnail {
+		log.Info(fmt.Sprintf("Trecho, capítulos e capa ignorados na gravação de %s", ytVideo.Title))
+	}
+
+	dir := config.GetConfig().VideoDir
+	saveThumbnail(ctx, ytVideo, &video, dir)
+	err := d.live.Record(ctx, video, ytVideo.HLSManifestURL, progress)
+	if err != nil && video.Thumbnail != "" {
+		os.Remove(filepath.Join(dir, video.Thumbnail))
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package youtube
+
+import (
+	"downloader/internal/domain"
+	"mime"
+	"strings"
+
+	yt "github.com/kkdai/youtube/v2"
+)
+
+func videoMetadata(v *yt.Video) domain.VideoMetadata {
+	metadata := domain.VideoMetadata{
+		Author:      v.Author,
+		ChannelID:   v.ChannelID,
+		Duration:    v.Duration,
+		Description: v.Description,
+		PublishDate: v.PublishDate,
+		Views:       v.Views,
+		Chapters:    parseChapters(v.Description, v.Duration),
+	}
+	for _, thumb := range v.Thumbnails {
+		metadata.Thumbnails = append(metadata.Thumbnails, domain.Thumbnail{URL: thumb.URL, Width: int(thumb.Width), Height: int(thumb.Height)})
+	}
+	return metadata
+}
+
+// streamInfo describes the chosen format and, when given, the audio format
+// merged into it.
+func streamInfo(format, audio *yt.Format) domain.StreamInfo {
+	info := domain.StreamInfo{
+		Itag:       format.ItagNo,
+		Resolution: format.QualityLabel,
+		Width:      format.Width,
+		Height:     format.Height,
+		FPS:        format.FPS,
+		Bitrate:    format.Bitrate,
+	}
+
+	codecs := formatCodecs(*format)
+	switch {
+	case format.Width == 0 && len(codecs) > 0:
+		info.AudioCodec = codecs[0]
+	case len(codecs) > 1:
+		info.VideoCodec, info.AudioCodec = codecs[0], codecs[1]
+	case len(codecs) == 1:
+		info.VideoCodec = codecs[0]
+	}
+
+	if audio != nil {
+		info.AudioItag = audio.ItagNo
+		info.Bitrate += audio.Bitrate
+		if codecs := formatCodecs(*audio); len(codecs) > 0 {
+			info.AudioCodec = codecs[0]
+		}
+	}
+	return info
+}
+
+// formatCodecs returns the codecs listed in the mime type of the format,
+// video first.
+func formatCodecs(f yt.Format) []string {
+	_, params, err := mime.ParseMediaType(f.MimeType)
+	if err != nil || params["codecs"] == "" {
+		return nil
+	}
+
+	var codecs []string
+	for _, codec := range strings.Split(params["codecs"], ",") {
+		codecs = append(codecs, strings.TrimSpace(codec))
+	}
+	return codecs
+}
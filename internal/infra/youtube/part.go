@@ -0,0 +1,292 @@
+package youtube
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrSizeMismatch = errors.New("downloaded size does not match the stream size")
+
+const manifestInterval = time.Second
+
+// partManifest is stored next to a .part file and tells which stream the
+// bytes on disk belong to, so an interrupted download can continue. A
+// stream fetched in chunks is preallocated and records each chunk apart.
+type partManifest struct {
+	VideoID string      `json:"video_id"`
+	Itag    int         `json:"itag"`
+	Bytes   int64       `json:"bytes"`
+	Size    int64       `json:"size"`
+	Chunks  []partChunk `json:"chunks,omitempty"`
+}
+
+// partChunk is the byte range [Start, End) of a stream, of which the first
+// Written bytes are on disk.
+type partChunk struct {
+	Start   int64 `json:"start"`
+	End     int64 `json:"end"`
+	Written int64 `json:"written"`
+}
+
+// partFile receives a stream before it is complete. It keeps its manifest
+// up to date while being written.
+type partFile struct {
+	path     string
+	file     *os.File
+	mu       sync.Mutex
+	manifest partManifest
+	saved    time.Time
+}
+
+// activeParts holds the part files in use, so two downloads of the same
+// stream in this process never write to the same file.
+var activeParts sync.Map
+
+// openPart opens the part file at path, keeping the bytes already written
+// when its manifest matches the stream.
+func openPart(path, videoID string, itag int) (*partFile, error) {
+	if _, busy := activeParts.LoadOrStore(path, true); busy {
+		return nil, fmt.Errorf("stream %s is already being downloaded", path)
+	}
+
+	part := &partFile{path: path, manifest: partManifest{VideoID: videoID, Itag: itag}}
+	if m, err := readManifest(part.manifestPath()); err == nil && m.VideoID == videoID && m.Itag == itag {
+		if stat, err := os.Stat(path); err == nil {
+			switch {
+			case m.Chunks == nil:
+				m.Bytes = min(m.Bytes, stat.Size())
+				part.manifest = m
+			case stat.Size() == m.Size:
+				part.manifest = m
+			}
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		activeParts.Delete(path)
+		return nil, fmt.Errorf("error creating file: %w", err)
+	}
+	if part.manifest.Chunks == nil {
+		if err = file.Truncate(part.manifest.Bytes); err == nil {
+			_, err = file.Seek(part.manifest.Bytes, 0)
+		}
+	}
+	if err != nil {
+		file.Close()
+		activeParts.Delete(path)
+		return nil, fmt.Errorf("error preparing %s: %w", path, err)
+	}
+
+	part.file = file
+	return part, nil
+}
+
+// Offset returns the number of bytes already on disk.
+func (p *partFile) Offset() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.manifest.Bytes
+}
+
+// SetSize records the full size of the stream, when known.
+func (p *partFile) SetSize(size int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.manifest.Size = size
+	return p.saveManifest()
+}
+
+// Split prepares the part to be written in up to n chunks of a stream of
+// the given size, preallocating the file. Chunks of an earlier attempt at
+// the same stream are kept as they are.
+func (p *partFile) Split(size int64, n int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.manifest.Chunks != nil && p.manifest.Size == size {
+		return nil
+	}
+
+	n = max(1, min(n, int(size/minChunkSize)))
+	chunks := make([]partChunk, n)
+	for i := range chunks {
+		chunks[i] = partChunk{Start: size * int64(i) / int64(n), End: size * int64(i+1) / int64(n)}
+	}
+
+	if err := p.file.Truncate(0); err != nil {
+		return fmt.Errorf("error preallocating %s: %w", p.path, err)
+	}
+	if err := p.file.Truncate(size); err != nil {
+		return fmt.Errorf("error preallocating %s: %w", p.path, err)
+	}
+	p.manifest.Bytes, p.manifest.Size, p.manifest.Chunks = 0, size, chunks
+	return p.saveManifest()
+}
+
+// Reset drops the bytes of an earlier attempt written in chunks, so the
+// stream can be written sequentially from the start.
+func (p *partFile) Reset() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.manifest.Chunks == nil {
+		return nil
+	}
+
+	if err := p.file.Truncate(0); err != nil {
+		return fmt.Errorf("error truncating %s: %w", p.path, err)
+	}
+	if _, err := p.file.Seek(0, 0); err != nil {
+		return fmt.Errorf("error truncating %s: %w", p.path, err)
+	}
+	p.manifest.Bytes, p.manifest.Size, p.manifest.Chunks = 0, 0, nil
+	return p.saveManifest()
+}
+
+// Pending returns the chunks that still miss bytes.
+func (p *partFile) Pending() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var pending []int
+	for i, c := range p.manifest.Chunks {
+		if c.Start+c.Written < c.End {
+			pending = append(pending, i)
+		}
+	}
+	return pending
+}
+
+// Chunk returns the next offset to write in chunk i and the end of it.
+func (p *partFile) Chunk(i int) (offset, end int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c := p.manifest.Chunks[i]
+	return c.Start + c.Written, c.End
+}
+
+// ChunkWriter returns a writer that appends to chunk i.
+func (p *partFile) ChunkWriter(i int) io.Writer {
+	return &chunkWriter{part: p, index: i}
+}
+
+// Complete reports whether every byte of a stream of known size is on disk.
+func (p *partFile) Complete() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.manifest.Size > 0 && p.manifest.Bytes == p.manifest.Size
+}
+
+func (p *partFile) Write(b []byte) (int, error) {
+	n, err := p.file.Write(b)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.written(n)
+	return n, err
+}
+
+// written accounts for n new bytes, saving the manifest from time to time.
+// It must be called with mu held.
+func (p *partFile) written(n int) {
+	p.manifest.Bytes += int64(n)
+	if time.Since(p.saved) >= manifestInterval {
+		p.saveManifest()
+	}
+}
+
+// Close closes the file and saves the manifest, keeping the part for a
+// later resume.
+func (p *partFile) Close() error {
+	defer activeParts.Delete(p.path)
+	if err := p.file.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return fmt.Errorf("error closing file: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.saveManifest()
+}
+
+// Finish checks the size of a closed part and moves it to path.
+func (p *partFile) Finish(path string) error {
+	p.mu.Lock()
+	m := p.manifest
+	p.mu.Unlock()
+
+	if m.Size > 0 && m.Bytes != m.Size {
+		p.Remove()
+		return fmt.Errorf("%w: itag %d has %d of %d bytes", ErrSizeMismatch, m.Itag, m.Bytes, m.Size)
+	}
+	if err := os.Rename(p.path, path); err != nil {
+		return fmt.Errorf("error moving %s: %w", p.path, err)
+	}
+	os.Remove(p.manifestPath())
+	return nil
+}
+
+// Remove deletes the part and its manifest.
+func (p *partFile) Remove() {
+	p.file.Close()
+	activeParts.Delete(p.path)
+	for _, path := range []string{p.path, p.manifestPath()} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Error(fmt.Sprintf("Erro ao remover arquivo parcial %s: %v", path, err))
+		}
+	}
+}
+
+func (p *partFile) manifestPath() string {
+	return p.path + ".json"
+}
+
+// saveManifest writes the manifest through a temporary file, so a crash
+// never leaves it half written. It must be called with mu held.
+func (p *partFile) saveManifest() error {
+	data, err := json.Marshal(p.manifest)
+	if err != nil {
+		return fmt.Errorf("error encoding manifest: %w", err)
+	}
+
+	tmp := p.manifestPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing manifest: %w", err)
+	}
+	if err := os.Rename(tmp, p.manifestPath()); err != nil {
+		return fmt.Errorf("error writing manifest: %w", err)
+	}
+	p.saved = time.Now()
+	return nil
+}
+
+type chunkWriter struct {
+	part  *partFile
+	index int
+}
+
+func (w *chunkWriter) Write(b []byte) (int, error) {
+	offset, end := w.part.Chunk(w.index)
+	if offset+int64(len(b)) > end {
+		return 0, fmt.Errorf("chunk %d overflows its range", w.index)
+	}
+	n, err := w.part.file.WriteAt(b, offset)
+
+	w.part.mu.Lock()
+	defer w.part.mu.Unlock()
+	w.part.manifest.Chunks[w.index].Written += int64(n)
+	w.part.written(n)
+	return n, err
+}
+
+func readManifest(path string) (partManifest, error) {
+	var m partManifest
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(data, &m)
+	return m, err
+}
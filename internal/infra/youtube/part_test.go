@@ -0,0 +1,220 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	yt "github.com/kkdai/youtube/v2"
+)
+
+// requestedRanges records the Range header of the requests of a server.
+type requestedRanges struct {
+	mu     sync.Mutex
+	ranges []string
+}
+
+func (r *requestedRanges) add(value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ranges = append(r.ranges, value)
+}
+
+func (r *requestedRanges) all() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.ranges)
+}
+
+// streamServer serves data with Range support, recording the ranges asked.
+func streamServer(t *testing.T, data []byte) (*httptest.Server, *requestedRanges) {
+	t.Helper()
+	ranges := &requestedRanges{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges.add(r.Header.Get("Range"))
+		http.ServeContent(w, r, "stream", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(server.Close)
+	return server, ranges
+}
+
+// writePart leaves a part file holding data and its manifest, as an
+// interrupted download does.
+func writePart(t *testing.T, path string, data []byte, m partManifest) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".json", encoded, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPartResumes(t *testing.T) {
+	data := []byte("0123456789abcdefghij")
+	server, ranges := streamServer(t, data)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "v.f18.mp4.part")
+
+	// The manifest was saved after 6 bytes, and 2 more reached the disk.
+	writePart(t, path, data[:8], partManifest{VideoID: "v", Itag: 18, Bytes: 6, Size: int64(len(data))})
+
+	part, err := openPart(path, "v", 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if part.Offset() != 6 {
+		t.Fatalf("Offset = %d, want the 6 bytes of the manifest", part.Offset())
+	}
+	stream := newRangeReader(context.Background(), &yt.Client{}, server.URL, part.Offset(), int64(len(data)))
+	if _, err := io.Copy(part, stream); err != nil {
+		t.Fatal(err)
+	}
+	stream.Close()
+	if !part.Complete() {
+		t.Fatal("part not complete after the rest of the stream")
+	}
+	if err := part.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(dir, "v.mp4")
+	if err := part.Finish(target); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(target); !bytes.Equal(got, data) {
+		t.Fatalf("resumed file = %q, want %q", got, data)
+	}
+	if got := ranges.all(); len(got) != 1 || got[0] != "bytes=6-19" {
+		t.Fatalf("requested ranges %q, want [bytes=6-19]", got)
+	}
+	if _, err := os.Stat(path + ".json"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("manifest left behind: %v", err)
+	}
+}
+
+func TestPartDiscardsOtherStreams(t *testing.T) {
+	data := []byte("0123456789")
+	tests := []struct {
+		name     string
+		manifest partManifest
+		raw      string
+	}{
+		{name: "other itag", manifest: partManifest{VideoID: "v", Itag: 22, Bytes: 10, Size: 10}},
+		{name: "other video", manifest: partManifest{VideoID: "w", Itag: 18, Bytes: 10, Size: 10}},
+		{name: "chunks of another size", manifest: partManifest{VideoID: "v", Itag: 18, Size: 20, Chunks: []partChunk{{0, 20, 10}}}},
+		{name: "corrupt manifest", raw: "{"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "v.f18.mp4.part")
+			writePart(t, path, data, tt.manifest)
+			if tt.raw != "" {
+				os.WriteFile(path+".json", []byte(tt.raw), 0o644)
+			}
+
+			part, err := openPart(path, "v", 18)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer part.Remove()
+			if part.Offset() != 0 || part.manifest.Chunks != nil {
+				t.Fatalf("manifest = %+v, want a fresh one", part.manifest)
+			}
+			if stat, _ := os.Stat(path); stat.Size() != 0 {
+				t.Fatalf("part holds %d bytes of another stream", stat.Size())
+			}
+		})
+	}
+}
+
+func TestPartBusy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "v.f18.mp4.part")
+	part, err := openPart(path, "v", 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openPart(path, "v", 18); err == nil || !strings.Contains(err.Error(), "already being downloaded") {
+		t.Fatalf("second openPart = %v, want the part refused", err)
+	}
+	part.Remove()
+	again, err := openPart(path, "v", 18)
+	if err != nil {
+		t.Fatalf("openPart after Remove = %v", err)
+	}
+	again.Remove()
+}
+
+func TestPartFinishRefusesShortStream(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "v.f18.mp4.part")
+	part, err := openPart(path, "v", 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := part.SetSize(10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := part.Write([]byte("01234")); err != nil {
+		t.Fatal(err)
+	}
+	if err := part.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := part.Finish(filepath.Join(dir, "v.mp4")); !errors.Is(err, ErrSizeMismatch) {
+		t.Fatalf("Finish = %v, want ErrSizeMismatch", err)
+	}
+	for _, leftover := range []string{path, path + ".json", filepath.Join(dir, "v.mp4")} {
+		if _, err := os.Stat(leftover); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s left behind: %v", leftover, err)
+		}
+	}
+}
+
+func TestRangeReaderRefusesIgnoredRange(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("the whole stream"))
+	}))
+	defer server.Close()
+
+	stream := newRangeReader(context.Background(), &yt.Client{}, server.URL, 4, 16)
+	defer stream.Close()
+	if _, err := io.ReadAll(stream); !errors.Is(err, ErrRangeNotSupported) {
+		t.Fatalf("read = %v, want ErrRangeNotSupported", err)
+	}
+}
+
+func TestRangeReaderFetchesInPieces(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), rangeChunkSize+10)
+	server, ranges := streamServer(t, data)
+
+	stream := newRangeReader(context.Background(), &yt.Client{}, server.URL, 5, int64(len(data)))
+	defer stream.Close()
+	got, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(data)-5 {
+		t.Fatalf("read %d bytes, want %d", len(got), len(data)-5)
+	}
+	want := []string{"bytes=5-10485764", "bytes=10485765-10485769"}
+	if got := ranges.all(); !slices.Equal(got, want) {
+		t.Fatalf("requested ranges %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,81 @@
+package youtube
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	yt "github.com/kkdai/youtube/v2"
+)
+
+const watchURL = "https://www.youtube.com/watch?v="
+
+type KkdaiPlaylistFetcher struct{}
+
+func NewKkdaiPlaylistFetcher() *KkdaiPlaylistFetcher {
+	return &KkdaiPlaylistFetcher{}
+}
+
+// IsPlaylist reports whether rawURL points to a playlist or a channel
+// rather than to a video. A watch URL that also carries a list is treated
+// as the video.
+func (f *KkdaiPlaylistFetcher) IsPlaylist(rawURL string) bool {
+	if isChannelURL(rawURL) {
+		return true
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	query := u.Query()
+	return query.Get("list") != "" && query.Get("v") == "" && !strings.HasPrefix(u.Host, "youtu.be")
+}
+
+// Fetch lists a playlist. Channels are listed through their uploads
+// playlist, newest video first.
+func (f *KkdaiPlaylistFetcher) Fetch(ctx context.Context, rawURL string) (domain.Playlist, error) {
+	if isChannelURL(rawURL) {
+		uploads, err := uploadsPlaylistURL(ctx, rawURL)
+		if err != nil {
+			return domain.Playlist{}, err
+		}
+		rawURL = uploads
+	}
+
+	client := yt.Client{}
+	ytPlaylist, err := client.GetPlaylistContext(ctx, rawURL)
+	if err != nil {
+		return domain.Playlist{}, fmt.Errorf("error fetching playlist info: %w", err)
+	}
+
+	playlist := domain.Playlist{
+		ID:      ytPlaylist.ID,
+		Title:   ytPlaylist.Title,
+		Author:  ytPlaylist.Author,
+		Entries: make([]domain.PlaylistEntry, 0, len(ytPlaylist.Videos)),
+	}
+	for i, entry := range ytPlaylist.Videos {
+		playlist.Entries = append(playlist.Entries, domain.PlaylistEntry{
+			Index:    i + 1,
+			ID:       entry.ID,
+			URL:      watchURL + entry.ID,
+			Title:    entry.Title,
+			Duration: entry.Duration,
+		})
+	}
+	log.Info(fmt.Sprintf("Playlist %s com %d vídeos", playlist.Title, len(playlist.Entries)))
+	return playlist, nil
+}
+
+func (f *KkdaiPlaylistFetcher) PublishDate(ctx context.Context, entryID string) (time.Time, error) {
+	client := yt.Client{}
+	video, err := client.GetVideoContext(ctx, entryID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error fetching video info: %w", err)
+	}
+	return video.PublishDate, nil
+}
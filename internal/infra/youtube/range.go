@@ -0,0 +1,110 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	yt "github.com/kkdai/youtube/v2"
+)
+
+var ErrRangeNotSupported = errors.New("server did not honor the range request")
+
+// rangeChunkSize is the size of each Range request. YouTube throttles long
+// single responses, so the remainder of a stream is fetched in pieces.
+const rangeChunkSize = 10 * 1024 * 1024
+
+// rangeReader reads a stream from offset up to end with HTTP Range
+// requests. A zero end fetches everything after offset at once.
+type rangeReader struct {
+	ctx    context.Context
+	client *http.Client
+	url    string
+	offset int64
+	end    int64
+	body   io.ReadCloser
+}
+
+func openRange(ctx context.Context, client *yt.Client, ytVideo *yt.Video, format *yt.Format, offset, end int64) (io.ReadCloser, error) {
+	url, err := client.GetStreamURLContext(ctx, ytVideo, format)
+	if err != nil {
+		return nil, fmt.Errorf("error getting stream url: %w", err)
+	}
+	return newRangeReader(ctx, client, url, offset, end), nil
+}
+
+func newRangeReader(ctx context.Context, client *yt.Client, url string, offset, end int64) *rangeReader {
+	httpClient := client.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &rangeReader{ctx: ctx, client: httpClient, url: url, offset: offset, end: end}
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	for {
+		if r.body == nil {
+			if r.end > 0 && r.offset >= r.end {
+				return 0, io.EOF
+			}
+			if err := r.request(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := r.body.Read(p)
+		r.offset += int64(n)
+		if err == io.EOF {
+			r.body.Close()
+			r.body = nil
+			if r.end == 0 {
+				return n, io.EOF
+			}
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+func (r *rangeReader) request() error {
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+
+	if r.end > 0 {
+		end := min(r.offset+rangeChunkSize, r.end) - 1
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", r.offset, end))
+	} else {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", r.offset))
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error requesting range: %w", err)
+	}
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		resp.Body.Close()
+		return fmt.Errorf("%w: status %d at %d", ErrRangeNotSupported, resp.StatusCode, r.offset)
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return fmt.Errorf("range request at %d: %w", r.offset, yt.ErrUnexpectedStatusCode(resp.StatusCode))
+	}
+
+	r.body = resp.Body
+	return nil
+}
+
+func (r *rangeReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
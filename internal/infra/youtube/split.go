@@ -0,0 +1,76 @@
+package youtube
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"downloader/internal/infra/download"
+	"downloader/pkg/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// splitChapters cuts the downloaded video into one file per chapter, each
+// saved as a video whose parent is the full one. The cuts start at the
+// keyframe before each chapter. Failures are logged and the chapter is
+// skipped, as the full video was downloaded.
+func (d *KkdaiDownloader) splitChapters(ctx context.Context, video domain.Video, thumbnail []byte) {
+	chapters := video.Metadata.Chapters
+	switch {
+	case len(chapters) == 0:
+		log.Info(fmt.Sprintf("Capítulos não separados em %s: o vídeo não tem capítulos", video.Title))
+		return
+	case video.Clipped():
+		log.Info(fmt.Sprintf("Capítulos não separados em %s: apenas um trecho foi baixado", video.Title))
+		return
+	}
+
+	log.Info(fmt.Sprintf("Separando %d capítulos de %s", len(chapters), video.Title))
+	for i, chapter := range chapters {
+		if ctx.Err() != nil {
+			return
+		}
+		chapterVideo, err := d.cutChapter(video, i, chapter)
+		if err != nil {
+			log.Error(fmt.Sprintf("Erro ao separar o capítulo %d de %s: %v", i+1, video.Title, err))
+			continue
+		}
+		writeTags(chapterVideo, thumbnail)
+		if chapterVideo.Size, chapterVideo.Checksum, err = download.FileChecksum(chapterVideo.Path); err != nil {
+			log.Error(fmt.Sprintf("Erro ao calcular checksum de %s: %v", chapterVideo.Path, err))
+		}
+		chapterVideo.FinishedAt = time.Now()
+		chapterVideo.CreatedAt = chapterVideo.FinishedAt
+		if err := download.Save(d.db, chapterVideo); err != nil {
+			log.Error(fmt.Sprintf("Capítulo %d de %s descartado: %v", i+1, video.Title, err))
+		}
+	}
+}
+
+// cutChapter writes the chapter at index i of video to a file of its own.
+func (d *KkdaiDownloader) cutChapter(video domain.Video, i int, chapter domain.Chapter) (domain.Video, error) {
+	chapterVideo := video
+	chapterVideo.ID = uuid.NewString()
+	chapterVideo.ParentID = video.ID
+	chapterVideo.ChapterIndex = i + 1
+	chapterVideo.Title = chapter.Title
+	chapterVideo.Filename = utils.SanitizeFilename(fmt.Sprintf("%02d - %s", i+1, chapter.Title))
+	chapterVideo.SplitChapters = false
+	chapterVideo.ClipStart, chapterVideo.ClipEnd = chapter.Start, chapter.End
+	// Subtitles are only saved for the full video.
+	chapterVideo.SubtitleLanguages, chapterVideo.Subtitles = nil, nil
+	chapterVideo.Metadata.Chapters = nil
+	if chapter.End > chapter.Start {
+		chapterVideo.Metadata.Duration = chapter.End - chapter.Start
+	}
+	chapterVideo.Path = filepath.Join(filepath.Dir(video.Path), utils.SanitizeFilename(chapterVideo.ID+"."+video.Extension))
+
+	if err := d.muxer.Cut(video.Path, chapterVideo.Path, chapter.Start, chapter.End); err != nil {
+		os.Remove(chapterVideo.Path)
+		return domain.Video{}, err
+	}
+	return chapterVideo, nil
+}
@@ -0,0 +1,215 @@
+package youtube
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"downloader/internal/infra/download"
+	"downloader/pkg/config"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	yt "github.com/kkdai/youtube/v2"
+)
+
+// streamTarget is a format to download and the file that receives it.
+type streamTarget struct {
+	format *yt.Format
+	path   string
+}
+
+// fetchStreams downloads every stream concurrently, reporting the sum of
+// their progress to a single progress bar. Each stream is written to a
+// .part file that survives interruptions, and only moved to its target
+// path once every stream is complete. Streams of known size are fetched
+// in parallel chunks, the others sequentially.
+func (d *KkdaiDownloader) fetchStreams(ctx context.Context, client *yt.Client, ytVideo *yt.Video, streams []streamTarget, progress domain.ProgressBar) error {
+	parts := make([]*partFile, len(streams))
+	readers := make([]io.ReadCloser, len(streams))
+	urls := make([]string, len(streams))
+	release := func() {
+		closeStreams(readers)
+		for _, part := range parts {
+			if part != nil {
+				part.Close()
+			}
+		}
+	}
+
+	chunks := config.GetConfig().Chunks
+	var total, resumed int64
+	for i, target := range streams {
+		part, err := openPart(partPath(ytVideo.ID, *target.format), ytVideo.ID, target.format.ItagNo)
+		if err != nil {
+			release()
+			return err
+		}
+		parts[i] = part
+
+		if size := target.format.ContentLength; size > 0 {
+			if err := part.Split(size, chunks); err != nil {
+				release()
+				return err
+			}
+			if urls[i], err = client.GetStreamURLContext(ctx, ytVideo, target.format); err != nil {
+				release()
+				return fmt.Errorf("error getting stream url: %w", err)
+			}
+			total += size
+		} else {
+			if err := part.Reset(); err != nil {
+				release()
+				return err
+			}
+			stream, size, err := d.openStream(ctx, client, ytVideo, target.format, part)
+			if err != nil {
+				release()
+				return fmt.Errorf("error getting video stream: %w", err)
+			}
+			readers[i] = stream
+			total += size
+		}
+		resumed += part.Offset()
+	}
+	defer closeStreams(readers)
+
+	if resumed > 0 {
+		log.Info(fmt.Sprintf("Retomando download de %s a partir de %d bytes", ytVideo.Title, resumed))
+	}
+	progress.Start(total)
+	counter := download.NewProgressWriter(progress, resumed)
+	progress.Update(resumed)
+
+	errs := make([]error, len(streams))
+	var wg sync.WaitGroup
+	for i := range streams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if urls[i] != "" {
+				errs[i] = d.saveChunks(ctx, client, urls[i], parts[i], counter)
+			} else {
+				errs[i] = d.saveStream(ctx, readers[i], parts[i], counter)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, target := range streams {
+		if err := parts[i].Finish(target.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openStream returns the bytes of format missing from part and the full
+// size of the stream. A new part gets the whole stream, a partial one is
+// continued with Range requests.
+func (d *KkdaiDownloader) openStream(ctx context.Context, client *yt.Client, ytVideo *yt.Video, format *yt.Format, part *partFile) (io.ReadCloser, int64, error) {
+	if part.Complete() {
+		return http.NoBody, part.manifest.Size, nil
+	}
+
+	if offset := part.Offset(); offset > 0 {
+		size := part.manifest.Size
+		stream, err := openRange(ctx, client, ytVideo, format, offset, size)
+		return stream, size, err
+	}
+
+	stream, size, err := client.GetStreamContext(ctx, ytVideo, format)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := part.SetSize(size); err != nil {
+		stream.Close()
+		return nil, 0, err
+	}
+	return stream, size, nil
+}
+
+// saveStream copies the stream into part.
+func (d *KkdaiDownloader) saveStream(ctx context.Context, stream io.Reader, part *partFile, counter io.Writer) error {
+	_, err := io.Copy(part, io.TeeReader(&contextReader{ctx: ctx, r: stream}, counter))
+	return d.closePart(ctx, part, err)
+}
+
+// closePart ends the writing of part. When the download is cancelled the
+// part is deleted; any other interruption keeps it for a resume.
+func (d *KkdaiDownloader) closePart(ctx context.Context, part *partFile, err error) error {
+	if errors.Is(context.Cause(ctx), domain.ErrDownloadCancelled) {
+		if cancelErr := d.Cancel(part.file); cancelErr != nil {
+			log.Error(fmt.Sprintf("Erro ao descartar download cancelado: %v", cancelErr))
+		}
+		part.Remove()
+		return context.Cause(ctx)
+	}
+
+	if errors.Is(err, ErrRangeNotSupported) {
+		// The bytes on disk cannot be continued, start over next time.
+		part.Remove()
+		return fmt.Errorf("error resuming download: %w", err)
+	}
+	if closeErr := part.Close(); err == nil {
+		err = closeErr
+	}
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("error saving video: %w", err)
+	}
+	return nil
+}
+
+// contextReader stops reading as soon as its context is done, without
+// waiting for the underlying stream to notice.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+func closeStreams(readers []io.ReadCloser) {
+	for _, r := range readers {
+		if r != nil {
+			r.Close()
+		}
+	}
+}
+
+func removeStreams(streams []streamTarget) {
+	for _, target := range streams {
+		if err := os.Remove(target.path); err != nil && !os.IsNotExist(err) {
+			log.Error(fmt.Sprintf("Erro ao remover arquivo temporário %s: %v", target.path, err))
+		}
+	}
+}
+
+// partPath names the part file of a stream after the YouTube video rather
+// than the download, so a new request for the same stream resumes it.
+func partPath(videoID string, format yt.Format) string {
+	return filepath.Join(config.GetConfig().VideoDir, tempStreamName(videoID, format)+".part")
+}
+
+// tempStreamName names the file holding one stream of a merged download.
+func tempStreamName(id string, format yt.Format) string {
+	return id + ".f" + strconv.Itoa(format.ItagNo) + "." + formatExtension(format)
+}
@@ -0,0 +1,148 @@
+package youtube
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yt "github.com/kkdai/youtube/v2"
+)
+
+const maxCaptionSize = 16 << 20
+
+// downloadSubtitles saves the captions asked for by the video next to it.
+// A missing or broken track is logged and skipped, as the media itself was
+// downloaded.
+func downloadSubtitles(ctx context.Context, ytVideo *yt.Video, video domain.Video, dir string) []domain.Subtitle {
+	var subtitles []domain.Subtitle
+	for _, language := range video.SubtitleLanguages {
+		track, translated, ok := selectCaption(ytVideo.CaptionTracks, language, video.AutoSubtitles)
+		if !ok {
+			log.Info(fmt.Sprintf("Legenda %s não disponível para %s", language, ytVideo.Title))
+			continue
+		}
+
+		subtitle := domain.Subtitle{
+			Language:      language,
+			Name:          track.Name.SimpleText,
+			AutoGenerated: track.Kind == "asr",
+			Translated:    translated,
+		}
+		if err := saveSubtitle(ctx, track, subtitle, video.ID, dir); err != nil {
+			if ctx.Err() != nil {
+				return subtitles
+			}
+			log.Error(fmt.Sprintf("Erro ao baixar legenda %s de %s: %v", language, ytVideo.Title, err))
+			continue
+		}
+		subtitles = append(subtitles, subtitle)
+	}
+	return subtitles
+}
+
+// selectCaption picks the track for language: one written by people, then,
+// when auto is set, an auto-generated one, and last a translation of the
+// best translatable track.
+func selectCaption(tracks []yt.CaptionTrack, language string, auto bool) (yt.CaptionTrack, bool, bool) {
+	for _, manual := range []bool{true, false} {
+		if !manual && !auto {
+			break
+		}
+		for _, track := range tracks {
+			if (track.Kind != "asr") == manual && sameLanguage(track.LanguageCode, language) {
+				return track, false, true
+			}
+		}
+	}
+
+	if auto {
+		for _, manual := range []bool{true, false} {
+			for _, track := range tracks {
+				if track.IsTranslatable && (track.Kind != "asr") == manual {
+					return track, true, true
+				}
+			}
+		}
+	}
+	return yt.CaptionTrack{}, false, false
+}
+
+// sameLanguage matches "en" with "en", "EN" or "en-US", but not "en-US"
+// with "en-GB".
+func sameLanguage(code, language string) bool {
+	if strings.EqualFold(code, language) {
+		return true
+	}
+	base, _, _ := strings.Cut(code, "-")
+	return !strings.Contains(language, "-") && strings.EqualFold(base, language)
+}
+
+func saveSubtitle(ctx context.Context, track yt.CaptionTrack, subtitle domain.Subtitle, videoID, dir string) error {
+	trackURL, err := url.Parse(track.BaseURL)
+	if err != nil {
+		return fmt.Errorf("invalid caption url: %w", err)
+	}
+	query := trackURL.Query()
+	query.Set("fmt", "srv3")
+	if subtitle.Translated {
+		query.Set("tlang", subtitle.Language)
+	}
+	trackURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, trackURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error fetching caption track: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error fetching caption track: %w", yt.ErrUnexpectedStatusCode(resp.StatusCode))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCaptionSize))
+	if err != nil {
+		return fmt.Errorf("error reading caption track: %w", err)
+	}
+	cues, err := parseTimedText(data)
+	if err != nil {
+		return err
+	}
+
+	for _, format := range domain.SubtitleFormats {
+		path := filepath.Join(dir, domain.SubtitleFilename(videoID, subtitle.Language, format))
+		if err := writeSubtitleFile(path, format, cues); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSubtitleFile(path, format string, cues []cue) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", path, err)
+	}
+
+	if format == "srt" {
+		err = writeSRT(file, cues)
+	} else {
+		err = writeVTT(file, cues)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package youtube
+
+import (
+	"downloader/internal/domain"
+	"downloader/internal/infra/tagger"
+	"errors"
+	"fmt"
+)
+
+// writeTags tags the downloaded file, embedding the thumbnail as its cover
+// when the video asks for it. Failures are logged, as the media itself was
+// downloaded.
+func writeTags(video domain.Video, thumbnail []byte) {
+	var cover []byte
+	if video.EmbedThumbnail && thumbnail != nil {
+		switch video.ThumbnailType {
+		case "image/jpeg", "image/png":
+			cover = thumbnail
+		default:
+			log.Info(fmt.Sprintf("Capa não incorporada em %s: imagem %s não suportada", video.Title, video.ThumbnailType))
+		}
+	}
+
+	err := tagger.Write(video, cover, video.ThumbnailType)
+	switch {
+	case errors.Is(err, tagger.ErrUnsupportedFormat):
+		log.Info(fmt.Sprintf("Tags não gravadas em %s: formato %s não suportado", video.Title, video.Extension))
+	case err != nil:
+		log.Error(fmt.Sprintf("Erro ao gravar tags em %s: %v", video.Title, err))
+	}
+}
@@ -0,0 +1,99 @@
+package youtube
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+
+	yt "github.com/kkdai/youtube/v2"
+)
+
+const maxThumbnailSize = 8 << 20
+
+var thumbnailExtensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/webp": "webp",
+}
+
+// downloadThumbnail saves the largest thumbnail of the video next to it,
+// returning its file name and type. The maxresdefault image is not always
+// listed, so it is tried first.
+func downloadThumbnail(ctx context.Context, ytVideo *yt.Video, videoID, dir string) (string, string, []byte, error) {
+	thumbnails := slices.Clone(ytVideo.Thumbnails)
+	slices.SortStableFunc(thumbnails, func(a, b yt.Thumbnail) int {
+		return int(b.Width*b.Height) - int(a.Width*a.Height)
+	})
+
+	candidates := []string{"https://i.ytimg.com/vi/" + ytVideo.ID + "/maxresdefault.jpg"}
+	for _, thumb := range thumbnails {
+		candidates = append(candidates, thumb.URL)
+	}
+
+	var lastErr error
+	for _, candidate := range candidates {
+		data, contentType, err := fetchImage(ctx, candidate)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", "", nil, ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+
+		filename := domain.ThumbnailFilename(videoID, thumbnailExtensions[contentType])
+		if err := os.WriteFile(filepath.Join(dir, filename), data, 0o644); err != nil {
+			return "", "", nil, fmt.Errorf("error writing thumbnail: %w", err)
+		}
+		return filename, contentType, data, nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no thumbnail available")
+	}
+	return "", "", nil, lastErr
+}
+
+func fetchImage(ctx context.Context, url string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("error fetching thumbnail: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("error fetching thumbnail: %w", yt.ErrUnexpectedStatusCode(resp.StatusCode))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxThumbnailSize))
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading thumbnail: %w", err)
+	}
+	contentType := http.DetectContentType(data)
+	if _, ok := thumbnailExtensions[contentType]; !ok {
+		return nil, "", fmt.Errorf("unexpected thumbnail type %s", contentType)
+	}
+	return data, contentType, nil
+}
+
+// saveThumbnail downloads the thumbnail of the video and returns it, so it
+// can be embedded. Failures are logged, as the media itself was downloaded.
+func saveThumbnail(ctx context.Context, ytVideo *yt.Video, video *domain.Video, dir string) []byte {
+	filename, contentType, data, err := downloadThumbnail(ctx, ytVideo, video.ID, dir)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error(fmt.Sprintf("Erro ao baixar thumbnail de %s: %v", ytVideo.Title, err))
+		}
+		return nil
+	}
+	video.Thumbnail, video.ThumbnailType = filename, contentType
+	return data
+}
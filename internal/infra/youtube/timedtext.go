@@ -0,0 +1,118 @@
+package youtube
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+var ErrEmptyCaption = errors.New("caption track has no text")
+
+// cue is a piece of text shown between Start and End.
+type cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// timedText holds both layouts YouTube serves caption tracks in: format 3
+// (srv3), with times in milliseconds, and the legacy transcript, in
+// seconds.
+type timedText struct {
+	Paragraphs []struct {
+		T        int64  `xml:"t,attr"`
+		D        int64  `xml:"d,attr"`
+		Text     string `xml:",chardata"`
+		Segments []struct {
+			Text string `xml:",chardata"`
+		} `xml:"s"`
+	} `xml:"body>p"`
+	Texts []struct {
+		Start float64 `xml:"start,attr"`
+		Dur   float64 `xml:"dur,attr"`
+		Text  string  `xml:",chardata"`
+	} `xml:"text"`
+}
+
+// parseTimedText reads a caption track in YouTube's timed-text format.
+func parseTimedText(data []byte) ([]cue, error) {
+	var doc timedText
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing caption track: %w", err)
+	}
+
+	var cues []cue
+	for _, p := range doc.Paragraphs {
+		text := p.Text
+		for _, s := range p.Segments {
+			text += s.Text
+		}
+		cues = appendCue(cues, time.Duration(p.T)*time.Millisecond, time.Duration(p.T+p.D)*time.Millisecond, text)
+	}
+	for _, t := range doc.Texts {
+		start := time.Duration(t.Start * float64(time.Second))
+		cues = appendCue(cues, start, start+time.Duration(t.Dur*float64(time.Second)), t.Text)
+	}
+
+	if len(cues) == 0 {
+		return nil, ErrEmptyCaption
+	}
+	return cues, nil
+}
+
+// appendCue adds a cue unless its text is blank. Texts of legacy tracks
+// are escaped twice, so entities are decoded once more.
+func appendCue(cues []cue, start, end time.Duration, text string) []cue {
+	text = strings.TrimSpace(html.UnescapeString(text))
+	if text == "" || end <= start {
+		return cues
+	}
+	return append(cues, cue{Start: start, End: end, Text: text})
+}
+
+func writeSRT(w io.Writer, cues []cue) error {
+	for i, c := range cues {
+		_, err := fmt.Fprintf(w, "%d\n%s --> %s\n%s\n\n", i+1, cueTime(c.Start, ","), cueTime(c.End, ","), cueText(c.Text))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeVTT(w io.Writer, cues []cue) error {
+	if _, err := io.WriteString(w, "WEBVTT\n\n"); err != nil {
+		return err
+	}
+	for _, c := range cues {
+		_, err := fmt.Fprintf(w, "%s --> %s\n%s\n\n", cueTime(c.Start, "."), cueTime(c.End, "."), cueText(c.Text))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cueText drops the blank lines of a cue text, as a blank line ends the
+// cue in both SRT and WebVTT.
+func cueText(text string) string {
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// cueTime formats d as HH:MM:SS followed by the milliseconds, which SRT
+// separates with a comma and WebVTT with a dot.
+func cueTime(d time.Duration, separator string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3_600_000, ms/60_000%60, ms/1000%60, separator, ms%1000)
+}
@@ -0,0 +1,112 @@
+package youtube
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimedText(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []cue
+		err  bool
+	}{
+		{
+			name: "format 3",
+			data: `<timedtext format="3"><body>
+				<p t="1000" d="2500">Olá</p>
+				<p t="4000" d="1000"><s>meu </s><s>mundo</s></p>
+				<p t="6000" d="1000">  </p>
+			</body></timedtext>`,
+			want: []cue{
+				{Start: time.Second, End: 3500 * time.Millisecond, Text: "Olá"},
+				{Start: 4 * time.Second, End: 5 * time.Second, Text: "meu mundo"},
+			},
+		},
+		{
+			name: "legacy transcript",
+			data: `<transcript>
+				<text start="0.5" dur="1.25">It&amp;#39;s &amp;amp; more</text>
+				<text start="2" dur="0">skipped</text>
+			</transcript>`,
+			want: []cue{{Start: 500 * time.Millisecond, End: 1750 * time.Millisecond, Text: "It's & more"}},
+		},
+		{
+			name: "no text",
+			data: `<timedtext format="3"><body></body></timedtext>`,
+			err:  true,
+		},
+		{
+			name: "not XML",
+			data: `{"events": []}`,
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cues, err := parseTimedText([]byte(tt.data))
+			if tt.err {
+				if err == nil {
+					t.Fatalf("parseTimedText = %v, want an error", cues)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cues) != len(tt.want) {
+				t.Fatalf("parseTimedText = %v, want %v", cues, tt.want)
+			}
+			for i := range cues {
+				if cues[i] != tt.want[i] {
+					t.Errorf("cue %d = %+v, want %+v", i, cues[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := parseTimedText([]byte(`<transcript></transcript>`)); !errors.Is(err, ErrEmptyCaption) {
+		t.Fatalf("empty transcript = %v, want ErrEmptyCaption", err)
+	}
+}
+
+func TestWriteCues(t *testing.T) {
+	cues := []cue{
+		{Start: 1500 * time.Millisecond, End: 4 * time.Second, Text: "Primeira"},
+		{Start: time.Hour + 2*time.Minute + 3*time.Second + 7*time.Millisecond, End: 2 * time.Hour, Text: "Duas\n\n \nlinhas"},
+	}
+
+	tests := []struct {
+		name  string
+		write func(*strings.Builder, []cue) error
+		want  string
+	}{
+		{
+			name:  "SRT",
+			write: func(b *strings.Builder, cues []cue) error { return writeSRT(b, cues) },
+			want: "1\n00:00:01,500 --> 00:00:04,000\nPrimeira\n\n" +
+				"2\n01:02:03,007 --> 02:00:00,000\nDuas\nlinhas\n\n",
+		},
+		{
+			name:  "WebVTT",
+			write: func(b *strings.Builder, cues []cue) error { return writeVTT(b, cues) },
+			want: "WEBVTT\n\n" +
+				"00:00:01.500 --> 00:00:04.000\nPrimeira\n\n" +
+				"01:02:03.007 --> 02:00:00.000\nDuas\nlinhas\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := tt.write(&b, cues); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Fatalf("wrote %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
@@ -1,18 +1,210 @@
 package usecase
 
 import (
+	"context"
 	"downloader/internal/domain"
+	logger "downloader/pkg/log"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
+var log = logger.GetLogger("usecase")
+
 type DownloadVideoUseCase struct {
 	Downloader domain.Downloader
+	Archive    domain.Archive
+	Queue      domain.JobQueue
 }
 
+var ErrInvalidLanguage = errors.New("invalid subtitle language")
+
+var ErrInvalidClip = errors.New("invalid clip range")
+
+var ErrInvalidDuration = errors.New("invalid duration")
+
+var languagePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
 type Solicitation struct {
-	URL       string
-	Requester string
+	URL           string
+	Requester     string
+	Format        string
+	AudioOnly     bool
+	Subtitles     []string
+	AutoSubtitles bool
+	// EmbedThumbnail stores the thumbnail as the cover art of the output.
+	EmbedThumbnail bool
+	// Start and End select the part of the video to download. A zero End
+	// runs to the end.
+	Start time.Duration
+	End   time.Duration
+	// SplitChapters also saves every chapter of the video as a file.
+	SplitChapters bool
+	// LiveFromStart records a livestream from its earliest available
+	// segment, and LiveMaxDuration, when not zero, stops the recording.
+	LiveFromStart   bool
+	LiveMaxDuration time.Duration
+}
+
+func (sol Solicitation) Video() domain.Video {
+	return domain.Video{
+		URL:               sol.URL,
+		Requester:         sol.Requester,
+		Format:            sol.Format,
+		AudioOnly:         sol.AudioOnly,
+		SubtitleLanguages: sol.Subtitles,
+		AutoSubtitles:     sol.AutoSubtitles,
+		EmbedThumbnail:    sol.EmbedThumbnail,
+		ClipStart:         sol.Start,
+		ClipEnd:           sol.End,
+		SplitChapters:     sol.SplitChapters,
+		LiveFromStart:     sol.LiveFromStart,
+		LiveMaxDuration:   sol.LiveMaxDuration,
+		RequestedAt:       time.Now(),
+	}
+}
+
+// ParseLanguages splits a comma separated list of language codes, such as
+// "en,pt-BR".
+func ParseLanguages(list string) ([]string, error) {
+	var languages []string
+	for _, language := range strings.Split(list, ",") {
+		language = strings.TrimSpace(language)
+		if language == "" {
+			continue
+		}
+		if !languagePattern.MatchString(language) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidLanguage, language)
+		}
+		if !slices.Contains(languages, language) {
+			languages = append(languages, language)
+		}
+	}
+	return languages, nil
+}
+
+// ParseClip parses the start and end of a clip, given in seconds, as
+// "[hh:]mm:ss[.fff]" or as a duration such as "1m30s". Empty values leave
+// that side open.
+func ParseClip(start, end string) (time.Duration, time.Duration, error) {
+	from, err := parseTime(start, ErrInvalidClip)
+	if err != nil {
+		return 0, 0, err
+	}
+	to, err := parseTime(end, ErrInvalidClip)
+	if err != nil {
+		return 0, 0, err
+	}
+	if to > 0 && to <= from {
+		return 0, 0, fmt.Errorf("%w: end must be after start", ErrInvalidClip)
+	}
+	return from, to, nil
+}
+
+// ParseDuration parses a duration given as for ParseClip. An empty value
+// is zero.
+func ParseDuration(value string) (time.Duration, error) {
+	return parseTime(value, ErrInvalidDuration)
+}
+
+func parseTime(value string, invalid error) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	if strings.ContainsAny(value, "hms") {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("%w: %q", invalid, value)
+		}
+		return d, nil
+	}
+
+	fields := strings.Split(value, ":")
+	if len(fields) > 3 {
+		return 0, fmt.Errorf("%w: %q", invalid, value)
+	}
+	var seconds float64
+	for i, field := range fields {
+		n, err := strconv.ParseFloat(field, 64)
+		last := i == len(fields)-1
+		if err != nil || n < 0 || math.IsNaN(n) || math.IsInf(n, 0) || (!last && n != math.Trunc(n)) || (i > 0 && n >= 60) {
+			return 0, fmt.Errorf("%w: %q", invalid, value)
+		}
+		seconds = seconds*60 + n
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func (uc *DownloadVideoUseCase) Execute(ctx context.Context, sol Solicitation, progress domain.ProgressBar) error {
+	return uc.Download(ctx, sol.Video(), progress)
+}
+
+// Enqueue schedules the video as a job of the download queue. URLs the
+// downloader can tell it does not handle are refused up front, while ctx,
+// the context of the request, is alive.
+func (uc *DownloadVideoUseCase) Enqueue(ctx context.Context, video domain.Video, priority int) (domain.Job, error) {
+	if uc.Queue == nil {
+		return domain.Job{}, errors.New("no download queue configured")
+	}
+	if checker, ok := uc.Downloader.(domain.SourceChecker); ok {
+		if err := checker.Supports(ctx, video.URL); err != nil {
+			return domain.Job{}, err
+		}
+	}
+	return uc.Queue.Enqueue(domain.Job{Video: video, Priority: priority})
+}
+
+// Download runs an already built video request, such as one restored from
+// the job queue.
+func (uc *DownloadVideoUseCase) Download(ctx context.Context, video domain.Video, progress domain.ProgressBar) error {
+	video.StartedAt = time.Now()
+	if video.SourceID == "" {
+		video.SourceID = uc.sourceID(video.URL)
+	}
+	if err := uc.Downloader.Download(ctx, video, progress); err != nil {
+		return err
+	}
+
+	// A clip does not count as the video being downloaded.
+	if uc.Archive != nil && video.SourceID != "" && !video.Clipped() {
+		if err := uc.Archive.Add(video.SourceID); err != nil {
+			log.Error(fmt.Sprintf("Erro ao registrar %s no arquivo de downloads: %v", video.SourceID, err))
+		}
+	}
+	return nil
+}
+
+// sourceID asks the downloader for the source ID of url, which the archive
+// records. Downloaders that cannot tell leave it empty.
+func (uc *DownloadVideoUseCase) sourceID(url string) string {
+	identifier, ok := uc.Downloader.(domain.SourceIdentifier)
+	if !ok {
+		return ""
+	}
+	id, err := identifier.SourceID(url)
+	if err != nil {
+		log.Error(fmt.Sprintf("Erro ao identificar o vídeo de %s: %v", url, err))
+		return ""
+	}
+	return id
 }
 
-func (uc *DownloadVideoUseCase) Execute(sol Solicitation, progress domain.ProgressBar) error {
-	return uc.Downloader.Download(domain.Video{URL: sol.URL, Requester: sol.Requester}, progress)
+// NotifyCancelled tells the requester that a download was cancelled before
+// it started. Playlist entries are left to the playlist summary.
+func (uc *DownloadVideoUseCase) NotifyCancelled(video domain.Video) error {
+	if video.InPlaylist() {
+		return nil
+	}
+	return uc.Downloader.Finalize(domain.Notification{
+		Title:   video.URL,
+		Message: "Download cancelado",
+		To:      video.Requester,
+		Kind:    domain.NotificationCancelled,
+	})
 }
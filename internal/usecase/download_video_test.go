@@ -0,0 +1,195 @@
+package usecase
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeDownloader struct {
+	err        error
+	downloaded []domain.Video
+}
+
+func (d *fakeDownloader) Download(ctx context.Context, video domain.Video, progress domain.ProgressBar) error {
+	d.downloaded = append(d.downloaded, video)
+	return d.err
+}
+
+func (d *fakeDownloader) Finalize(notification domain.Notification) error { return nil }
+
+func (d *fakeDownloader) Cancel(file *os.File) error { return nil }
+
+// identifyingDownloader tells the ID after "v=" in a URL.
+type identifyingDownloader struct{ fakeDownloader }
+
+func (d *identifyingDownloader) SourceID(url string) (string, error) {
+	_, id, ok := strings.Cut(url, "v=")
+	if !ok {
+		return "", errors.New("no video ID")
+	}
+	return id, nil
+}
+
+type fakeArchive map[string]bool
+
+func (a fakeArchive) Contains(sourceID string) bool { return a[sourceID] }
+
+func (a fakeArchive) Add(sourceID string) error {
+	a[sourceID] = true
+	return nil
+}
+
+func TestDownloadArchivesSourceID(t *testing.T) {
+	tests := []struct {
+		name       string
+		downloader domain.Downloader
+		video      domain.Video
+		want       []string
+	}{
+		{
+			name:       "ID resolved from the URL",
+			downloader: &identifyingDownloader{},
+			video:      domain.Video{URL: "https://www.youtube.com/watch?v=abc"},
+			want:       []string{"abc"},
+		},
+		{
+			name:       "ID set in advance",
+			downloader: &identifyingDownloader{},
+			video:      domain.Video{URL: "https://www.youtube.com/watch?v=abc", SourceID: "xyz"},
+			want:       []string{"xyz"},
+		},
+		{
+			name:       "URL without ID",
+			downloader: &identifyingDownloader{},
+			video:      domain.Video{URL: "https://example.com/file.mp4"},
+		},
+		{
+			name:       "downloader without IDs",
+			downloader: &fakeDownloader{},
+			video:      domain.Video{URL: "https://www.youtube.com/watch?v=abc"},
+		},
+		{
+			name:       "clip",
+			downloader: &identifyingDownloader{},
+			video:      domain.Video{URL: "https://www.youtube.com/watch?v=abc", ClipEnd: 10},
+		},
+		{
+			name:       "failed download",
+			downloader: &identifyingDownloader{fakeDownloader{err: errors.New("failed")}},
+			video:      domain.Video{URL: "https://www.youtube.com/watch?v=abc"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := fakeArchive{}
+			uc := DownloadVideoUseCase{Downloader: tt.downloader, Archive: archive}
+			uc.Download(context.Background(), tt.video, nil)
+
+			if len(archive) != len(tt.want) {
+				t.Fatalf("archive = %v, want %v", archive, tt.want)
+			}
+			for _, id := range tt.want {
+				if !archive[id] {
+					t.Errorf("archive = %v, want %s in it", archive, id)
+				}
+			}
+		})
+	}
+}
+
+func TestParseClip(t *testing.T) {
+	tests := []struct {
+		start, end string
+		from, to   time.Duration
+		invalid    bool
+	}{
+		{start: "", end: ""},
+		{start: "90", from: 90 * time.Second},
+		{start: "1.5", end: "2.25", from: 1500 * time.Millisecond, to: 2250 * time.Millisecond},
+		{start: "01:30", end: "1:02:03.5", from: 90 * time.Second, to: time.Hour + 2*time.Minute + 3500*time.Millisecond},
+		{start: " 1m30s ", end: "2h", from: 90 * time.Second, to: 2 * time.Hour},
+		{end: "0:45", to: 45 * time.Second},
+		{start: "100:00", from: 100 * time.Minute},
+		{start: "1:60", invalid: true},
+		{start: "1.5:00", invalid: true},
+		{start: "1:2:3:4", invalid: true},
+		{start: "-5", invalid: true},
+		{start: "-1m", invalid: true},
+		{start: "NaN", invalid: true},
+		{start: "Inf", invalid: true},
+		{start: "abc", invalid: true},
+		{start: "1:", invalid: true},
+		{start: "1x", invalid: true},
+		{start: "30", end: "30", invalid: true},
+		{start: "1:00", end: "30", invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.start+"-"+tt.end, func(t *testing.T) {
+			from, to, err := ParseClip(tt.start, tt.end)
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidClip) {
+					t.Fatalf("ParseClip(%q, %q) = %v, %v, %v, want ErrInvalidClip", tt.start, tt.end, from, to, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if from != tt.from || to != tt.to {
+				t.Fatalf("ParseClip(%q, %q) = %v, %v, want %v, %v", tt.start, tt.end, from, to, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+// checkingDownloader refuses the URLs in refused, recording the context it
+// was asked with.
+type checkingDownloader struct {
+	fakeDownloader
+	refused map[string]bool
+	ctx     context.Context
+}
+
+func (d *checkingDownloader) Supports(ctx context.Context, url string) error {
+	d.ctx = ctx
+	if d.refused[url] {
+		return domain.ErrUnsupportedSource
+	}
+	return nil
+}
+
+type requestKey struct{}
+
+func TestEnqueueChecksSource(t *testing.T) {
+	tests := []struct {
+		url    string
+		queued bool
+	}{
+		{url: "https://example.com/video.mp4", queued: true},
+		{url: "https://example.com/page", queued: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			downloader := &checkingDownloader{refused: map[string]bool{"https://example.com/page": true}}
+			queue := &fakeQueue{}
+			uc := &DownloadVideoUseCase{Downloader: downloader, Queue: queue}
+			ctx := context.WithValue(context.Background(), requestKey{}, "request")
+
+			_, err := uc.Enqueue(ctx, domain.Video{URL: tt.url}, 0)
+			if queued := err == nil && len(queue.jobs) == 1; queued != tt.queued {
+				t.Fatalf("Enqueue = %v with %d jobs, want queued %v", err, len(queue.jobs), tt.queued)
+			}
+			if !tt.queued && !errors.Is(err, domain.ErrUnsupportedSource) {
+				t.Fatalf("Enqueue = %v, want ErrUnsupportedSource", err)
+			}
+			if downloader.ctx == nil || downloader.ctx.Value(requestKey{}) != "request" {
+				t.Fatal("the source was not checked with the request context")
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidItems = errors.New("invalid playlist items")
+
+type PlaylistUseCase struct {
+	Fetcher    domain.PlaylistFetcher
+	Downloader domain.Downloader
+	Archive    domain.Archive
+}
+
+// PlaylistSolicitation asks for the videos of a playlist. Items selects
+// entries by their 1-based index, e.g. "1-10,15" or "20-"; empty selects
+// every entry.
+type PlaylistSolicitation struct {
+	Solicitation
+	Items          string
+	Reverse        bool
+	SkipDownloaded bool
+}
+
+// PlaylistDownload is a playlist expanded into one video per selected
+// entry. Every video carries ID as its PlaylistID.
+type PlaylistDownload struct {
+	ID       string
+	Playlist domain.Playlist
+	Videos   []domain.Video
+	Skipped  int
+}
+
+// PlaylistResult counts how the videos of a playlist download ended.
+type PlaylistResult struct {
+	Done      int
+	Failed    int
+	Cancelled int
+	Skipped   int
+}
+
+func (uc *PlaylistUseCase) IsPlaylist(url string) bool {
+	return uc.Fetcher != nil && uc.Fetcher.IsPlaylist(url)
+}
+
+// Expand fetches the playlist and builds the videos to download.
+func (uc *PlaylistUseCase) Expand(ctx context.Context, sol PlaylistSolicitation) (PlaylistDownload, error) {
+	playlist, err := uc.Fetcher.Fetch(ctx, sol.URL)
+	if err != nil {
+		return PlaylistDownload{}, err
+	}
+
+	entries, err := selectEntries(playlist.Entries, sol.Items)
+	if err != nil {
+		return PlaylistDownload{}, err
+	}
+	if sol.Reverse {
+		slices.Reverse(entries)
+	}
+
+	download := PlaylistDownload{ID: uuid.NewString(), Playlist: playlist}
+	for _, entry := range entries {
+		if sol.SkipDownloaded && uc.Archive != nil && uc.Archive.Contains(entry.ID) {
+			download.Skipped++
+			continue
+		}
+
+		entrySol := sol.Solicitation
+		entrySol.URL = entry.URL
+		video := entrySol.Video()
+		video.SourceID = entry.ID
+		video.PlaylistID = download.ID
+		video.PlaylistTitle = playlist.Title
+		download.Videos = append(download.Videos, video)
+	}
+
+	for i := range download.Videos {
+		download.Videos[i].PlaylistSkipped = download.Skipped
+	}
+
+	log.Info(fmt.Sprintf("Playlist %s: %d vídeos selecionados, %d já baixados", playlist.Title, len(download.Videos), download.Skipped))
+	return download, nil
+}
+
+// Execute downloads the selected videos of a playlist one after the other
+// and sends the summary. The web server queues each video as its own job
+// instead.
+func (uc *PlaylistUseCase) Execute(ctx context.Context, sol PlaylistSolicitation, progress domain.ProgressBar) (PlaylistResult, error) {
+	download, err := uc.Expand(ctx, sol)
+	if err != nil {
+		return PlaylistResult{}, err
+	}
+
+	videos := DownloadVideoUseCase{Downloader: uc.Downloader, Archive: uc.Archive}
+	result := PlaylistResult{Skipped: download.Skipped}
+	for i, video := range download.Videos {
+		if ctx.Err() != nil {
+			result.Cancelled += len(download.Videos) - i
+			break
+		}
+
+		log.Info(fmt.Sprintf("Playlist %s: vídeo %d de %d", download.Playlist.Title, i+1, len(download.Videos)))
+		err := videos.Download(ctx, video, progress)
+		switch {
+		case err == nil:
+			result.Done++
+		case ctx.Err() != nil:
+			result.Cancelled++
+		default:
+			log.Error(fmt.Sprintf("Erro ao baixar %s: %v", video.URL, err))
+			result.Failed++
+		}
+	}
+
+	if err := uc.NotifySummary(download.Playlist.Title, sol.Requester, result); err != nil {
+		log.Error(fmt.Sprintf("Erro ao notificar resumo da playlist: %v", err))
+	}
+	if ctx.Err() != nil {
+		return result, fmt.Errorf("playlist download stopped: %w", context.Cause(ctx))
+	}
+	return result, nil
+}
+
+// NotifySummary sends the single notification of a finished playlist.
+func (uc *PlaylistUseCase) NotifySummary(title, requester string, result PlaylistResult) error {
+	total := result.Done + result.Failed + result.Cancelled
+	message := fmt.Sprintf("%d de %d vídeos baixados", result.Done, total)
+	if result.Failed > 0 {
+		message += fmt.Sprintf(", %d falharam", result.Failed)
+	}
+	if result.Cancelled > 0 {
+		message += fmt.Sprintf(", %d cancelados", result.Cancelled)
+	}
+	if result.Skipped > 0 {
+		message += fmt.Sprintf(", %d já baixados", result.Skipped)
+	}
+
+	return uc.Downloader.Finalize(domain.Notification{
+		Title:   title,
+		Message: message,
+		To:      requester,
+		Kind:    domain.NotificationPlaylist,
+	})
+}
+
+// selectEntries keeps the entries whose index matches items, in playlist
+// order and without repetitions.
+func selectEntries(entries []domain.PlaylistEntry, items string) ([]domain.PlaylistEntry, error) {
+	if strings.TrimSpace(items) == "" {
+		return entries, nil
+	}
+
+	selected := map[int]bool{}
+	for _, term := range strings.Split(items, ",") {
+		first, last, err := parseItemRange(strings.TrimSpace(term), len(entries))
+		if err != nil {
+			return nil, err
+		}
+		for i := first; i <= min(last, len(entries)); i++ {
+			selected[i] = true
+		}
+	}
+
+	var result []domain.PlaylistEntry
+	for _, entry := range entries {
+		if selected[entry.Index] {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+// parseItemRange parses "N", "N-M", "N-" or "-M" into an inclusive range.
+func parseItemRange(term string, count int) (int, int, error) {
+	from, to, isRange := strings.Cut(term, "-")
+	if !isRange {
+		to = from
+	}
+
+	first, last := 1, count
+	var err error
+	if from != "" {
+		if first, err = strconv.Atoi(from); err != nil {
+			return 0, 0, fmt.Errorf("%w: %q", ErrInvalidItems, term)
+		}
+	}
+	if to != "" {
+		if last, err = strconv.Atoi(to); err != nil {
+			return 0, 0, fmt.Errorf("%w: %q", ErrInvalidItems, term)
+		}
+	}
+	if first < 1 || last < first || (from == "" && to == "") {
+		return 0, 0, fmt.Errorf("%w: %q", ErrInvalidItems, term)
+	}
+	return first, last, nil
+}
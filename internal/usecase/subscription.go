@@ -0,0 +1,265 @@
+package usecase
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidSubscription = errors.New("invalid subscription")
+
+var ErrSubscriptionNotFound = errors.New("subscription not found")
+
+const (
+	DefaultSubscriptionInterval = time.Hour
+	MinSubscriptionInterval     = 5 * time.Minute
+)
+
+type SubscriptionUseCase struct {
+	Store   domain.SubscriptionStore
+	Fetcher domain.PlaylistFetcher
+	Videos  *DownloadVideoUseCase
+
+	// mu serializes the writes to the store, so a check that takes a while
+	// does not undo an update made meanwhile.
+	mu sync.Mutex
+}
+
+// Create validates and stores a new subscription.
+func (uc *SubscriptionUseCase) Create(sub domain.Subscription) (domain.Subscription, error) {
+	if err := uc.validate(&sub); err != nil {
+		return domain.Subscription{}, err
+	}
+
+	sub.ID = uuid.NewString()
+	sub.Seen = map[string]bool{}
+	sub.LastChecked, sub.LastError = time.Time{}, ""
+	sub.CreatedAt = time.Now()
+	sub.UpdatedAt = sub.CreatedAt
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if err := uc.Store.Save(sub.ID, sub); err != nil {
+		return domain.Subscription{}, fmt.Errorf("error saving subscription: %w", err)
+	}
+	log.Info(fmt.Sprintf("Inscrição %s criada para %s", sub.ID, sub.URL))
+	return sub, nil
+}
+
+// Update replaces the settings of a subscription, keeping what it has
+// already seen.
+func (uc *SubscriptionUseCase) Update(id string, sub domain.Subscription) (domain.Subscription, error) {
+	if err := uc.validate(&sub); err != nil {
+		return domain.Subscription{}, err
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	current, err := uc.Store.Get(id)
+	if err != nil {
+		return domain.Subscription{}, ErrSubscriptionNotFound
+	}
+
+	sub.ID = id
+	sub.Seen = current.Seen
+	sub.LastChecked, sub.LastError = current.LastChecked, current.LastError
+	sub.CreatedAt = current.CreatedAt
+	sub.UpdatedAt = time.Now()
+	if err := uc.Store.Save(id, sub); err != nil {
+		return domain.Subscription{}, fmt.Errorf("error saving subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func (uc *SubscriptionUseCase) Delete(id string) error {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if err := uc.Store.Remove(id); err != nil {
+		return ErrSubscriptionNotFound
+	}
+	log.Info(fmt.Sprintf("Inscrição %s removida", id))
+	return nil
+}
+
+func (uc *SubscriptionUseCase) Get(id string) (domain.Subscription, error) {
+	sub, err := uc.Store.Get(id)
+	if err != nil {
+		return domain.Subscription{}, ErrSubscriptionNotFound
+	}
+	return sub, nil
+}
+
+// List returns the subscriptions, oldest first, optionally filtered by
+// requester.
+func (uc *SubscriptionUseCase) List(requester string) ([]domain.Subscription, error) {
+	q := domain.Query{OrderBy: "CreatedAt"}
+	if requester != "" {
+		q.Filters = []domain.Filter{{Field: "Requester", Op: domain.OpEqual, Value: requester}}
+	}
+
+	subs, err := uc.Store.List(q)
+	if err != nil {
+		return nil, fmt.Errorf("error listing subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+// Due returns the subscriptions whose interval has elapsed.
+func (uc *SubscriptionUseCase) Due(now time.Time) ([]domain.Subscription, error) {
+	subs, err := uc.List("")
+	if err != nil {
+		return nil, err
+	}
+
+	var due []domain.Subscription
+	for _, sub := range subs {
+		if sub.Due(now) {
+			due = append(due, sub)
+		}
+	}
+	return due, nil
+}
+
+// Check lists the subscription source and enqueues the videos not seen
+// before that pass its filters. The first check of a subscription without
+// a date filter only records the videos already published, so that just
+// new uploads are downloaded.
+func (uc *SubscriptionUseCase) Check(ctx context.Context, id string) (int, error) {
+	sub, err := uc.Get(id)
+	if err != nil {
+		return 0, err
+	}
+
+	playlist, err := uc.Fetcher.Fetch(ctx, sub.URL)
+	if err != nil {
+		uc.finishCheck(id, nil, err)
+		return 0, err
+	}
+
+	baseline := sub.LastChecked.IsZero() && sub.PublishedAfter.IsZero()
+	title, _ := regexp.Compile(sub.TitlePattern)
+
+	seen := map[string]bool{}
+	enqueued := 0
+	for _, entry := range playlist.Entries {
+		if sub.Seen[entry.ID] || seen[entry.ID] {
+			continue
+		}
+		if baseline {
+			seen[entry.ID] = true
+			continue
+		}
+
+		match, err := uc.matches(ctx, sub, title, entry)
+		if err != nil {
+			// Leave the entry unseen so the next check tries it again.
+			log.Error(fmt.Sprintf("Erro ao verificar %s da inscrição %s: %v", entry.ID, id, err))
+			continue
+		}
+		seen[entry.ID] = true
+		if !match {
+			continue
+		}
+
+		video := Solicitation{URL: entry.URL, Requester: sub.Requester, Format: sub.Format, AudioOnly: sub.AudioOnly}.Video()
+		video.SourceID = entry.ID
+		if _, err := uc.Videos.Enqueue(ctx, video, 0); err != nil {
+			delete(seen, entry.ID)
+			log.Error(fmt.Sprintf("Erro ao enfileirar %s da inscrição %s: %v", entry.ID, id, err))
+			continue
+		}
+		enqueued++
+	}
+
+	uc.finishCheck(id, seen, nil)
+	if enqueued > 0 {
+		log.Info(fmt.Sprintf("Inscrição %s: %d novos vídeos enfileirados", id, enqueued))
+	}
+	return enqueued, nil
+}
+
+func (uc *SubscriptionUseCase) matches(ctx context.Context, sub domain.Subscription, title *regexp.Regexp, entry domain.PlaylistEntry) (bool, error) {
+	if title != nil && !title.MatchString(entry.Title) {
+		return false, nil
+	}
+	if entry.Duration > 0 {
+		if sub.MinDuration > 0 && entry.Duration < sub.MinDuration {
+			return false, nil
+		}
+		if sub.MaxDuration > 0 && entry.Duration > sub.MaxDuration {
+			return false, nil
+		}
+	}
+	if !sub.PublishedAfter.IsZero() {
+		published, err := uc.Fetcher.PublishDate(ctx, entry.ID)
+		if err != nil {
+			return false, err
+		}
+		if published.Before(sub.PublishedAfter) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// finishCheck records the outcome of a check on the stored subscription,
+// which may have been updated while the check ran.
+func (uc *SubscriptionUseCase) finishCheck(id string, seen map[string]bool, checkErr error) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	sub, err := uc.Store.Get(id)
+	if err != nil {
+		return
+	}
+
+	if sub.Seen == nil {
+		sub.Seen = map[string]bool{}
+	}
+	for entryID := range seen {
+		sub.Seen[entryID] = true
+	}
+	sub.LastChecked = time.Now()
+	sub.LastError = ""
+	if checkErr != nil {
+		sub.LastError = checkErr.Error()
+	}
+	if err := uc.Store.Save(id, sub); err != nil {
+		log.Error(fmt.Sprintf("Erro ao salvar inscrição %s: %v", id, err))
+	}
+}
+
+func (uc *SubscriptionUseCase) validate(sub *domain.Subscription) error {
+	sub.URL = strings.TrimSpace(sub.URL)
+	if sub.URL == "" {
+		return fmt.Errorf("%w: url is required", ErrInvalidSubscription)
+	}
+	if sub.Requester == "" {
+		return fmt.Errorf("%w: requester is required", ErrInvalidSubscription)
+	}
+	if !uc.Fetcher.IsPlaylist(sub.URL) {
+		return fmt.Errorf("%w: url must be a channel or a playlist", ErrInvalidSubscription)
+	}
+	if sub.TitlePattern != "" {
+		if _, err := regexp.Compile(sub.TitlePattern); err != nil {
+			return fmt.Errorf("%w: title pattern: %v", ErrInvalidSubscription, err)
+		}
+	}
+	if sub.MinDuration < 0 || sub.MaxDuration < 0 || (sub.MaxDuration > 0 && sub.MaxDuration < sub.MinDuration) {
+		return fmt.Errorf("%w: invalid duration range", ErrInvalidSubscription)
+	}
+
+	if sub.Interval == 0 {
+		sub.Interval = DefaultSubscriptionInterval
+	}
+	if sub.Interval < MinSubscriptionInterval {
+		return fmt.Errorf("%w: interval must be at least %s", ErrInvalidSubscription, MinSubscriptionInterval)
+	}
+	return nil
+}
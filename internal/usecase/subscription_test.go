@@ -0,0 +1,176 @@
+package usecase
+
+import (
+	"context"
+	"downloader/internal/domain"
+	memoria "downloader/internal/infra/db/mem_db"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+// fakeFetcher lists entries and tells their publish dates, failing for the
+// IDs without one.
+type fakeFetcher struct {
+	entries   []domain.PlaylistEntry
+	published map[string]time.Time
+}
+
+func (f *fakeFetcher) IsPlaylist(url string) bool { return true }
+
+func (f *fakeFetcher) Fetch(ctx context.Context, url string) (domain.Playlist, error) {
+	return domain.Playlist{Entries: f.entries}, nil
+}
+
+func (f *fakeFetcher) PublishDate(ctx context.Context, entryID string) (time.Time, error) {
+	date, ok := f.published[entryID]
+	if !ok {
+		return time.Time{}, errors.New("no publish date")
+	}
+	return date, nil
+}
+
+type fakeQueue struct{ jobs []domain.Job }
+
+func (q *fakeQueue) Enqueue(job domain.Job) (domain.Job, error) {
+	q.jobs = append(q.jobs, job)
+	return job, nil
+}
+
+func TestSubscriptionCheck(t *testing.T) {
+	day := time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC)
+	entries := []domain.PlaylistEntry{
+		{ID: "a", URL: "https://www.youtube.com/watch?v=a", Title: "Aula 1", Duration: 10 * time.Minute},
+		{ID: "b", URL: "https://www.youtube.com/watch?v=b", Title: "Vlog", Duration: 2 * time.Minute},
+		{ID: "c", URL: "https://www.youtube.com/watch?v=c", Title: "Aula 2", Duration: time.Hour},
+		{ID: "d", URL: "https://www.youtube.com/watch?v=d", Title: "Aula 3"},
+	}
+	published := map[string]time.Time{
+		"a": day.AddDate(0, 0, -2),
+		"b": day.AddDate(0, 0, 1),
+		"c": day.AddDate(0, 0, 2),
+	}
+
+	tests := []struct {
+		name     string
+		sub      domain.Subscription
+		enqueued []string
+		seen     []string
+	}{
+		{
+			name: "first check records a baseline",
+			sub:  domain.Subscription{},
+			seen: []string{"a", "b", "c", "d"},
+		},
+		{
+			name:     "first check with a date filter downloads",
+			sub:      domain.Subscription{PublishedAfter: day},
+			enqueued: []string{"b", "c"},
+			// d has no publish date and is tried again next time.
+			seen: []string{"a", "b", "c"},
+		},
+		{
+			name:     "later check skips seen entries",
+			sub:      domain.Subscription{LastChecked: day, Seen: map[string]bool{"a": true, "b": true}},
+			enqueued: []string{"c", "d"},
+			seen:     []string{"a", "b", "c", "d"},
+		},
+		{
+			name:     "title pattern",
+			sub:      domain.Subscription{LastChecked: day, TitlePattern: "^Aula"},
+			enqueued: []string{"a", "c", "d"},
+			seen:     []string{"a", "b", "c", "d"},
+		},
+		{
+			name: "duration range",
+			sub:  domain.Subscription{LastChecked: day, MinDuration: 5 * time.Minute, MaxDuration: 30 * time.Minute},
+			// Entries of unknown duration pass.
+			enqueued: []string{"a", "d"},
+			seen:     []string{"a", "b", "c", "d"},
+		},
+		{
+			name:     "all filters",
+			sub:      domain.Subscription{LastChecked: day, TitlePattern: "Aula", MinDuration: 5 * time.Minute, PublishedAfter: day},
+			enqueued: []string{"c"},
+			seen:     []string{"a", "b", "c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &fakeQueue{}
+			uc := &SubscriptionUseCase{
+				Store:   memoria.NewMemoriaDatabase[domain.Subscription](),
+				Fetcher: &fakeFetcher{entries: entries, published: published},
+				Videos:  &DownloadVideoUseCase{Downloader: &fakeDownloader{}, Queue: queue},
+			}
+			tt.sub.ID, tt.sub.URL, tt.sub.Requester = "sub", "https://www.youtube.com/@canal", "ana"
+			if err := uc.Store.Save(tt.sub.ID, tt.sub); err != nil {
+				t.Fatal(err)
+			}
+
+			n, err := uc.Check(context.Background(), tt.sub.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var enqueued []string
+			for _, job := range queue.jobs {
+				if job.Video.Requester != "ana" {
+					t.Errorf("job of %s requested by %q", job.Video.SourceID, job.Video.Requester)
+				}
+				enqueued = append(enqueued, job.Video.SourceID)
+			}
+			if n != len(enqueued) || fmt.Sprint(enqueued) != fmt.Sprint(tt.enqueued) {
+				t.Errorf("Check enqueued %d: %v, want %v", n, enqueued, tt.enqueued)
+			}
+
+			stored, err := uc.Store.Get(tt.sub.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var seen []string
+			for id := range stored.Seen {
+				seen = append(seen, id)
+			}
+			sort.Strings(seen)
+			if fmt.Sprint(seen) != fmt.Sprint(tt.seen) {
+				t.Errorf("seen = %v, want %v", seen, tt.seen)
+			}
+			if stored.LastChecked.IsZero() || stored.LastError != "" {
+				t.Errorf("check recorded at %v with error %q", stored.LastChecked, stored.LastError)
+			}
+		})
+	}
+}
+
+func TestSubscriptionCheckRetriesUnseen(t *testing.T) {
+	fetcher := &fakeFetcher{
+		entries:   []domain.PlaylistEntry{{ID: "a", URL: "https://www.youtube.com/watch?v=a"}},
+		published: map[string]time.Time{},
+	}
+	queue := &fakeQueue{}
+	uc := &SubscriptionUseCase{
+		Store:   memoria.NewMemoriaDatabase[domain.Subscription](),
+		Fetcher: fetcher,
+		Videos:  &DownloadVideoUseCase{Downloader: &fakeDownloader{}, Queue: queue},
+	}
+	after := time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC)
+	if err := uc.Store.Save("sub", domain.Subscription{ID: "sub", PublishedAfter: after}); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := uc.Check(context.Background(), "sub"); err != nil || n != 0 {
+		t.Fatalf("Check without a publish date = %d, %v", n, err)
+	}
+	fetcher.published["a"] = after.Add(time.Hour)
+	if n, err := uc.Check(context.Background(), "sub"); err != nil || n != 1 {
+		t.Fatalf("Check once the date is known = %d, %v", n, err)
+	}
+	if n, err := uc.Check(context.Background(), "sub"); err != nil || n != 0 {
+		t.Fatalf("third Check enqueued %d again, %v", n, err)
+	}
+	if len(queue.jobs) != 1 {
+		t.Fatalf("queued %d jobs, want 1", len(queue.jobs))
+	}
+}
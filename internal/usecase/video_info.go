@@ -0,0 +1,23 @@
+package usecase
+
+import (
+	"context"
+	"downloader/internal/domain"
+	"errors"
+	"strings"
+)
+
+var ErrMissingURL = errors.New("url is required")
+
+type VideoInfoUseCase struct {
+	Fetcher domain.VideoInfoFetcher
+}
+
+// Execute fetches what is available for a video before downloading it.
+func (uc *VideoInfoUseCase) Execute(ctx context.Context, url string) (domain.VideoInfo, error) {
+	url = strings.TrimSpace(url)
+	if url == "" {
+		return domain.VideoInfo{}, ErrMissingURL
+	}
+	return uc.Fetcher.Info(ctx, url)
+}
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strconv"
 )
 
 type Config struct {
@@ -15,25 +16,42 @@ type Config struct {
 	VideoDir   string         `json:"video_dir"`
 	ConfigDir  string         `json:"config_dir"`
 	URLWebhook string         `json:"url_webhook"`
+	FFmpegPath string         `json:"ffmpeg_path"`
+	Workers    int            `json:"workers"`
+	Chunks     int            `json:"chunks"`
+	Retry      ConfigRetry    `json:"retry"`
 	Database   ConfigDatabase `json:"db"`
 }
 
+type ConfigRetry struct {
+	MaxAttempts int `json:"max_attempts"`
+	BaseDelayMs int `json:"base_delay_ms"`
+	MaxDelayMs  int `json:"max_delay_ms"`
+}
+
+// ConfigDatabase selects where the videos and jobs are kept. Driver is
+// "bolt", a file at Path, "postgres", shared by every instance, or
+// "memory", which forgets them on restart. URL is the postgres host or a
+// full postgres:// URL.
 type ConfigDatabase struct {
-	Port string `json:"port"`
-	URL  string `json:"url"`
-	User string `json:"usr"`
-	Psw  string `json:"psw"`
+	Driver   string `json:"driver"`
+	Path     string `json:"path"`
+	Port     string `json:"port"`
+	URL      string `json:"url"`
+	User     string `json:"usr"`
+	Psw      string `json:"psw"`
+	Name     string `json:"name"`
+	MaxConns int    `json:"max_conns"`
 }
 
 var appConfig Config
-var dbConfig ConfigDatabase
 
 func GetConfig() Config {
 	return appConfig
 }
 
 func GetDbConfig() ConfigDatabase {
-	return dbConfig
+	return appConfig.Database
 }
 
 func LoadConfig() error {
@@ -57,6 +75,62 @@ func LoadConfig() error {
 	if appConfig.URLWebhook == "" {
 		appConfig.URLWebhook = utils.GetEnvOrDefault("WEBHOOK", "http://host.docker.internal:5677/webhook/downloader-yt")
 	}
+
+	if appConfig.FFmpegPath == "" {
+		appConfig.FFmpegPath = os.Getenv("FFMPEG_PATH")
+	}
+
+	if appConfig.Workers <= 0 {
+		appConfig.Workers = getEnvIntOrDefault("WORKERS", 2)
+	}
+
+	if appConfig.Chunks <= 0 {
+		appConfig.Chunks = getEnvIntOrDefault("CHUNKS", 4)
+	}
+
+	if appConfig.Retry.MaxAttempts <= 0 {
+		appConfig.Retry.MaxAttempts = getEnvIntOrDefault("RETRY_ATTEMPTS", 4)
+	}
+
+	if appConfig.Retry.BaseDelayMs <= 0 {
+		appConfig.Retry.BaseDelayMs = getEnvIntOrDefault("RETRY_BASE_DELAY_MS", 2000)
+	}
+
+	if appConfig.Retry.MaxDelayMs <= 0 {
+		appConfig.Retry.MaxDelayMs = getEnvIntOrDefault("RETRY_MAX_DELAY_MS", 60000)
+	}
+
+	if appConfig.Database.Driver == "" {
+		appConfig.Database.Driver = utils.GetEnvOrDefault("DB_DRIVER", "bolt")
+	}
+
+	if appConfig.Database.Path == "" {
+		appConfig.Database.Path = utils.GetEnvOrDefault("DB_PATH", filepath.Join(appConfig.ConfigDir, "downloader.db"))
+	}
+
+	if appConfig.Database.URL == "" {
+		appConfig.Database.URL = utils.GetEnvOrDefault("DB_URL", "localhost")
+	}
+
+	if appConfig.Database.Port == "" {
+		appConfig.Database.Port = utils.GetEnvOrDefault("DB_PORT", "5432")
+	}
+
+	if appConfig.Database.User == "" {
+		appConfig.Database.User = os.Getenv("DB_USER")
+	}
+
+	if appConfig.Database.Psw == "" {
+		appConfig.Database.Psw = os.Getenv("DB_PASSWORD")
+	}
+
+	if appConfig.Database.Name == "" {
+		appConfig.Database.Name = utils.GetEnvOrDefault("DB_NAME", "downloader")
+	}
+
+	if appConfig.Database.MaxConns <= 0 {
+		appConfig.Database.MaxConns = getEnvIntOrDefault("DB_MAX_CONNS", 10)
+	}
 	return nil
 }
 
@@ -76,6 +150,14 @@ func init() {
 	saveConfig()
 }
 
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
 func readConfig() error {
 	file, err := os.ReadFile(filepath.Join(utils.GetEnvOrDefault("CONFIG_DIR", "./.config"), "config.json"))
 	if err != nil {